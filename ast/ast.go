@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"sort"
 	"strings"
 
 	"github.com/dedisuryadi/bilang/token"
@@ -9,6 +10,7 @@ import (
 
 type Node interface {
 	TokenLiteral() string
+	Pos() token.Pos
 	String() string
 	Type() token.Type
 }
@@ -25,6 +27,7 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+	Comments   []token.Token
 }
 
 func (p *Program) Type() token.Type { return token.PROGRAM }
@@ -34,6 +37,12 @@ func (p *Program) TokenLiteral() string {
 	}
 	return ""
 }
+func (p *Program) Pos() token.Pos {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Pos{}
+}
 func (p *Program) String() string {
 	var out bytes.Buffer
 	for _, s := range p.Statements {
@@ -50,6 +59,7 @@ type Identifier struct {
 func (id *Identifier) expressionNode()      {}
 func (id *Identifier) Type() token.Type     { return id.Token.Type }
 func (id *Identifier) TokenLiteral() string { return id.Token.Literal }
+func (id *Identifier) Pos() token.Pos       { return id.Token.Pos() }
 func (id *Identifier) String() string       { return id.Value }
 
 type VarStatement struct {
@@ -62,6 +72,7 @@ func (vs *VarStatement) expressionNode()      {}
 func (vs *VarStatement) statementNode()       {}
 func (vs *VarStatement) Type() token.Type     { return vs.Token.Type }
 func (vs *VarStatement) TokenLiteral() string { return vs.Token.Literal }
+func (vs *VarStatement) Pos() token.Pos       { return vs.Token.Pos() }
 func (vs *VarStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(vs.TokenLiteral() + " ")
@@ -83,6 +94,7 @@ type KonstStatement struct {
 func (ks *KonstStatement) statementNode()       {}
 func (ks *KonstStatement) Type() token.Type     { return ks.Token.Type }
 func (ks *KonstStatement) TokenLiteral() string { return ks.Token.Literal }
+func (ks *KonstStatement) Pos() token.Pos       { return ks.Token.Pos() }
 func (ks *KonstStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ks.TokenLiteral() + " ")
@@ -103,6 +115,7 @@ type PilihStatement struct {
 func (ps *PilihStatement) statementNode()       {}
 func (ps *PilihStatement) Type() token.Type     { return ps.Token.Type }
 func (ps *PilihStatement) TokenLiteral() string { return ps.Token.Literal }
+func (ps *PilihStatement) Pos() token.Pos       { return ps.Token.Pos() }
 func (ps *PilihStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ps.TokenLiteral() + " ")
@@ -113,6 +126,58 @@ func (ps *PilihStatement) String() string {
 	return out.String()
 }
 
+type LemparStatement struct {
+	Token token.Token // The 'lempar' token
+	Value Expression
+}
+
+func (ls *LemparStatement) statementNode()       {}
+func (ls *LemparStatement) Type() token.Type     { return ls.Token.Type }
+func (ls *LemparStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LemparStatement) Pos() token.Pos       { return ls.Token.Pos() }
+func (ls *LemparStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ls.TokenLiteral() + " ")
+	if ls.Value != nil {
+		out.WriteString(ls.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+type ImporStatement struct {
+	Token token.Token // The 'impor' token
+	Alias *Identifier // nil when the module name comes from the file name
+	Path  *StringLiteral
+}
+
+func (is *ImporStatement) statementNode()       {}
+func (is *ImporStatement) Type() token.Type     { return is.Token.Type }
+func (is *ImporStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImporStatement) Pos() token.Pos       { return is.Token.Pos() }
+func (is *ImporStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(is.TokenLiteral() + " ")
+	if is.Alias != nil {
+		out.WriteString(is.Alias.String() + " ")
+	}
+	out.WriteString(`"` + is.Path.Value + `"`)
+	return out.String()
+}
+
+type EksporStatement struct {
+	Token     token.Token // The 'ekspor' token
+	Statement Statement   // *VarStatement or *KonstStatement
+}
+
+func (es *EksporStatement) statementNode()       {}
+func (es *EksporStatement) Type() token.Type     { return es.Token.Type }
+func (es *EksporStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EksporStatement) Pos() token.Pos       { return es.Token.Pos() }
+func (es *EksporStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -121,6 +186,7 @@ type ExpressionStatement struct {
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) Type() token.Type     { return es.Token.Type }
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Pos       { return es.Token.Pos() }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -128,6 +194,17 @@ func (es *ExpressionStatement) String() string {
 	return ""
 }
 
+type IntegerLiteral struct {
+	Token token.Token
+	Value int64
+}
+
+func (il *IntegerLiteral) expressionNode()      {}
+func (il *IntegerLiteral) Type() token.Type     { return il.Token.Type }
+func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Pos       { return il.Token.Pos() }
+func (il *IntegerLiteral) String() string       { return il.Token.Literal }
+
 type FloatLiteral struct {
 	Token token.Token
 	Value float64
@@ -136,6 +213,7 @@ type FloatLiteral struct {
 func (il *FloatLiteral) expressionNode()      {}
 func (il *FloatLiteral) Type() token.Type     { return il.Token.Type }
 func (il *FloatLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *FloatLiteral) Pos() token.Pos       { return il.Token.Pos() }
 func (il *FloatLiteral) String() string       { return il.Token.Literal }
 
 type PrefixExpression struct {
@@ -147,6 +225,7 @@ type PrefixExpression struct {
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) Type() token.Type     { return pe.Token.Type }
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Pos       { return pe.Token.Pos() }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -166,6 +245,12 @@ type InfixExpression struct {
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) Type() token.Type     { return ie.Token.Type }
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Pos {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos()
+}
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -184,6 +269,7 @@ type Boolean struct {
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) Type() token.Type     { return b.Token.Type }
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Pos       { return b.Token.Pos() }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 type JikaExpression struct {
@@ -196,6 +282,7 @@ type JikaExpression struct {
 func (je *JikaExpression) expressionNode()      {}
 func (je *JikaExpression) Type() token.Type     { return je.Token.Type }
 func (je *JikaExpression) TokenLiteral() string { return je.Token.Literal }
+func (je *JikaExpression) Pos() token.Pos       { return je.Token.Pos() }
 func (je *JikaExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("jika")
@@ -209,6 +296,30 @@ func (je *JikaExpression) String() string {
 	return out.String()
 }
 
+type CobaExpression struct {
+	Token   token.Token // The 'coba' token
+	Body    *BlockStatement
+	Param   *Identifier // nil when the error is not bound, e.g. `tangkap { }`
+	Handler *BlockStatement
+}
+
+func (ce *CobaExpression) expressionNode()      {}
+func (ce *CobaExpression) Type() token.Type     { return ce.Token.Type }
+func (ce *CobaExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CobaExpression) Pos() token.Pos       { return ce.Token.Pos() }
+func (ce *CobaExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("coba ")
+	out.WriteString(ce.Body.String())
+	out.WriteString(" tangkap")
+	if ce.Param != nil {
+		out.WriteString("(" + ce.Param.String() + ")")
+	}
+	out.WriteString(" ")
+	out.WriteString(ce.Handler.String())
+	return out.String()
+}
+
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
@@ -217,6 +328,7 @@ type BlockStatement struct {
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) Type() token.Type     { return bs.Token.Type }
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Pos       { return bs.Token.Pos() }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 	for _, s := range bs.Statements {
@@ -229,11 +341,19 @@ type FunctionLiteral struct {
 	Token      token.Token // The 'fn' token
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Name       string // the variable a var or konst binds it to, empty when anonymous
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) Type() token.Type     { return fl.Token.Type }
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Pos {
+	// `x => x * 2` starts at its parameter, not at the arrow
+	if fl.Token.Type == token.FATARROW && len(fl.Parameters) > 0 {
+		return fl.Parameters[0].Pos()
+	}
+	return fl.Token.Pos()
+}
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 	params := []string{}
@@ -257,6 +377,7 @@ type LoopLiteral struct {
 
 func (l LoopLiteral) expressionNode()      {}
 func (l LoopLiteral) TokenLiteral() string { return l.Token.Literal }
+func (l LoopLiteral) Pos() token.Pos       { return l.Token.Pos() }
 func (l LoopLiteral) Type() token.Type     { return l.Token.Type }
 func (l LoopLiteral) String() string {
 	var out bytes.Buffer
@@ -275,6 +396,7 @@ type ContinueExpression struct {
 
 func (c ContinueExpression) expressionNode()      {}
 func (c ContinueExpression) TokenLiteral() string { return c.Token.Literal }
+func (c ContinueExpression) Pos() token.Pos       { return c.Token.Pos() }
 func (c ContinueExpression) Type() token.Type     { return c.Token.Type }
 func (c ContinueExpression) String() string       { return c.Token.Literal + "\n" }
 
@@ -284,12 +406,14 @@ type BreakExpression struct {
 
 func (b BreakExpression) expressionNode()      {}
 func (b BreakExpression) TokenLiteral() string { return b.Token.Literal }
+func (b BreakExpression) Pos() token.Pos       { return b.Token.Pos() }
 func (b BreakExpression) Type() token.Type     { return b.Token.Type }
 func (b BreakExpression) String() string       { return b.Token.Literal + "\n" }
 
 type PilahExpression struct {
 	Token      token.Token
-	Target     *ExpressionStatement
+	Target     *ExpressionStatement // nil for `pilah { ... }`
+	Lbrace     token.Token          // the { opening the cases
 	Conditions []*ExpressionStatement
 	Values     []Expression
 }
@@ -297,6 +421,7 @@ type PilahExpression struct {
 func (pl *PilahExpression) expressionNode()      {}
 func (pl *PilahExpression) Type() token.Type     { return pl.Token.Type }
 func (pl *PilahExpression) TokenLiteral() string { return pl.Token.Literal }
+func (pl *PilahExpression) Pos() token.Pos       { return pl.Token.Pos() }
 func (pl *PilahExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(pl.Target.String())
@@ -315,6 +440,7 @@ type Wildcard struct {
 func (w *Wildcard) expressionNode()      {}
 func (w *Wildcard) Type() token.Type     { return w.Token.Type }
 func (w *Wildcard) TokenLiteral() string { return w.Token.Literal }
+func (w *Wildcard) Pos() token.Pos       { return w.Token.Pos() }
 func (w *Wildcard) String() string       { return w.Token.Literal }
 
 type CallExpression struct {
@@ -326,6 +452,12 @@ type CallExpression struct {
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) Type() token.Type     { return ce.Token.Type }
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Pos {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos()
+}
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 	args := []string{}
@@ -347,8 +479,32 @@ type StringLiteral struct {
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) Type() token.Type     { return sl.Token.Type }
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Pos       { return sl.Token.Pos() }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// InterpolatedString is a "string with ${expressions}". Parts alternates
+// between StringLiterals holding the text and the embedded expressions.
+type InterpolatedString struct {
+	Token token.Token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) Type() token.Type     { return is.Token.Type }
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Pos() token.Pos       { return is.Token.Pos() }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	for _, part := range is.Parts {
+		if s, ok := part.(*StringLiteral); ok {
+			out.WriteString(s.Value)
+			continue
+		}
+		out.WriteString("${" + part.String() + "}")
+	}
+	return out.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token
 	Elements []Expression
@@ -357,6 +513,7 @@ type ArrayLiteral struct {
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) Type() token.Type     { return al.Token.Type }
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Pos       { return al.Token.Pos() }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 	elements := []string{}
@@ -378,6 +535,12 @@ type IndexExpression struct {
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) Type() token.Type     { return ie.Token.Type }
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Pos {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos()
+}
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -396,12 +559,27 @@ type HashLiteral struct {
 func (h *HashLiteral) expressionNode()      {}
 func (h *HashLiteral) Type() token.Type     { return h.Token.Type }
 func (h *HashLiteral) TokenLiteral() string { return h.Token.Literal }
+func (h *HashLiteral) Pos() token.Pos       { return h.Token.Pos() }
+
+// Keys returns the keys of the pairs in the order they appear in the
+// source.
+func (h *HashLiteral) Keys() []Expression {
+	keys := make([]Expression, 0, len(h.Pairs))
+	for k := range h.Pairs {
+		keys = append(keys, k)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].Pos().Before(keys[j].Pos())
+	})
+	return keys
+}
+
 func (h *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for k, v := range h.Pairs {
-		pairs = append(pairs, k.String()+":"+v.String())
+	for _, k := range h.Keys() {
+		pairs = append(pairs, k.String()+":"+h.Pairs[k].String())
 	}
 
 	out.WriteString("{")
@@ -419,6 +597,7 @@ type RegExLiteral struct {
 func (rel *RegExLiteral) expressionNode()      {}
 func (rel *RegExLiteral) Type() token.Type     { return rel.Token.Type }
 func (rel *RegExLiteral) TokenLiteral() string { return rel.Token.Literal }
+func (rel *RegExLiteral) Pos() token.Pos       { return rel.Token.Pos() }
 func (rel *RegExLiteral) String() string       { return rel.Value }
 
 type MethodCallExpression struct {
@@ -430,6 +609,12 @@ type MethodCallExpression struct {
 func (mc *MethodCallExpression) expressionNode()      {}
 func (mc *MethodCallExpression) Type() token.Type     { return mc.Token.Type }
 func (mc *MethodCallExpression) TokenLiteral() string { return mc.Token.Literal }
+func (mc *MethodCallExpression) Pos() token.Pos {
+	if mc.Object != nil {
+		return mc.Object.Pos()
+	}
+	return mc.Token.Pos()
+}
 func (mc *MethodCallExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(mc.Object.String())
@@ -447,6 +632,12 @@ type Pipe struct {
 func (p *Pipe) expressionNode()      {}
 func (p *Pipe) Type() token.Type     { return p.Token.Type }
 func (p *Pipe) TokenLiteral() string { return p.Token.Literal }
+func (p *Pipe) Pos() token.Pos {
+	if p.Left != nil {
+		return p.Left.Pos()
+	}
+	return p.Token.Pos()
+}
 func (p *Pipe) String() string {
 	var out bytes.Buffer
 	out.WriteString(p.Left.String())
@@ -462,4 +653,5 @@ type NihilLiteral struct {
 func (n *NihilLiteral) expressionNode()      {}
 func (n *NihilLiteral) Type() token.Type     { return n.Token.Type }
 func (n *NihilLiteral) TokenLiteral() string { return n.Token.Literal }
+func (n *NihilLiteral) Pos() token.Pos       { return n.Token.Pos() }
 func (n *NihilLiteral) String() string       { return n.Token.Literal }
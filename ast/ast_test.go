@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/dedisuryadi/bilang/token"
@@ -26,3 +27,76 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestInspect(t *testing.T) {
+	ident := func(name string) *Identifier {
+		return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	}
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{Expression: &FunctionLiteral{
+				Parameters: []*Identifier{ident("a")},
+				Body: &BlockStatement{Statements: []Statement{
+					&ExpressionStatement{Expression: &InfixExpression{Left: ident("a"), Operator: "+", Right: ident("b")}},
+				}},
+			}},
+			&ExpressionStatement{Expression: ident("c")},
+		},
+	}
+
+	var names []string
+	Inspect(program, func(node Node) bool {
+		if id, ok := node.(*Identifier); ok {
+			names = append(names, id.Value)
+		}
+		return true
+	})
+	if strings.Join(names, " ") != "a a b c" {
+		t.Errorf("wrong identifiers visited. got=%q", names)
+	}
+
+	names = nil
+	Inspect(program, func(node Node) bool {
+		if id, ok := node.(*Identifier); ok {
+			names = append(names, id.Value)
+		}
+		_, fn := node.(*FunctionLiteral)
+		return !fn
+	})
+	if strings.Join(names, " ") != "c" {
+		t.Errorf("function literal should be skipped. got=%q", names)
+	}
+}
+
+func TestFprint(t *testing.T) {
+	ident := func(name string, col int) *Identifier {
+		return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name, Line: 1, Col: col}, Value: name}
+	}
+	program := &Program{
+		Statements: []Statement{
+			&VarStatement{
+				Token: token.Token{Type: token.VAR, Literal: "var", Line: 1, Col: 1},
+				Name:  ident("x", 5),
+				Value: &PrefixExpression{
+					Token:    token.Token{Type: token.MINUS, Literal: "-", Line: 1, Col: 9},
+					Operator: "-",
+					Right:    ident("y", 10),
+				},
+			},
+		},
+	}
+
+	var out strings.Builder
+	if err := Fprint(&out, program); err != nil {
+		t.Fatal(err)
+	}
+	want := `Program 1:1
+  VarStatement 1:1
+    Identifier x 1:5
+    PrefixExpression - 1:9
+      Identifier y 1:10
+`
+	if out.String() != want {
+		t.Errorf("wrong tree.\ngot:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,73 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Fprint writes the tree rooted at node to w, one node per line indented
+// by its depth, with the node's position and, for names, literals and
+// operators, its value, e.g.
+//
+//	VarStatement 1:1
+//	  Identifier x 1:5
+//	  InfixExpression + 1:11
+//	    IntegerLiteral 1 1:9
+//	    IntegerLiteral 2 1:13
+func Fprint(w io.Writer, node Node) error {
+	var err error
+	write := func(n Node, depth int) {
+		if err == nil {
+			_, err = fmt.Fprintln(w, strings.Repeat("  ", depth)+describe(n))
+		}
+	}
+
+	var visit func(n Node, depth int)
+	visit = func(n Node, depth int) {
+		Inspect(n, func(child Node) bool {
+			if child == n {
+				return true
+			}
+			write(child, depth)
+			visit(child, depth+1)
+			return false
+		})
+	}
+	if !isNil(node) {
+		write(node, 0)
+		visit(node, 1)
+	}
+	return err
+}
+
+// describe names n by its type, followed by its value when it has one and
+// its position when it is known.
+func describe(n Node) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(fmt.Sprintf("%T", n), "*"), "ast.")
+	var value string
+	switch n := n.(type) {
+	case *Identifier:
+		value = n.Value
+	case *IntegerLiteral, *FloatLiteral, *Boolean, *RegExLiteral:
+		value = n.TokenLiteral()
+	case *StringLiteral:
+		value = strconv.Quote(n.Value)
+	case *PrefixExpression:
+		value = n.Operator
+	case *InfixExpression:
+		value = n.Operator
+	case *FunctionLiteral:
+		value = n.Name
+	}
+
+	parts := []string{name}
+	if value != "" {
+		parts = append(parts, value)
+	}
+	if pos := n.Pos(); pos.IsValid() {
+		parts = append(parts, pos.String())
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,115 @@
+package ast
+
+// Inspect traverses the tree rooted at node in depth-first order, calling
+// f for every node in source order. When f returns false the children of
+// that node are skipped. Nil children are never passed to f.
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *VarStatement:
+		Inspect(n.Name, f)
+		Inspect(n.Value, f)
+	case *KonstStatement:
+		Inspect(n.Name, f)
+		Inspect(n.Value, f)
+	case *PilihStatement:
+		Inspect(n.ReturnValue, f)
+	case *LemparStatement:
+		Inspect(n.Value, f)
+	case *ImporStatement:
+		Inspect(n.Alias, f)
+		Inspect(n.Path, f)
+	case *EksporStatement:
+		Inspect(n.Statement, f)
+	case *ExpressionStatement:
+		Inspect(n.Expression, f)
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *PrefixExpression:
+		Inspect(n.Right, f)
+	case *InfixExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	case *JikaExpression:
+		Inspect(n.Condition, f)
+		Inspect(n.Consequence, f)
+		Inspect(n.Alternative, f)
+	case *CobaExpression:
+		Inspect(n.Body, f)
+		Inspect(n.Param, f)
+		Inspect(n.Handler, f)
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Inspect(p, f)
+		}
+		Inspect(n.Body, f)
+	case *LoopLiteral:
+		for _, kv := range n.KV {
+			Inspect(kv, f)
+		}
+		Inspect(n.Iter, f)
+		Inspect(n.Body, f)
+	case *PilahExpression:
+		Inspect(n.Target, f)
+		for i, c := range n.Conditions {
+			Inspect(c, f)
+			if i < len(n.Values) {
+				Inspect(n.Values[i], f)
+			}
+		}
+	case *CallExpression:
+		Inspect(n.Function, f)
+		for _, a := range n.Arguments {
+			Inspect(a, f)
+		}
+	case *ArrayLiteral:
+		for _, e := range n.Elements {
+			Inspect(e, f)
+		}
+	case *InterpolatedString:
+		for _, p := range n.Parts {
+			Inspect(p, f)
+		}
+	case *IndexExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Index, f)
+	case *HashLiteral:
+		for _, k := range n.Keys() {
+			Inspect(k, f)
+			Inspect(n.Pairs[k], f)
+		}
+	case *MethodCallExpression:
+		Inspect(n.Object, f)
+		Inspect(n.Call, f)
+	case *Pipe:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	}
+}
+
+// isNil reports whether node is nil, including a typed nil pointer stored
+// in the interface, which the parser produces for optional children.
+func isNil(node Node) bool {
+	switch n := node.(type) {
+	case nil:
+		return true
+	case *Identifier:
+		return n == nil
+	case *ExpressionStatement:
+		return n == nil
+	case *BlockStatement:
+		return n == nil
+	case *StringLiteral:
+		return n == nil
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+// Package bilang embeds the Bilang interpreter in Go programs.
+//
+//	in := bilang.New()
+//	_ = in.Set("umur", 30)
+//	_ = in.Register("sapa", func(nama string) string { return "halo " + nama })
+//	v, err := in.Eval(ctx, `sapa("dedi") + ", umur ${umur}"`)
+//
+// Values cross between Go and Bilang as described by ToObject and
+// FromObject. The command line interpreter lives in cmd/bilang.
+package bilang
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+// Interpreter evaluates Bilang source in a global environment of its own,
+// which keeps its variables between calls to Eval and EvalFile. It is safe
+// for concurrent use, evaluations take turns. Functions registered with
+// Register may call Get and Set, but not Eval.
+type Interpreter struct {
+	mu     sync.Mutex // held by Eval and EvalFile
+	script *evaluator.Script
+}
+
+// New returns an Interpreter whose scripts may impor .bi files.
+func New() *Interpreter {
+	script := evaluator.NewScript()
+	script.SetModuleLoader(parser.ParseFile)
+	return &Interpreter{script: script}
+}
+
+// Eval parses and runs src and returns the value of its last statement,
+// converted by FromObject. Syntax errors are returned as the *parser.Parser
+// that found them, runtime errors as an *Error. The script stops once ctx
+// is done, the error then wraps ctx.Err().
+func (in *Interpreter) Eval(ctx context.Context, src string) (interface{}, error) {
+	return in.run(ctx, "", src)
+}
+
+// EvalFile is Eval for the file at path, relative imports are resolved
+// against its directory.
+func (in *Interpreter) EvalFile(ctx context.Context, path string) (interface{}, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return in.run(ctx, path, string(src))
+}
+
+func (in *Interpreter) run(ctx context.Context, file, src string) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p := parser.New(lexer.New(src))
+	p.SetFile(file)
+	program, err := p.ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.script.SetFile(file)
+	result := in.script.RunContext(ctx, program)
+	if errObj, ok := result.(*evaluator.Error); ok {
+		return nil, &Error{Err: errObj, file: file, src: src}
+	}
+	return FromObject(result), nil
+}
+
+// SetLimits bounds every later call to Eval and EvalFile, see
+// evaluator.Limits. A script exceeding them fails with an *Error of kind
+// evaluator.LimitError.
+func (in *Interpreter) SetLimits(l evaluator.Limits) {
+	in.script.SetLimits(l)
+}
+
+// SetStdout sends what scripts print with stdout and println to w,
+// os.Stdout by default.
+func (in *Interpreter) SetStdout(w io.Writer) {
+	in.script.SetStdout(w)
+}
+
+// SetStdin makes baca_baris read from r, os.Stdin by default.
+func (in *Interpreter) SetStdin(r io.Reader) {
+	in.script.SetStdin(r)
+}
+
+// Get returns the global called name converted by FromObject.
+func (in *Interpreter) Get(name string) (interface{}, bool) {
+	val, ok := in.script.Global(name)
+	if !ok {
+		return nil, false
+	}
+	return FromObject(val), true
+}
+
+// Set assigns v, converted by ToObject, to the global variable called
+// name. Konstanta can't be assigned.
+func (in *Interpreter) Set(name string, v interface{}) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	val, err := ToObject(v)
+	if err != nil {
+		return fmt.Errorf("bilang: set %s: %w", name, err)
+	}
+	if errObj := in.script.SetGlobal(name, val); errObj != nil {
+		return fmt.Errorf("bilang: set %s: %s", name, errObj.Message)
+	}
+	return nil
+}
+
+// Register makes the Go function fn callable from scripts as the builtin
+// name, see NewBuiltin for how its arguments and results are converted.
+// A name such as "teks.besar" puts the function in a namespace, and the
+// name of a standard builtin replaces it for this interpreter only.
+func (in *Interpreter) Register(name string, fn interface{}) error {
+	for _, part := range strings.SplitN(name, ".", 2) {
+		if err := checkName(part); err != nil {
+			return err
+		}
+	}
+	b, err := NewBuiltin(name, fn)
+	if err != nil {
+		return err
+	}
+	in.script.Builtins().Register(name, b.Fn)
+	return nil
+}
+
+// Remove removes builtins from this interpreter, e.g. "println" or "math.*"
+// for a whole namespace, see evaluator.Registry.Remove.
+func (in *Interpreter) Remove(names ...string) {
+	in.script.Builtins().Remove(names...)
+}
+
+func checkName(name string) error {
+	l := lexer.New(name)
+	tok, err := l.NextToken()
+	if err != nil || tok.Type != token.IDENT || tok.Literal != name {
+		return fmt.Errorf("bilang: %q is not a valid identifier", name)
+	}
+	return nil
+}
+
+// Error is a runtime error that stopped a script. Err holds its kind,
+// position and the call stack at the time.
+type Error struct {
+	Err  *evaluator.Error
+	file string
+	src  string // of file, for the excerpt
+}
+
+// Error renders the error like the bilang command does: with its
+// position, an excerpt of the source and a traceback when it was raised
+// inside a function.
+func (e *Error) Error() string {
+	src := ""
+	if e.Err.File == e.file {
+		src = e.src
+	}
+	msg := token.FormatError(e.Err.File, src, e.Err.Pos(), e.Err.Inspect())
+	if len(e.Err.Stack) > 1 {
+		msg += "\n\n" + e.Err.Traceback()
+	}
+	return msg
+}
+
+// Unwrap returns the cause of a LimitError, evaluator.ErrLimit or the
+// error of the context, so errors.Is can tell why the script stopped.
+func (e *Error) Unwrap() error {
+	return e.Err.Cause
+}
@@ -0,0 +1,318 @@
+package bilang
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/parser"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 + 2", int64(3)},
+		{"1.5 * 2", 3.0},
+		{`"halo" + " dunia"`, "halo dunia"},
+		{"5 > 3", true},
+		{`[1, "a", [benar]]`, []interface{}{int64(1), "a", []interface{}{true}}},
+		{`{"a": 1, "b": [2]}`, map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2)}}},
+		{`{1: "a", "b": 2}`, map[interface{}]interface{}{int64(1): "a", "b": int64(2)}},
+	}
+	for _, tt := range tests {
+		got, err := New().Eval(context.Background(), tt.input)
+		if err != nil {
+			t.Errorf("%q: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: wrong result. expected=%#v, got=%#v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestEvalKeepsGlobals(t *testing.T) {
+	in := New()
+	ctx := context.Background()
+	if _, err := in.Eval(ctx, "var x = 20; konst k = 1;"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := in.Eval(ctx, "x + 22")
+	if err != nil || got != int64(42) {
+		t.Fatalf("wrong result. got=%v, %v", got, err)
+	}
+	if x, ok := in.Get("x"); !ok || x != int64(20) {
+		t.Errorf("Get(x) wrong. got=%v, %v", x, ok)
+	}
+	if _, ok := in.Get("y"); ok {
+		t.Errorf("Get(y) found an undefined variable")
+	}
+	if err := in.Set("k", 2); err == nil || !strings.Contains(err.Error(), "konstanta k") {
+		t.Errorf("Set reassigned a konstanta. got=%v", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	in := New()
+	values := map[string]interface{}{
+		"i":   int8(-3),
+		"u":   uint32(7),
+		"f":   float32(0.5),
+		"s":   "teks",
+		"b":   true,
+		"n":   nil,
+		"arr": []string{"a", "b"},
+		"m":   map[string]int{"x": 1},
+		"obj": &evaluator.Integer{Value: 9},
+	}
+	for name, v := range values {
+		if err := in.Set(name, v); err != nil {
+			t.Fatalf("Set(%s): %s", name, err)
+		}
+	}
+	got, err := in.Eval(context.Background(), `[i, u, f, s, b, n, arr, m["x"], obj]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{int64(-3), int64(7), 0.5, "teks", true, nil, []interface{}{"a", "b"}, int64(1), int64(9)}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong result. expected=%#v, got=%#v", expected, got)
+	}
+
+	errs := map[string]interface{}{
+		"ch":  make(chan int),
+		"big": uint64(1 << 63),
+		"1x":  1,
+		"var": 1,
+	}
+	for name, v := range errs {
+		if err := in.Set(name, v); err == nil {
+			t.Errorf("Set(%s, %T) expected an error", name, v)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	in := New()
+	funcs := map[string]interface{}{
+		"sapa": func(nama string) string { return "halo " + nama },
+		"jumlah": func(xs ...int) int {
+			s := 0
+			for _, x := range xs {
+				s += x
+			}
+			return s
+		},
+		"bagi": func(a, b float64) (float64, error) {
+			if b == 0 {
+				return 0, errors.New("pembagi nol")
+			}
+			return a / b, nil
+		},
+		"kunci": func(m map[string]interface{}) []string {
+			keys := []string{}
+			for k := range m {
+				keys = append(keys, k)
+			}
+			return keys
+		},
+		"mentah":     func(args ...evaluator.Object) evaluator.Object { return &evaluator.Integer{Value: int64(len(args))} },
+		"panik":      func() { panic("aduh") },
+		"jenis":      func(v interface{}) string { return reflect.TypeOf(v).String() },
+		"teks.besar": strings.ToUpper,
+		"panjang":    func(s string) int { return -1 },
+	}
+	for name, fn := range funcs {
+		if err := in.Register(name, fn); err != nil {
+			t.Fatalf("Register(%s): %s", name, err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`sapa("dedi")`, "halo dedi"},
+		{`jumlah()`, int64(0)},
+		{`jumlah(1, 2, 3)`, int64(6)},
+		{`bagi(1, 4)`, 0.25},
+		{`kunci({"a": 1})`, []interface{}{"a"}},
+		{`mentah(1, "a")`, int64(2)},
+		{`jenis([1])`, "[]interface {}"},
+		{`"x" |> sapa`, "halo x"},
+		{`teks.besar("abc")`, "ABC"},
+		{`panjang("abc")`, int64(-1)},
+	}
+	for _, tt := range tests {
+		got, err := in.Eval(context.Background(), tt.input)
+		if err != nil {
+			t.Errorf("%q: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: wrong result. expected=%#v, got=%#v", tt.input, tt.expected, got)
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`sapa(1)`, "TypeError: argument 1 to `sapa`: cannot use INTEGER as string"},
+		{`sapa()`, "TypeError: wrong number of arguments. got=0, want=1"},
+		{`jumlah(1, "2")`, "TypeError: argument 2 to `jumlah`: cannot use STRING as int"},
+		{`bagi(1, 0)`, "RuntimeError: bagi: pembagi nol"},
+		{`panik()`, "RuntimeError: panik: panic: aduh"},
+	}
+	for _, tt := range errTests {
+		_, err := in.Eval(context.Background(), tt.input)
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Errorf("%q: expected a runtime error, got %v", tt.input, err)
+			continue
+		}
+		if got := e.Err.Kind + ": " + e.Err.Message; got != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	if _, err := New().Eval(context.Background(), `panjang("abc")`); err != nil {
+		t.Errorf("Register changed the builtins of another interpreter: %s", err)
+	}
+	in.Remove("teks.*", "sapa")
+	if _, err := in.Eval(context.Background(), `sapa("x")`); err == nil {
+		t.Errorf("sapa not removed")
+	}
+
+	if err := in.Register("teks.", strings.ToLower); err == nil {
+		t.Errorf("Register accepted an invalid name")
+	}
+	if err := in.Register("bukan", 1); err == nil {
+		t.Errorf("Register accepted a non function")
+	}
+	if err := in.Register("banyak", func() (int, int) { return 0, 0 }); err == nil {
+		t.Errorf("Register accepted a function returning two values")
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	in := New()
+	_, err := in.Eval(context.Background(), "var = 1;")
+	if _, ok := err.(*parser.Parser); !ok {
+		t.Errorf("expected a parse error, got %T %v", err, err)
+	}
+
+	_, err = in.Eval(context.Background(), "var f = fn(x) { x / 0 };\nf(1)")
+	expected := "1:19: ERROR: division by zero: 1 / 0\n" +
+		"    var f = fn(x) { x / 0 };\n" +
+		"                      ^\n\n" +
+		"f(1)\n" +
+		"\t1:19\n" +
+		"main\n" +
+		"\t2:1"
+	if err == nil || err.Error() != expected {
+		t.Errorf("wrong error.\nexpected=%q\ngot=%v", expected, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := in.Eval(ctx, "1"); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	in := New()
+	in.SetLimits(evaluator.Limits{MaxSteps: 10000})
+	_, err := in.Eval(context.Background(), "var f = fn() { f() }; f()")
+	if !errors.Is(err, evaluator.ErrLimit) {
+		t.Fatalf("expected the step limit to stop the script, got %v", err)
+	}
+	if kind := err.(*Error).Err.Kind; kind != evaluator.LimitError {
+		t.Errorf("wrong error kind. got=%s", kind)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	in.SetLimits(evaluator.Limits{})
+	if _, err := in.Eval(ctx, "var g = fn(n) { g(n + 1) }; g(0)"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestStdio(t *testing.T) {
+	var out bytes.Buffer
+	in := New()
+	in.SetStdout(&out)
+	in.SetStdin(strings.NewReader("dedi\n"))
+	if _, err := in.Eval(context.Background(), `println("halo " + baca_baris())`); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "halo dedi\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
+
+func TestConcurrentEval(t *testing.T) {
+	in := New()
+	if err := in.Set("n", 0); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := in.Eval(context.Background(), "var x = [n, n] |> panjang"); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			if err := in.Set("n", i); err != nil {
+				t.Error(err)
+			}
+			in.Get("x")
+		}(i)
+	}
+	wg.Wait()
+	if x, _ := in.Get("x"); x != int64(2) {
+		t.Errorf("wrong result. got=%v", x)
+	}
+}
+
+func TestEvalFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bilang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"main.bi": "impor \"lib.bi\";\nlib.ganda(21)",
+		"lib.bi":  "ekspor konst ganda = x => x * 2",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := New().EvalFile(context.Background(), filepath.Join(dir, "main.bi"))
+	if err != nil || got != int64(42) {
+		t.Errorf("wrong result. got=%v, %v", got, err)
+	}
+	if _, err := New().EvalFile(context.Background(), filepath.Join(dir, "missing.bi")); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
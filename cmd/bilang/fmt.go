@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/dedisuryadi/bilang/format"
+)
+
+// formatFiles implements `bilang fmt [-w] files...`: it prints the files
+// formatted, or with -w rewrites the ones that change. Without files it
+// formats stdin.
+func formatFiles(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bilang fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {}
+	write := flags.Bool("w", false, "")
+	if err := flags.Parse(args); err != nil {
+		_, _ = fmt.Fprint(stderr, "\n", usage)
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			_, _ = fmt.Fprint(stderr, "bilang fmt: -w membutuhkan file\n\n", usage)
+			return exitUsage
+		}
+		src, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "bilang fmt: %s\n", err)
+			return exitIOError
+		}
+		out, err := format.File("<stdin>", src)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return exitParseError
+		}
+		_, _ = stdout.Write(out)
+		return exitOK
+	}
+
+	code := exitOK
+	for _, path := range flags.Args() {
+		if c := formatFile(path, *write, stdout, stderr); c != exitOK {
+			code = c
+		}
+	}
+	return code
+}
+
+func formatFile(path string, write bool, stdout, stderr io.Writer) int {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "bilang fmt: %s\n", err)
+		return exitIOError
+	}
+	out, err := format.File(path, src)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return exitParseError
+	}
+	if !write {
+		_, _ = stdout.Write(out)
+		return exitOK
+	}
+	if bytes.Equal(src, out) {
+		return exitOK
+	}
+	info, err := os.Stat(path)
+	if err == nil {
+		err = ioutil.WriteFile(path, out, info.Mode().Perm())
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "bilang fmt: %s\n", err)
+		return exitIOError
+	}
+	return exitOK
+}
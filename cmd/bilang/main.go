@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lsp"
+	"github.com/dedisuryadi/bilang/repl"
+	"github.com/dedisuryadi/bilang/vm"
+)
+
+const (
+	exitOK           = 0
+	exitRuntimeError = 1
+	exitParseError   = 2
+	exitUsage        = 2
+	exitIOError      = 3
+)
+
+const usage = `Penggunaan:
+    bilang run file.bi [args...]   jalankan file skrip, "-" untuk membaca dari stdin
+    bilang -e 'ekspresi' [args...] evaluasi ekspresi lalu cetak hasilnya
+    bilang repl                    jalankan REPL interaktif
+    bilang fmt [-w] [file.bi...]   rapikan kode, -w menulis hasilnya ke file
+    bilang lsp                     jalankan language server untuk editor lewat stdio
+    bilang test [-run re] [-v] [path...]
+                                   jalankan fungsi uji_* di file *_test.bi, path/... termasuk subdirektori
+    bilang                         jalankan REPL, atau skrip dari stdin jika bukan terminal
+
+Opsi:
+    -backend eval|vm               eval menelusuri AST (bawaan), vm mengompilasi ke bytecode
+    -max-depth n                   batas kedalaman pemanggilan fungsi (bawaan 10000)
+    -timeout durasi                hentikan skrip setelah durasi, misalnya 5s (bawaan tanpa batas)
+
+Kode keluar: 0 sukses, 1 runtime error atau uji gagal, 2 parse error atau penggunaan salah,
+3 file tidak bisa dibaca atau ditulis.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bilang", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {}
+	backend := flags.String("backend", "eval", "")
+	expr := flags.String("e", "", "")
+	maxDepth := flags.Int("max-depth", evaluator.DefaultMaxDepth, "")
+	timeout := flags.Duration("timeout", 0, "")
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		_, _ = fmt.Fprint(stdout, usage)
+		return exitOK
+	} else if err != nil {
+		_, _ = fmt.Fprint(stderr, "\n", usage)
+		return exitUsage
+	}
+	newBackend, ok := backends[*backend]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "bilang: backend tidak dikenal %q\n\n%s", *backend, usage)
+		return exitUsage
+	}
+	newScript := func() repl.Script {
+		script := newBackend()
+		script.SetMaxDepth(*maxDepth)
+		return script
+	}
+	args = flags.Args()
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if isFlagSet(flags, "e") {
+		return execute(ctx, "-e", *expr, args, newScript(), stdin, stdout, stderr, true)
+	}
+
+	if len(args) == 0 {
+		if f, ok := stdin.(*os.File); ok && repl.IsTerminal(f) {
+			repl.Start(stdin, stdout, newScript())
+			return exitOK
+		}
+		return runFile(ctx, "-", nil, newScript(), stdin, stdout, stderr)
+	}
+
+	switch cmd := args[0]; cmd {
+	case "run":
+		if len(args) < 2 {
+			_, _ = fmt.Fprint(stderr, "bilang run: file skrip belum diberikan\n\n", usage)
+			return exitUsage
+		}
+		return runFile(ctx, args[1], args[2:], newScript(), stdin, stdout, stderr)
+
+	case "repl":
+		repl.Start(stdin, stdout, newScript())
+		return exitOK
+
+	case "fmt":
+		return formatFiles(args[1:], stdin, stdout, stderr)
+
+	case "test":
+		return testFiles(ctx, args[1:], newScript, stdout, stderr)
+
+	case "lsp":
+		if err := lsp.Serve(stdin, stdout); err != nil {
+			_, _ = fmt.Fprintln(stderr, "bilang lsp:", err)
+			return exitRuntimeError
+		}
+		return exitOK
+
+	case "help":
+		_, _ = fmt.Fprint(stdout, usage)
+		return exitOK
+
+	default:
+		_, _ = fmt.Fprintf(stderr, "bilang: perintah tidak dikenal %q\n\n%s", cmd, usage)
+		return exitUsage
+	}
+}
+
+var backends = map[string]func() repl.Script{
+	"eval": func() repl.Script { return evaluator.NewScript() },
+	"vm":   func() repl.Script { return vm.NewScript() },
+}
+
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bilang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"ok.bi":      "var x = 1 + 2;",
+		"runtime.bi": "var x = 1;\nx / 0;",
+		"parse.bi":   "var = 5;",
+		"trace.bi":   "var bagi = fn(a, b) { a / b };\nbagi(1, 0);",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"run", filepath.Join(dir, "ok.bi")}, exitOK, "", ""},
+		{[]string{"run", filepath.Join(dir, "runtime.bi")}, exitRuntimeError, "", "runtime.bi:2:3: ERROR: division by zero: 1 / 0\n    x / 0;\n      ^\n"},
+		{[]string{"run", filepath.Join(dir, "parse.bi")}, exitParseError, "", "parse.bi:1:5: expected identifier, found '='\n    var = 5;\n        ^\n"},
+		{[]string{"run", filepath.Join(dir, "missing.bi")}, exitIOError, "", "missing.bi"},
+		{[]string{"run"}, exitUsage, "", "file skrip belum diberikan"},
+		{[]string{"-e", "1 + 2"}, exitOK, "3\n", ""},
+		{[]string{"-e", "var x = 1;"}, exitOK, "", ""},
+		{[]string{"-e", "argv", "a", "b"}, exitOK, "[a, b]\n", ""},
+		{[]string{"-e", "panjang(argv)"}, exitOK, "0\n", ""},
+		{[]string{"-e", "argv = [];"}, exitRuntimeError, "", "argv"},
+		{[]string{"-e", "1 +"}, exitParseError, "", "expected expression, found end of input"},
+		{[]string{"-e", "y"}, exitRuntimeError, "", "identifier not found: y"},
+		{[]string{"foo"}, exitUsage, "", `perintah tidak dikenal "foo"`},
+		{[]string{"-backend", "vm", "-e", "1 + 2"}, exitOK, "3\n", ""},
+		{[]string{"-backend=vm", "-e", "argv", "a"}, exitOK, "[a]\n", ""},
+		{[]string{"-backend=vm", "run", filepath.Join(dir, "runtime.bi")}, exitRuntimeError, "", "runtime.bi:2:3: ERROR: division by zero: 1 / 0\n    x / 0;\n      ^\n"},
+		{[]string{"run", filepath.Join(dir, "trace.bi")}, exitRuntimeError, "", "      ^\n\nbagi(1, 0)\n\t" + filepath.Join(dir, "trace.bi") + ":1:25\nmain\n\t"},
+		{[]string{"-backend=vm", "run", filepath.Join(dir, "trace.bi")}, exitRuntimeError, "", "      ^\n\nbagi(1, 0)\n\t"},
+		{[]string{"-max-depth", "10", "-e", "var f = fn(n) { 1 + f(n) }; f(1)"}, exitRuntimeError, "", "stack overflow: kedalaman pemanggilan melebihi 10"},
+		{[]string{"-backend=vm", "-max-depth=10", "-e", "var f = fn(n) { 1 + f(n) }; f(1)"}, exitRuntimeError, "", "stack overflow: kedalaman pemanggilan melebihi 10"},
+		{[]string{"-timeout", "20ms", "-e", "var f = fn(n) { f(n + 1) }; f(0)"}, exitRuntimeError, "", "evaluation stopped: context deadline exceeded"},
+		{[]string{"-backend=vm", "-timeout=20ms", "-e", "var f = fn(n) { f(n + 1) }; f(0)"}, exitRuntimeError, "", "evaluation stopped: context deadline exceeded"},
+		{[]string{"-backend", "jit", "-e", "1"}, exitUsage, "", `backend tidak dikenal "jit"`},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(""), &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("%v: exit code wrong. want=%d, got=%d (stderr %q)", tt.args, tt.code, code, stderr.String())
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: stdout wrong. want=%q, got=%q", tt.args, tt.stdout, stdout.String())
+		}
+		if tt.stderr == "" && stderr.Len() > 0 || !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("%v: stderr wrong. want %q, got=%q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
+
+func TestRunStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"run", "-"}, strings.NewReader("konst a = 1;\na = 2;"), &stdout, &stderr)
+	if code != exitRuntimeError {
+		t.Fatalf("exit code wrong. want=%d, got=%d", exitRuntimeError, code)
+	}
+	if !strings.HasPrefix(stderr.String(), "<stdin>:") {
+		t.Errorf("stderr should name <stdin>, got=%q", stderr.String())
+	}
+}
+
+func TestRunNoCommand(t *testing.T) {
+	// stdin that isn't a terminal is run as a script
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-backend", "vm"}, strings.NewReader("println(1 + 2)\n1 / 0"), &stdout, &stderr)
+	if code != exitRuntimeError {
+		t.Fatalf("exit code wrong. want=%d, got=%d (stderr %q)", exitRuntimeError, code, stderr.String())
+	}
+	if stdout.String() != "3\n" {
+		t.Errorf("stdout wrong. want=%q, got=%q", "3\n", stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "<stdin>:2:3: ERROR: division by zero") {
+		t.Errorf("stderr should name <stdin>, got=%q", stderr.String())
+	}
+
+	f, err := ioutil.TempFile(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("println(argv)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := run(nil, f, &stdout, &stderr); code != exitOK || stdout.String() != "[]\n" {
+		t.Errorf("file as stdin: code=%d, stdout=%q", code, stdout.String())
+	}
+
+	// /dev/null is a character device, but not a terminal
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	stdout.Reset()
+	if code := run(nil, null, &stdout, &stderr); code != exitOK || stdout.Len() > 0 {
+		t.Errorf("%s as stdin: code=%d, stdout=%q", os.DevNull, code, stdout.String())
+	}
+}
+
+func TestRunIO(t *testing.T) {
+	src := `var nama = baca_baris(); stdout("halo ", nama, "\n"); println(baca_baris(), baca_baris())`
+	for _, backend := range []string{"eval", "vm"} {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"-backend", backend, "-e", src}, strings.NewReader("dedi\r\nbaris kedua"), &stdout, &stderr)
+		if code != exitOK {
+			t.Fatalf("%s: exit code wrong. got=%d (stderr %q)", backend, code, stderr.String())
+		}
+		if expected := "halo dedi\nbaris kedua\nnihil\n"; stdout.String() != expected {
+			t.Errorf("%s: stdout wrong. want=%q, got=%q", backend, expected, stdout.String())
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.bi")
+	if err := ioutil.WriteFile(path, []byte("var x=1+2;println( x )"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.bi")
+	if err := ioutil.WriteFile(bad, []byte("var = 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const formatted = "var x = 1 + 2\nprintln(x)\n"
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"fmt", path}, "", exitOK, formatted, ""},
+		{[]string{"fmt"}, "1+2", exitOK, "1 + 2\n", ""},
+		{[]string{"fmt", bad}, "", exitParseError, "", "bad.bi:1:5: expected identifier"},
+		{[]string{"fmt", filepath.Join(dir, "missing.bi")}, "", exitIOError, "", "missing.bi"},
+		{[]string{"fmt", "-w"}, "", exitUsage, "", "-w membutuhkan file"},
+		{[]string{"fmt", "-w", path}, "", exitOK, "", ""},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("%v: exit code wrong. want=%d, got=%d (stderr %q)", tt.args, tt.code, code, stderr.String())
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("%v: stdout wrong. want=%q, got=%q", tt.args, tt.stdout, stdout.String())
+		}
+		if tt.stderr == "" && stderr.Len() > 0 || !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("%v: stderr wrong. want %q, got=%q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != formatted {
+		t.Errorf("fmt -w wrote %q, want %q", got, formatted)
+	}
+}
+
+func TestLanguageServer(t *testing.T) {
+	frame := func(body string) string {
+		return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	tests := []struct {
+		stdin string
+		code  int
+	}{
+		{frame(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`) +
+			frame(`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`) +
+			frame(`{"jsonrpc":"2.0","method":"exit"}`), exitOK},
+		{frame(`{"jsonrpc":"2.0","method":"exit"}`), exitRuntimeError},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"lsp"}, strings.NewReader(tt.stdin), &stdout, &stderr); code != tt.code {
+			t.Errorf("exit code wrong. want=%d, got=%d (stderr %q)", tt.code, code, stderr.String())
+		}
+		if tt.code == exitOK && !strings.Contains(stdout.String(), `"hoverProvider":true`) {
+			t.Errorf("no capabilities in %q", stdout.String())
+		}
+	}
+}
+
+func TestTest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"hitung_test.bi": `var tambah = fn(a, b) { a + b }
+var uji_tambah = fn() { pastikan_sama(3, tambah(1, 2)) }
+var uji_gagal = fn() {
+    println("sebelum")
+    pastikan_sama(4, tambah(1, 2), "tambah")
+}
+`,
+		"global_test.bi": `var n = [1]
+var uji_satu = fn() { pastikan_sama(1, panjang(n)) }
+var uji_dua = fn() { pastikan_sama(1, panjang(n)) }
+`,
+		"sub/teks_test.bi":      "konst uji_teks = fn() { pastikan(panjang(\"ab\") == 2) }\n",
+		"sub/bukan_uji.bi":      "var uji_x = fn() { pastikan(salah) }\n",
+		"testdata/data_test.bi": "var uji_x = fn() { pastikan(salah) }\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	global := filepath.Join(dir, "global_test.bi")
+	hitung := filepath.Join(dir, "hitung_test.bi")
+	teks := filepath.Join(dir, "sub", "teks_test.bi")
+	rusak := filepath.Join(t.TempDir(), "rusak_test.bi")
+	if err := ioutil.WriteFile(rusak, []byte("var = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	failure := "--- FAIL: uji_gagal (0.00s)\n" +
+		"    " + hitung + ":5:5: ERROR: pastikan_sama gagal: tambah: harapan 4, didapat 3\n" +
+		"            pastikan_sama(4, tambah(1, 2), \"tambah\")\n" +
+		"            ^\n\n" +
+		"    uji_gagal()\n" +
+		"    \t" + hitung + ":5:5\n" +
+		"    main\n" +
+		"    \t" + hitung + ":3:5\n"
+
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"test", dir}, exitRuntimeError,
+			"ok  \t" + global + "\t0.000s\n" +
+				"sebelum\n" + failure +
+				"FAIL\t" + hitung + "\t0.000s\nFAIL\n", ""},
+		{[]string{"test", "-run", "tambah|teks", dir + "/..."}, exitOK,
+			"ok  \t" + global + "\t0.000s [tidak ada uji]\n" +
+				"ok  \t" + hitung + "\t0.000s\n" +
+				"ok  \t" + teks + "\t0.000s\n", ""},
+		{[]string{"-backend", "vm", "test", "-v", global, teks}, exitOK,
+			"=== RUN   uji_satu\n--- PASS: uji_satu (0.00s)\n=== RUN   uji_dua\n--- PASS: uji_dua (0.00s)\n" +
+				"ok  \t" + global + "\t0.000s\n" +
+				"=== RUN   uji_teks\n--- PASS: uji_teks (0.00s)\n" +
+				"ok  \t" + teks + "\t0.000s\n", ""},
+		{[]string{"-backend", "vm", "test", "-run", "gagal", hitung}, exitRuntimeError,
+			"sebelum\n" + failure + "FAIL\t" + hitung + "\t0.000s\nFAIL\n", ""},
+		{[]string{"test", rusak}, exitParseError,
+			rusak + ":1:5: expected identifier, found '='\n    var = 1\n        ^\nFAIL\t" + rusak + "\t[parse error]\nFAIL\n", ""},
+		{[]string{"test", filepath.Join(dir, "kosong")}, exitUsage, "", "kosong"},
+		{[]string{"test", "-run", "("}, exitUsage, "", "-run tidak valid"},
+	}
+	durations := regexp.MustCompile(`\d+\.\d+s`)
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(""), &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("%v: exit code wrong. want=%d, got=%d (stderr %q)", tt.args, tt.code, code, stderr.String())
+		}
+		// durations vary, only their format is checked
+		got := durations.ReplaceAllStringFunc(stdout.String(), func(d string) string {
+			dot := strings.Index(d, ".")
+			return strings.Repeat("0", dot) + "." + strings.Repeat("0", len(d)-dot-2) + "s"
+		})
+		if got != tt.stdout {
+			t.Errorf("%v: stdout wrong.\nwant=%q\ngot= %q", tt.args, tt.stdout, got)
+		}
+		if tt.stderr == "" && stderr.Len() > 0 || !strings.Contains(stderr.String(), tt.stderr) {
+			t.Errorf("%v: stderr wrong. want %q, got=%q", tt.args, tt.stderr, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+	"github.com/dedisuryadi/bilang/repl"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+// runFile executes the script at path, "-" reads it from stdin.
+func runFile(ctx context.Context, path string, args []string, script repl.Script, stdin io.Reader, stdout, stderr io.Writer) int {
+	var (
+		src []byte
+		err error
+	)
+	if path == "-" {
+		src, err = ioutil.ReadAll(stdin)
+		path = "<stdin>"
+	} else {
+		src, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "bilang: %s\n", err)
+		return exitIOError
+	}
+	return execute(ctx, path, string(src), args, script, stdin, stdout, stderr, false)
+}
+
+// execute parses src and runs it with script until ctx is done, reporting
+// errors prefixed with name. The script reads stdin with baca_baris and
+// writes stdout with println. The script sees args as the konstanta argv.
+func execute(ctx context.Context, name, src string, args []string, script repl.Script, stdin io.Reader, stdout, stderr io.Writer, printResult bool) int {
+	p := parser.New(lexer.New(src))
+	p.SetFile(name)
+	program, err := p.ParseProgram()
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return exitParseError
+	}
+
+	argv := &evaluator.Array{Elements: make([]evaluator.Object, len(args))}
+	for i, arg := range args {
+		argv.Elements[i] = &evaluator.String{Value: arg}
+	}
+
+	script.SetFile(name)
+	script.SetModuleLoader(parser.ParseFile)
+	script.DefineKonst("argv", argv)
+	script.SetStdout(stdout)
+	script.SetStdin(stdin)
+
+	result := script.RunContext(ctx, program)
+	if err, ok := result.(*evaluator.Error); ok {
+		_, _ = fmt.Fprintln(stderr, formatRuntimeError(name, src, err))
+		return exitRuntimeError
+	}
+	if printResult && result != nil && result.Type() != evaluator.NULL {
+		_, _ = fmt.Fprintln(stdout, result.Inspect())
+	}
+	return exitOK
+}
+
+// formatRuntimeError renders err with an excerpt of the file it was raised
+// in, which is an imported module rather than the script itself when
+// err.File differs from name. Errors raised inside a function are followed
+// by their traceback.
+func formatRuntimeError(name, src string, err *evaluator.Error) string {
+	file := name
+	if err.File != "" && err.File != name {
+		file = err.File
+		src = ""
+		if b, readErr := ioutil.ReadFile(file); readErr == nil {
+			src = string(b)
+		}
+	}
+	msg := token.FormatError(file, src, err.Pos(), err.Inspect())
+	if len(err.Stack) > 1 {
+		msg += "\n\n" + err.Traceback()
+	}
+	return msg
+}
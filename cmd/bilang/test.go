@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+	"github.com/dedisuryadi/bilang/repl"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+// testFiles implements `bilang test [-run regexp] [-v] [path...]`: it runs
+// the uji_* functions of the *_test.bi files in the paths, "." when none
+// are given, and reports them like go test does. A test fails when it
+// ends with an error, e.g. a failed pastikan.
+func testFiles(ctx context.Context, args []string, newScript func() repl.Script, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bilang test", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {}
+	run := flags.String("run", "", "")
+	verbose := flags.Bool("v", false, "")
+	if err := flags.Parse(args); err != nil {
+		_, _ = fmt.Fprint(stderr, "\n", usage)
+		return exitUsage
+	}
+	match, err := regexp.Compile(*run)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "bilang test: -run tidak valid: %s\n", err)
+		return exitUsage
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := testPaths(paths)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "bilang test: %s\n", err)
+		return exitUsage
+	}
+	if len(files) == 0 {
+		_, _ = fmt.Fprintf(stderr, "bilang test: tidak ada file *_test.bi di %s\n", strings.Join(paths, " "))
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, path := range files {
+		t := &tester{ctx: ctx, path: path, match: match, verbose: *verbose, newScript: newScript, out: stdout}
+		if c := t.run(); c > code {
+			code = c
+		}
+	}
+	if code != exitOK {
+		_, _ = fmt.Fprintln(stdout, "FAIL")
+	}
+	return code
+}
+
+// testPaths returns the *_test.bi files in paths, sorted. A directory
+// holds the files right in it, dir/... also those in its subdirectories
+// except testdata and the ones starting with '.' or '_', like go test.
+// A file is taken as it is.
+func testPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if dir := strings.TrimSuffix(path, "/..."); dir != path {
+			err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				name := info.Name()
+				if info.IsDir() && p != dir && (name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					return filepath.SkipDir
+				}
+				if !info.IsDir() && strings.HasSuffix(name, "_test.bi") {
+					files = append(files, p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*_test.bi"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// tester runs the tests of the file at path.
+type tester struct {
+	ctx       context.Context
+	path      string
+	match     *regexp.Regexp // of the tests to run, see -run
+	verbose   bool
+	newScript func() repl.Script
+	out       io.Writer
+}
+
+// run runs the tests of the file, each in a script of its own that runs
+// the file first, so no test sees the globals another one changed.
+func (t *tester) run() int {
+	start := time.Now()
+	b, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		_, _ = fmt.Fprintf(t.out, "FAIL\t%s\t%s\n", t.path, err)
+		return exitIOError
+	}
+	src := string(b)
+	p := parser.New(lexer.New(src))
+	p.SetFile(t.path)
+	program, err := p.ParseProgram()
+	if err != nil {
+		_, _ = fmt.Fprintf(t.out, "%s\nFAIL\t%s\t[parse error]\n", err, t.path)
+		return exitParseError
+	}
+
+	ran, failed := 0, false
+	for _, name := range testNames(program) {
+		if !t.match.MatchString(name.Value) {
+			continue
+		}
+		ran++
+		if t.verbose {
+			_, _ = fmt.Fprintf(t.out, "=== RUN   %s\n", name.Value)
+		}
+		began := time.Now()
+		err := t.runTest(program, name)
+		elapsed := time.Since(began).Seconds()
+		switch {
+		case err != nil:
+			failed = true
+			_, _ = fmt.Fprintf(t.out, "--- FAIL: %s (%.2fs)\n", name.Value, elapsed)
+			_, _ = fmt.Fprintln(t.out, indent(formatRuntimeError(t.path, src, err)))
+		case t.verbose:
+			_, _ = fmt.Fprintf(t.out, "--- PASS: %s (%.2fs)\n", name.Value, elapsed)
+		}
+	}
+
+	elapsed := time.Since(start).Seconds()
+	switch {
+	case failed:
+		_, _ = fmt.Fprintf(t.out, "FAIL\t%s\t%.3fs\n", t.path, elapsed)
+		return exitRuntimeError
+	case ran == 0:
+		_, _ = fmt.Fprintf(t.out, "ok  \t%s\t%.3fs [tidak ada uji]\n", t.path, elapsed)
+	default:
+		_, _ = fmt.Fprintf(t.out, "ok  \t%s\t%.3fs\n", t.path, elapsed)
+	}
+	return exitOK
+}
+
+// runTest runs program, then calls the function bound to name.
+func (t *tester) runTest(program *ast.Program, name *ast.Identifier) *evaluator.Error {
+	script := t.newScript()
+	evaluator.RegisterAssertions(script.Builtins())
+	script.SetFile(t.path)
+	script.SetModuleLoader(parser.ParseFile)
+	script.DefineKonst("argv", &evaluator.Array{})
+	script.SetStdout(t.out)
+	script.SetStdin(strings.NewReader(""))
+	if err, ok := script.RunContext(t.ctx, program).(*evaluator.Error); ok {
+		return err
+	}
+
+	// the call is reported at the name of the test
+	lparen := token.Token{Type: token.LPAREN, Literal: "(", Line: name.Token.Line, Col: name.Token.Col}
+	call := &ast.Program{Statements: []ast.Statement{&ast.ExpressionStatement{
+		Token:      name.Token,
+		Expression: &ast.CallExpression{Token: lparen, Function: name},
+	}}}
+	if err, ok := script.RunContext(t.ctx, call).(*evaluator.Error); ok {
+		return err
+	}
+	return nil
+}
+
+// testNames returns the names of the tests of program, in source order:
+// the global var and konst starting with uji_ bound to a function.
+func testNames(program *ast.Program) []*ast.Identifier {
+	var names []*ast.Identifier
+	for _, stmt := range program.Statements {
+		if ekspor, ok := stmt.(*ast.EksporStatement); ok {
+			stmt = ekspor.Statement
+		}
+		var (
+			name  *ast.Identifier
+			value ast.Expression
+		)
+		switch stmt := stmt.(type) {
+		case *ast.VarStatement:
+			name, value = stmt.Name, stmt.Value
+		case *ast.KonstStatement:
+			name, value = stmt.Name, stmt.Value
+		}
+		if _, ok := value.(*ast.FunctionLiteral); ok && strings.HasPrefix(name.Value, "uji_") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// indent indents the lines of s that aren't empty by four spaces.
+func indent(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "    " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
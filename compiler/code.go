@@ -0,0 +1,263 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+type Instructions []byte
+
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+	for i := 0; i < len(ins); {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			_, _ = fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+		operands, read := ReadOperands(def, ins[i+1:])
+		_, _ = fmt.Fprintf(&out, "%04d %s", i, def.Name)
+		for _, o := range operands {
+			_, _ = fmt.Fprintf(&out, " %d", o)
+		}
+		out.WriteString("\n")
+		i += 1 + read
+	}
+	return out.String()
+}
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpPop
+	OpSwap
+	OpNihil
+	OpVoid // the value of statements without one, e.g. var
+	OpTrue
+	OpFalse
+
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpLessEqual
+	OpGreater
+	OpGreaterEqual
+	OpRegexMatch
+	OpRegexNotMatch
+	OpAnd
+	OpOr
+	OpMinus
+	OpBang
+
+	OpJump
+	OpJumpNotTruthy
+
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	OpGetCell
+	OpSetCell
+	OpLoadCell
+	OpMakeCell
+	OpGetFree
+	OpLoadFree
+	OpClear
+
+	OpCheckKonst
+	OpCheckType
+	OpDefineKonst
+
+	OpArray
+	OpHash
+	OpInterpolate
+	OpIndex
+	OpMember
+
+	OpCall
+	OpTailCall
+	OpReturn
+	OpClosure
+	OpPipe
+
+	OpIterInit
+	OpIterNext
+	OpMatch
+
+	OpTry
+	OpEndTry
+	OpThrow
+	OpFail
+
+	OpImport
+	OpExport
+)
+
+// Kinds of the scope operand of OpCheckType and OpMember, they tell the
+// vm where the variable it refers to lives.
+const (
+	ScopeNone = iota
+	ScopeGlobal
+	ScopeLocal
+	ScopeCell
+	ScopeFree
+)
+
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+	OpPop:      {"OpPop", []int{}},
+	OpSwap:     {"OpSwap", []int{}},
+	OpNihil:    {"OpNihil", []int{}},
+	OpVoid:     {"OpVoid", []int{}},
+	OpTrue:     {"OpTrue", []int{}},
+	OpFalse:    {"OpFalse", []int{}},
+
+	OpAdd:           {"OpAdd", []int{}},
+	OpSub:           {"OpSub", []int{}},
+	OpMul:           {"OpMul", []int{}},
+	OpDiv:           {"OpDiv", []int{}},
+	OpMod:           {"OpMod", []int{}},
+	OpEqual:         {"OpEqual", []int{}},
+	OpNotEqual:      {"OpNotEqual", []int{}},
+	OpLess:          {"OpLess", []int{}},
+	OpLessEqual:     {"OpLessEqual", []int{}},
+	OpGreater:       {"OpGreater", []int{}},
+	OpGreaterEqual:  {"OpGreaterEqual", []int{}},
+	OpRegexMatch:    {"OpRegexMatch", []int{}},
+	OpRegexNotMatch: {"OpRegexNotMatch", []int{}},
+	OpAnd:           {"OpAnd", []int{}},
+	OpOr:            {"OpOr", []int{}},
+	OpMinus:         {"OpMinus", []int{}},
+	OpBang:          {"OpBang", []int{}},
+
+	// absolute address
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+
+	OpGetGlobal: {"OpGetGlobal", []int{2}},
+	OpSetGlobal: {"OpSetGlobal", []int{2}},
+	OpGetLocal:  {"OpGetLocal", []int{2}},
+	OpSetLocal:  {"OpSetLocal", []int{2}},
+	OpGetCell:   {"OpGetCell", []int{2}},
+	OpSetCell:   {"OpSetCell", []int{2}},
+	OpLoadCell:  {"OpLoadCell", []int{2}},
+	OpMakeCell:  {"OpMakeCell", []int{2}},
+	OpGetFree:   {"OpGetFree", []int{1}},
+	OpLoadFree:  {"OpLoadFree", []int{1}},
+	// first local, number of locals
+	OpClear: {"OpClear", []int{2, 2}},
+
+	// name constant
+	OpCheckKonst: {"OpCheckKonst", []int{2}},
+	// scope, index, name constant
+	OpCheckType: {"OpCheckType", []int{1, 2, 2}},
+	// name constant
+	OpDefineKonst: {"OpDefineKonst", []int{2}},
+
+	OpArray: {"OpArray", []int{2}},
+	OpHash:  {"OpHash", []int{2}},
+	// number of parts
+	OpInterpolate: {"OpInterpolate", []int{2}},
+	OpIndex:       {"OpIndex", []int{}},
+	// scope, index, object name constant, member name constant
+	OpMember: {"OpMember", []int{1, 2, 2, 2}},
+
+	OpCall: {"OpCall", []int{1}},
+	// like OpCall but a closure replaces the current frame, anything
+	// else is called normally and the OpReturn that follows returns it
+	OpTailCall: {"OpTailCall", []int{1}},
+	OpReturn:   {"OpReturn", []int{}},
+	// function constant, number of free variables
+	OpClosure: {"OpClosure", []int{2, 1}},
+	// address to continue at when the value can't be piped
+	OpPipe: {"OpPipe", []int{2}},
+
+	// local holding the iterator, name constant
+	OpIterInit: {"OpIterInit", []int{2, 2}},
+	// local holding the iterator, number of values to push, address once done
+	OpIterNext: {"OpIterNext", []int{2, 1, 2}},
+	OpMatch:    {"OpMatch", []int{}},
+
+	// handler address
+	OpTry:    {"OpTry", []int{2}},
+	OpEndTry: {"OpEndTry", []int{}},
+	OpThrow:  {"OpThrow", []int{}},
+	// error constant
+	OpFail: {"OpFail", []int{2}},
+
+	// path constant
+	OpImport: {"OpImport", []int{2}},
+	// name constant
+	OpExport: {"OpExport", []int{2}},
+}
+
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		switch width := def.OperandWidths[i]; width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += def.OperandWidths[i]
+	}
+	return instruction
+}
+
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return ins[0]
+}
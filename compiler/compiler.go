@@ -0,0 +1,938 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+const COMPILED_FUNCTION = "COMPILED_FUNCTION"
+
+// CompiledFunction is the bytecode of a function literal, or of a whole
+// program when Literal is nil.
+type CompiledFunction struct {
+	Instructions  Instructions
+	Positions     map[int]token.Pos // source position of instructions that may fail
+	NumLocals     int
+	NumParameters int
+	LocalNames    []string
+	FreeNames     []string
+	File          string
+	Literal       *ast.FunctionLiteral
+}
+
+func (cf *CompiledFunction) Type() evaluator.Type { return COMPILED_FUNCTION }
+func (cf *CompiledFunction) Inspect() string {
+	if cf.Literal == nil {
+		return "program"
+	}
+	return (&evaluator.Function{Parameters: cf.Literal.Parameters, Body: cf.Literal.Body}).Inspect()
+}
+
+type Bytecode struct {
+	Main      *CompiledFunction
+	Constants []evaluator.Object
+	Globals   []string // names of the globals by index
+}
+
+var infixOps = map[string]Opcode{
+	"+":  OpAdd,
+	"-":  OpSub,
+	"*":  OpMul,
+	"/":  OpDiv,
+	"%":  OpMod,
+	"==": OpEqual,
+	"!=": OpNotEqual,
+	"<":  OpLess,
+	"<=": OpLessEqual,
+	">":  OpGreater,
+	">=": OpGreaterEqual,
+	"=~": OpRegexMatch,
+	"!~": OpRegexNotMatch,
+	"&&": OpAnd,
+	"||": OpOr,
+}
+
+// Operators maps the binary opcodes back to the operator, the vm hands
+// them to evaluator.Infix.
+var Operators = func() map[Opcode]string {
+	ops := make(map[Opcode]string, len(infixOps))
+	for op, code := range infixOps {
+		ops[code] = op
+	}
+	return ops
+}()
+
+type loop struct {
+	head   int
+	tries  int   // try regions that were open when the loop started
+	breaks []int // OpJump instructions to patch with the loop's end
+}
+
+type compilationScope struct {
+	instructions Instructions
+	positions    map[int]token.Pos
+	loops        []*loop
+	tries        int
+}
+
+// Compiler turns programs into bytecode for the vm. It keeps its globals
+// and konstanta between calls to Compile, so a REPL can compile one line
+// at a time.
+type Compiler struct {
+	constants []evaluator.Object
+	names     map[string]int // constant index of STRING names
+	registry  *evaluator.Registry
+	builtins  map[*evaluator.Builtin]int // constant index of builtins
+	symbols   *SymbolTable
+	scopes    []*compilationScope
+	konst     map[string]bool
+	file      string
+	err       error
+}
+
+func New() *Compiler {
+	return &Compiler{
+		names:    make(map[string]int),
+		registry: evaluator.NewRegistry(),
+		builtins: make(map[*evaluator.Builtin]int),
+		symbols:  NewSymbolTable(),
+		konst:    make(map[string]bool),
+	}
+}
+
+// SetFile sets the file the compiled functions report errors in.
+func (c *Compiler) SetFile(path string) {
+	c.file = path
+}
+
+// SetBuiltins sets the registry identifiers are looked up in, builtins
+// are resolved when compiling.
+func (c *Compiler) SetBuiltins(r *evaluator.Registry) {
+	c.registry = r
+}
+
+// DefineGlobal declares a global the host sets before running, e.g.
+// argv, and returns its index.
+func (c *Compiler) DefineGlobal(name string, konst bool) int {
+	if konst {
+		c.konst[name] = true
+	}
+	return c.symbols.Define(name).Index
+}
+
+// GlobalNames returns the names of the globals by index.
+func (c *Compiler) GlobalNames() []string {
+	return c.symbols.GlobalNames()
+}
+
+// GlobalIndex returns the index of the global name.
+func (c *Compiler) GlobalIndex(name string) (int, bool) {
+	sym, ok := c.symbols.store[name]
+	return sym.Index, ok
+}
+
+// Compile compiles program as the main function, its value is the value
+// of the last statement.
+func (c *Compiler) Compile(program *ast.Program) (*Bytecode, error) {
+	c.err = nil
+	c.symbols.locals = nil
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ks, ok := node.(*ast.KonstStatement); ok && ks.Name != nil {
+			c.konst[ks.Name.Value] = true
+		}
+		return true
+	})
+	c.symbols.Capture(capturedNames(program.Statements))
+
+	c.enterScope()
+	c.compileStatements(program.Statements, true)
+	c.emit(OpReturn)
+	scope := c.leaveScope()
+
+	main := &CompiledFunction{
+		Instructions: scope.instructions,
+		Positions:    scope.positions,
+		NumLocals:    len(c.symbols.locals),
+		LocalNames:   c.symbols.locals,
+		File:         c.file,
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &Bytecode{Main: main, Constants: c.constants, Globals: c.symbols.globals}, nil
+}
+
+func (c *Compiler) scope() *compilationScope {
+	return c.scopes[len(c.scopes)-1]
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, &compilationScope{positions: make(map[int]token.Pos)})
+}
+
+func (c *Compiler) leaveScope() *compilationScope {
+	scope := c.scope()
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	return scope
+}
+
+// enterBlock opens the scope of a tiap body or a tangkap handler and
+// returns the first local it allocates.
+func (c *Compiler) enterBlock(stmts []ast.Statement) int {
+	c.symbols = newBlockSymbolTable(c.symbols)
+	start := len(c.symbols.frame.locals)
+	c.symbols.Hoist(declaredNames(stmts))
+	return start
+}
+
+// leaveBlock closes the scope opened by enterBlock, clear is the OpClear
+// resetting its locals.
+func (c *Compiler) leaveBlock(start, clear int) {
+	c.changeOperands(clear, start, len(c.symbols.frame.locals)-start)
+	c.symbols = c.symbols.Outer
+}
+
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	return c.emitAt(token.Pos{}, op, operands...)
+}
+
+// emitAt emits an instruction that may fail, errors it raises are
+// reported at pos.
+func (c *Compiler) emitAt(pos token.Pos, op Opcode, operands ...int) int {
+	for i, o := range operands {
+		if max := 1<<(8*definitions[op].OperandWidths[i]) - 1; o > max && c.err == nil {
+			c.err = fmt.Errorf("%s: operand %d of %s exceeds %d", pos, o, definitions[op].Name, max)
+		}
+	}
+	scope := c.scope()
+	offset := len(scope.instructions)
+	scope.instructions = append(scope.instructions, Make(op, operands...)...)
+	if pos.IsValid() {
+		scope.positions[offset] = pos
+	}
+	return offset
+}
+
+func (c *Compiler) changeOperands(offset int, operands ...int) {
+	ins := c.scope().instructions
+	copy(ins[offset:], Make(Opcode(ins[offset]), operands...))
+}
+
+// patch points the jump at offset, whose address is its last operand, to
+// the next instruction.
+func (c *Compiler) patch(offset int) {
+	ins := c.scope().instructions
+	def := definitions[Opcode(ins[offset])]
+	operands, _ := ReadOperands(def, ins[offset+1:])
+	operands[len(operands)-1] = len(ins)
+	c.changeOperands(offset, operands...)
+}
+
+func (c *Compiler) addConstant(obj evaluator.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) name(name string) int {
+	if i, ok := c.names[name]; ok {
+		return i
+	}
+	i := c.addConstant(&evaluator.String{Value: name})
+	c.names[name] = i
+	return i
+}
+
+// fail emits code raising err at pos, for code the evaluator rejects
+// only once it runs.
+func (c *Compiler) fail(err *evaluator.Error, pos token.Pos) {
+	c.emitAt(pos, OpFail, c.addConstant(err))
+}
+
+func (c *Compiler) compileStatements(stmts []ast.Statement, value bool) {
+	if len(stmts) == 0 {
+		if value {
+			c.emit(OpVoid)
+		}
+		return
+	}
+	for i, stmt := range stmts {
+		c.compileStatement(stmt, value && i == len(stmts)-1)
+	}
+}
+
+func (c *Compiler) compileBlock(block *ast.BlockStatement) {
+	if block == nil {
+		c.emit(OpVoid)
+		return
+	}
+	c.compileStatements(block.Statements, true)
+}
+
+// compileStatement leaves the value of stmt on the stack when value is
+// set, nothing otherwise.
+func (c *Compiler) compileStatement(stmt ast.Statement, value bool) {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		if stmt.Expression == nil {
+			break
+		}
+		c.compileExpression(stmt.Expression)
+		if !value {
+			c.emit(OpPop)
+		}
+		return
+
+	case *ast.VarStatement:
+		c.compileVar(stmt)
+
+	case *ast.KonstStatement:
+		c.compileExpression(stmt.Value)
+		c.emitAt(stmt.Token.Pos(), OpDefineKonst, c.name(stmt.Name.Value))
+		c.store(c.symbols.Define(stmt.Name.Value))
+
+	case *ast.PilihStatement:
+		if len(c.scopes) > 1 && c.scope().tries == 0 {
+			c.compileReturn(stmt.ReturnValue)
+		} else {
+			c.compileExpression(stmt.ReturnValue)
+			c.emit(OpReturn)
+		}
+		return
+
+	case *ast.LemparStatement:
+		c.compileExpression(stmt.Value)
+		c.emitAt(stmt.Token.Pos(), OpThrow)
+		return
+
+	case *ast.ImporStatement:
+		name, err := evaluator.ImportName(stmt)
+		if err != nil {
+			c.fail(err, stmt.Token.Pos())
+			return
+		}
+		c.emitAt(stmt.Token.Pos(), OpImport, c.name(stmt.Path.Value))
+		c.store(c.symbols.Define(name))
+
+	case *ast.EksporStatement:
+		c.compileStatement(stmt.Statement, false)
+		switch inner := stmt.Statement.(type) {
+		case *ast.VarStatement:
+			c.emit(OpExport, c.name(inner.Name.Value))
+		case *ast.KonstStatement:
+			c.emit(OpExport, c.name(inner.Name.Value))
+		}
+	}
+
+	if value {
+		c.emit(OpVoid)
+	}
+}
+
+// compileVar compiles `var x = v` and the assignment `x = v`, which is
+// the same statement. The konstanta and type checks run before storing.
+func (c *Compiler) compileVar(stmt *ast.VarStatement) {
+	c.compileExpression(stmt.Value)
+	name, pos := stmt.Name.Value, stmt.Token.Pos()
+	if c.konst[name] {
+		c.emitAt(pos, OpCheckKonst, c.name(name))
+	}
+	if prev, ok := c.symbols.Resolve(name); ok {
+		c.emitAt(pos, OpCheckType, scopeOperand(prev), prev.Index, c.name(name))
+	}
+	c.store(c.symbols.Define(name))
+}
+
+func (c *Compiler) compileExpression(node ast.Expression) {
+	switch node := node.(type) {
+	case *ast.VarStatement:
+		c.compileVar(node)
+		c.emit(OpVoid)
+
+	case *ast.IntegerLiteral:
+		c.emit(OpConstant, c.addConstant(&evaluator.Integer{Value: node.Value}))
+
+	case *ast.FloatLiteral:
+		c.emit(OpConstant, c.addConstant(&evaluator.Float{Value: node.Value}))
+
+	case *ast.StringLiteral:
+		c.emit(OpConstant, c.addConstant(&evaluator.String{Value: node.Value}))
+
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			c.compileExpression(part)
+		}
+		c.emit(OpInterpolate, len(node.Parts))
+
+	case *ast.RegExLiteral:
+		switch re := evaluator.NewRegex(node.Value).(type) {
+		case *evaluator.Error:
+			c.fail(re, node.Pos())
+		default:
+			c.emit(OpConstant, c.addConstant(re))
+		}
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(OpTrue)
+		} else {
+			c.emit(OpFalse)
+		}
+
+	case *ast.NihilLiteral:
+		c.emit(OpNihil)
+
+	case *ast.Identifier:
+		c.compileIdentifier(node)
+
+	case *ast.PrefixExpression:
+		c.compileExpression(node.Right)
+		switch node.Operator {
+		case "-":
+			c.emitAt(node.Token.Pos(), OpMinus)
+		default:
+			c.emitAt(node.Token.Pos(), OpBang)
+		}
+
+	case *ast.InfixExpression:
+		c.compileExpression(node.Left)
+		c.compileExpression(node.Right)
+		op, ok := infixOps[node.Operator]
+		if !ok {
+			c.emit(OpPop)
+			c.emit(OpPop)
+			c.fail(evaluator.NewErrorKind(evaluator.TypeError, "unknown operator: %s", node.Operator), node.Token.Pos())
+			return
+		}
+		c.emitAt(node.Token.Pos(), op)
+
+	case *ast.JikaExpression:
+		c.compileJika(node, false)
+
+	case *ast.CobaExpression:
+		c.compileCoba(node)
+
+	case *ast.FunctionLiteral:
+		c.compileFunction(node)
+
+	case *ast.CallExpression:
+		c.compileExpression(node.Function)
+		for _, arg := range node.Arguments {
+			c.compileExpression(arg)
+		}
+		c.emitAt(node.Pos(), OpCall, len(node.Arguments))
+
+	case *ast.MethodCallExpression:
+		c.compileMethodCall(node, 0)
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			c.compileExpression(el)
+		}
+		c.emit(OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		keys := node.Keys()
+		for _, k := range keys {
+			c.compileExpression(k)
+			c.compileExpression(node.Pairs[k])
+		}
+		c.emitAt(node.Token.Pos(), OpHash, len(keys)*2)
+
+	case *ast.IndexExpression:
+		c.compileExpression(node.Left)
+		c.compileExpression(node.Index)
+		c.emitAt(node.Token.Pos(), OpIndex)
+
+	case *ast.Pipe:
+		c.compilePipe(node)
+
+	case *ast.PilahExpression:
+		c.compilePilah(node, false)
+
+	case *ast.LoopLiteral:
+		c.compileLoop(node)
+
+	case *ast.BreakExpression:
+		c.compileJump(node.Token.Pos(), "usai", false)
+
+	case *ast.ContinueExpression:
+		c.compileJump(node.Token.Pos(), "lanjut", true)
+
+	default:
+		c.emit(OpVoid)
+	}
+}
+
+// compileIdentifier looks names up like the evaluator does: builtins
+// first, then the variables in scope. Unknown names become globals, which
+// fail with a NameError until something assigns them.
+func (c *Compiler) compileIdentifier(node *ast.Identifier) {
+	if b, ok := c.registry.Lookup(node.Value); ok {
+		i, ok := c.builtins[b]
+		if !ok {
+			i = c.addConstant(b)
+			c.builtins[b] = i
+		}
+		c.emit(OpConstant, i)
+		return
+	}
+	sym, ok := c.symbols.Resolve(node.Value)
+	if !ok {
+		sym = c.globals().Define(node.Value)
+	}
+	c.load(sym, node.Token.Pos())
+}
+
+func (c *Compiler) globals() *SymbolTable {
+	t := c.symbols
+	for t.Outer != nil {
+		t = t.Outer
+	}
+	return t
+}
+
+func (c *Compiler) load(sym Symbol, pos token.Pos) {
+	switch {
+	case sym.Scope == GlobalScope:
+		c.emitAt(pos, OpGetGlobal, sym.Index)
+	case sym.Scope == FreeScope:
+		c.emitAt(pos, OpGetFree, sym.Index)
+	case sym.Cell:
+		c.emitAt(pos, OpGetCell, sym.Index)
+	default:
+		c.emitAt(pos, OpGetLocal, sym.Index)
+	}
+}
+
+func (c *Compiler) store(sym Symbol) {
+	switch {
+	case sym.Scope == GlobalScope:
+		c.emit(OpSetGlobal, sym.Index)
+	case sym.Cell:
+		c.emit(OpSetCell, sym.Index)
+	default:
+		c.emit(OpSetLocal, sym.Index)
+	}
+}
+
+func scopeOperand(sym Symbol) int {
+	switch {
+	case sym.Scope == GlobalScope:
+		return ScopeGlobal
+	case sym.Scope == FreeScope:
+		return ScopeFree
+	case sym.Cell:
+		return ScopeCell
+	default:
+		return ScopeLocal
+	}
+}
+
+func (c *Compiler) compileFunction(node *ast.FunctionLiteral) {
+	var stmts []ast.Statement
+	if node.Body != nil {
+		stmts = node.Body.Statements
+	}
+	table := NewEnclosedSymbolTable(c.symbols)
+	table.Capture(capturedNames(stmts))
+	c.symbols = table
+	c.enterScope()
+
+	for _, p := range node.Parameters {
+		if sym := table.DefineParameter(p.Value); sym.Cell {
+			c.emit(OpMakeCell, sym.Index)
+		}
+	}
+	table.Hoist(declaredNames(stmts))
+	c.compileBody(stmts)
+
+	scope := c.leaveScope()
+	c.symbols = table.Outer
+
+	freeNames := make([]string, len(table.FreeSymbols))
+	for i, free := range table.FreeSymbols {
+		freeNames[i] = free.Name
+		if free.Scope == FreeScope {
+			c.emit(OpLoadFree, free.Index)
+		} else {
+			c.emit(OpLoadCell, free.Index)
+		}
+	}
+	fn := &CompiledFunction{
+		Instructions:  scope.instructions,
+		Positions:     scope.positions,
+		NumLocals:     len(table.locals),
+		NumParameters: len(node.Parameters),
+		LocalNames:    table.locals,
+		FreeNames:     freeNames,
+		File:          c.file,
+		Literal:       node,
+	}
+	c.emit(OpClosure, c.addConstant(fn), len(freeNames))
+}
+
+// compileBody compiles stmts as the rest of a function body, returning
+// the value of the last statement.
+func (c *Compiler) compileBody(stmts []ast.Statement) {
+	if len(stmts) == 0 {
+		c.emit(OpVoid)
+		c.emit(OpReturn)
+		return
+	}
+	last := len(stmts) - 1
+	for _, stmt := range stmts[:last] {
+		c.compileStatement(stmt, false)
+	}
+	if stmt, ok := stmts[last].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+		c.compileReturn(stmt.Expression)
+		return
+	}
+	c.compileStatement(stmts[last], true)
+	c.emit(OpReturn)
+}
+
+// compileReturn compiles node in tail position, every path through it
+// returns from the function. Calls become OpTailCall, so recursion in
+// tail position runs in constant stack, and jika and pilah pass the tail
+// position on to their branches.
+func (c *Compiler) compileReturn(node ast.Expression) {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		c.compileExpression(node.Function)
+		for _, arg := range node.Arguments {
+			c.compileExpression(arg)
+		}
+		c.emitAt(node.Pos(), OpTailCall, len(node.Arguments))
+		c.emit(OpReturn)
+
+	case *ast.JikaExpression:
+		c.compileJika(node, true)
+
+	case *ast.PilahExpression:
+		c.compilePilah(node, true)
+
+	default:
+		c.compileExpression(node)
+		c.emit(OpReturn)
+	}
+}
+
+// compileJika compiles jika, in tail position each branch returns.
+func (c *Compiler) compileJika(node *ast.JikaExpression, tail bool) {
+	c.compileExpression(node.Condition)
+	alternative := c.emit(OpJumpNotTruthy, 0)
+	c.compileBranch(node.Consequence, tail)
+	end := -1
+	if !tail {
+		end = c.emit(OpJump, 0)
+	}
+	c.patch(alternative)
+	switch {
+	case node.Alternative != nil:
+		c.compileBranch(node.Alternative, tail)
+	case tail:
+		c.emit(OpNihil)
+		c.emit(OpReturn)
+	default:
+		c.emit(OpNihil)
+	}
+	if end >= 0 {
+		c.patch(end)
+	}
+}
+
+func (c *Compiler) compileBranch(block *ast.BlockStatement, tail bool) {
+	switch {
+	case !tail:
+		c.compileBlock(block)
+	case block == nil:
+		c.compileBody(nil)
+	default:
+		c.compileBody(block.Statements)
+	}
+}
+
+func (c *Compiler) compileCoba(node *ast.CobaExpression) {
+	try := c.emit(OpTry, 0)
+	c.scope().tries++
+	c.compileBlock(node.Body)
+	c.scope().tries--
+	c.emit(OpEndTry)
+	end := c.emit(OpJump, 0)
+
+	// the vm jumps here with the exception on the stack
+	c.patch(try)
+	var stmts []ast.Statement
+	if node.Handler != nil {
+		stmts = node.Handler.Statements
+	}
+	start := c.enterBlock(stmts)
+	clear := c.emit(OpClear, start, 0)
+	if node.Param != nil {
+		c.store(c.symbols.Define(node.Param.Value))
+	} else {
+		c.emit(OpPop)
+	}
+	c.compileStatements(stmts, true)
+	c.leaveBlock(start, clear)
+	c.patch(end)
+}
+
+func (c *Compiler) compileLoop(node *ast.LoopLiteral) {
+	c.compileIdentifier(node.Iter)
+	iter := c.symbols.frame.hidden()
+	c.emitAt(node.Iter.Pos(), OpIterInit, iter, c.name(node.Iter.String()))
+
+	var stmts []ast.Statement
+	if node.Body != nil {
+		stmts = node.Body.Statements
+	}
+	start := c.enterBlock(stmts)
+	kv := make([]Symbol, 0, 2)
+	for i := 0; i < len(node.KV) && i < 2; i++ {
+		kv = append(kv, c.symbols.Define(node.KV[i].Value))
+	}
+
+	head := c.emit(OpIterNext, iter, len(kv), 0)
+	clear := c.emit(OpClear, start, 0)
+	// the vm pushes the value below the key
+	for _, sym := range kv {
+		c.store(sym)
+	}
+
+	l := &loop{head: head, tries: c.scope().tries}
+	c.scope().loops = append(c.scope().loops, l)
+	c.compileStatements(stmts, false)
+	c.scope().loops = c.scope().loops[:len(c.scope().loops)-1]
+	c.emit(OpJump, head)
+
+	c.patch(head)
+	for _, b := range l.breaks {
+		c.patch(b)
+	}
+	c.leaveBlock(start, clear)
+	c.emit(OpNihil)
+}
+
+// compileJump compiles usai and lanjut, which leave the innermost tiap
+// of the function and any coba they are in.
+func (c *Compiler) compileJump(pos token.Pos, keyword string, continues bool) {
+	scope := c.scope()
+	if len(scope.loops) == 0 {
+		c.fail(evaluator.NewError("%s hanya bisa digunakan di dalam tiap", keyword), pos)
+		return
+	}
+	l := scope.loops[len(scope.loops)-1]
+	for i := l.tries; i < scope.tries; i++ {
+		c.emit(OpEndTry)
+	}
+	if continues {
+		c.emit(OpJump, l.head)
+		return
+	}
+	l.breaks = append(l.breaks, c.emit(OpJump, 0))
+}
+
+// compilePilah compiles pilah, in tail position each arm returns.
+func (c *Compiler) compilePilah(node *ast.PilahExpression, tail bool) {
+	if node.Target != nil && node.Target.Expression != nil {
+		c.compileExpression(node.Target.Expression)
+	} else {
+		c.emit(OpNihil)
+	}
+
+	value := func(i int) {
+		var value ast.Expression
+		if i < len(node.Values) {
+			value = node.Values[i]
+		}
+		if tail {
+			c.compileReturn(value)
+		} else {
+			c.compileExpression(value)
+		}
+	}
+
+	var ends []int
+	wildcard := false
+	for i, cond := range node.Conditions {
+		if cond.Token.Type == token.UNDERSCORE {
+			c.emit(OpPop)
+			value(i)
+			wildcard = true
+			break
+		}
+		c.compileExpression(cond.Expression)
+		c.emit(OpMatch)
+		next := c.emit(OpJumpNotTruthy, 0)
+		c.emit(OpPop)
+		value(i)
+		if !tail {
+			ends = append(ends, c.emit(OpJump, 0))
+		}
+		c.patch(next)
+	}
+	if !wildcard {
+		c.emit(OpPop)
+		c.emit(OpNihil)
+		if tail {
+			c.emit(OpReturn)
+		}
+	}
+	for _, end := range ends {
+		c.patch(end)
+	}
+}
+
+// compileMethodCall compiles obj.name and obj.name(args), with piped
+// extra arguments already on the stack below the member.
+func (c *Compiler) compileMethodCall(node *ast.MethodCallExpression, piped int) {
+	obj, ok := node.Object.(*ast.Identifier)
+	if !ok {
+		c.fail(evaluator.NewError("invalid method call expression"), node.Pos())
+		return
+	}
+
+	switch call := node.Call.(type) {
+	case *ast.Identifier:
+		c.compileMember(obj, call.Value, node.Pos())
+		if piped > 0 {
+			c.emit(OpSwap)
+			c.emitAt(call.Pos(), OpCall, piped)
+		}
+		return
+
+	case *ast.CallExpression:
+		name, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			break
+		}
+		c.compileMember(obj, name.Value, node.Pos())
+		if piped > 0 {
+			c.emit(OpSwap)
+		}
+		for _, arg := range call.Arguments {
+			c.compileExpression(arg)
+		}
+		c.emitAt(call.Pos(), OpCall, piped+len(call.Arguments))
+		return
+	}
+	c.fail(evaluator.NewError("invalid method call expression"), node.Pos())
+}
+
+func (c *Compiler) compileMember(obj *ast.Identifier, name string, pos token.Pos) {
+	scope, index := ScopeNone, 0
+	if sym, ok := c.symbols.Resolve(obj.Value); ok {
+		scope, index = scopeOperand(sym), sym.Index
+	}
+	c.emitAt(pos, OpMember, scope, index, c.name(obj.Value), c.name(name))
+}
+
+// compilePipe passes the left operand as the first argument of the call
+// on the right, left |> f(a) calls f(left, a).
+func (c *Compiler) compilePipe(node *ast.Pipe) {
+	c.compileExpression(node.Left)
+	pipe := c.emit(OpPipe, 0)
+
+	switch right := node.Right.(type) {
+	case *ast.Identifier:
+		c.compileIdentifier(right)
+		c.emit(OpSwap)
+		c.emitAt(node.Token.Pos(), OpCall, 1)
+
+	case *ast.CallExpression:
+		c.compileExpression(right.Function)
+		c.emit(OpSwap)
+		for _, arg := range right.Arguments {
+			c.compileExpression(arg)
+		}
+		c.emitAt(right.Pos(), OpCall, 1+len(right.Arguments))
+
+	case *ast.MethodCallExpression:
+		c.compileMethodCall(right, 1)
+
+	default:
+		c.emit(OpPop)
+		c.emit(OpNihil)
+	}
+	c.patch(pipe)
+}
+
+// hidden allocates a local no variable refers to.
+func (t *SymbolTable) hidden() int {
+	t.locals = append(t.locals, "")
+	return len(t.locals) - 1
+}
+
+// declaredNames returns the names declared by stmts in their own scope,
+// including those in jika branches and coba bodies, which share it, but
+// not in function literals, tiap bodies or tangkap handlers.
+func declaredNames(stmts []ast.Statement) []string {
+	var names []string
+	var visit func(node ast.Node) bool
+	visit = func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.VarStatement:
+			names = append(names, node.Name.Value)
+		case *ast.KonstStatement:
+			names = append(names, node.Name.Value)
+		case *ast.ImporStatement:
+			if name, err := evaluator.ImportName(node); err == nil {
+				names = append(names, name)
+			}
+		case *ast.FunctionLiteral, *ast.LoopLiteral:
+			return false
+		case *ast.CobaExpression:
+			if node.Body != nil {
+				ast.Inspect(node.Body, visit)
+			}
+			return false
+		}
+		return true
+	}
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, visit)
+	}
+	return names
+}
+
+// capturedNames returns the identifiers used by function literals nested
+// in stmts, the variables they may capture.
+func capturedNames(stmts []ast.Statement) []string {
+	var names []string
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			fn, ok := node.(*ast.FunctionLiteral)
+			if !ok {
+				return true
+			}
+			ast.Inspect(fn, func(node ast.Node) bool {
+				if id, ok := node.(*ast.Identifier); ok {
+					names = append(names, id.Value)
+				}
+				return true
+			})
+			return false
+		})
+	}
+	return names
+}
+
+// Disassemble renders the instructions of fn and the functions it
+// contains, for debugging.
+func Disassemble(bc *Bytecode) string {
+	var out bytes.Buffer
+	out.WriteString(bc.Main.Instructions.String())
+	for i, c := range bc.Constants {
+		if fn, ok := c.(*CompiledFunction); ok {
+			_, _ = fmt.Fprintf(&out, "\nconstant %d %s\n", i, strings.SplitN(fn.Inspect(), "\n", 2)[0])
+			out.WriteString(fn.Instructions.String())
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,180 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope SymbolScope = "GLOBAL"
+	LocalScope  SymbolScope = "LOCAL"
+	FreeScope   SymbolScope = "FREE"
+)
+
+// Symbol is a resolved variable. Cell is set for locals that closures
+// capture, they live boxed in a cell so both see the same variable.
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+	Cell  bool
+}
+
+// SymbolTable mirrors the evaluator's environments. Every function body
+// gets its own table, and so do the bodies of tiap and the handler of
+// tangkap, but those block tables share the frame of the enclosing
+// function, they only allocate more locals in it.
+type SymbolTable struct {
+	Outer       *SymbolTable
+	FreeSymbols []Symbol
+
+	store map[string]Symbol
+	// hoisted holds names declared later in this scope: a function
+	// literal may refer to them before they are declared, the code of the
+	// scope itself can't.
+	hoisted map[string]Symbol
+	block   bool
+	frame   *SymbolTable // the table owning the frame, itself unless block
+
+	// only set on frame owners, the global table owns the frame of the
+	// main program
+	captured map[string]bool // names referred to by nested function literals
+	locals   []string        // names of the locals by index
+	globals  []string        // only set on the global table
+}
+
+func NewSymbolTable() *SymbolTable {
+	t := &SymbolTable{
+		store:    make(map[string]Symbol),
+		hoisted:  make(map[string]Symbol),
+		captured: make(map[string]bool),
+	}
+	t.frame = t
+	return t
+}
+
+// NewEnclosedSymbolTable returns the table of a function body.
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	t := NewSymbolTable()
+	t.Outer = outer
+	return t
+}
+
+// newBlockSymbolTable returns the table of a scope sharing the frame of
+// outer.
+func newBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+	return &SymbolTable{
+		Outer:   outer,
+		store:   make(map[string]Symbol),
+		hoisted: make(map[string]Symbol),
+		block:   true,
+		frame:   outer.frame,
+	}
+}
+
+func (t *SymbolTable) global() bool {
+	return t.Outer == nil
+}
+
+// LocalNames returns the names of the locals of the frame by index, its
+// length is the number of slots the frame needs.
+func (t *SymbolTable) LocalNames() []string {
+	return t.frame.locals
+}
+
+// GlobalNames returns the names of the globals by index.
+func (t *SymbolTable) GlobalNames() []string {
+	for t.Outer != nil {
+		t = t.Outer
+	}
+	return t.globals
+}
+
+func (t *SymbolTable) allocate(name string) Symbol {
+	if t.global() {
+		sym := Symbol{Name: name, Scope: GlobalScope, Index: len(t.globals)}
+		t.globals = append(t.globals, name)
+		return sym
+	}
+	frame := t.frame
+	sym := Symbol{Name: name, Scope: LocalScope, Index: len(frame.locals), Cell: frame.captured[name]}
+	frame.locals = append(frame.locals, name)
+	return sym
+}
+
+// Define declares name in this scope, a name declared twice keeps its
+// slot.
+func (t *SymbolTable) Define(name string) Symbol {
+	if sym, ok := t.store[name]; ok && sym.Scope != FreeScope {
+		return sym
+	}
+	sym, ok := t.hoisted[name]
+	if !ok {
+		sym = t.allocate(name)
+	}
+	t.store[name] = sym
+	return sym
+}
+
+// DefineParameter declares a function parameter, each gets its own slot
+// in order even if the names repeat.
+func (t *SymbolTable) DefineParameter(name string) Symbol {
+	sym := Symbol{Name: name, Scope: LocalScope, Index: len(t.frame.locals), Cell: t.frame.captured[name]}
+	t.frame.locals = append(t.frame.locals, name)
+	t.store[name] = sym
+	return sym
+}
+
+// Hoist reserves slots for names that are declared somewhere in this scope.
+func (t *SymbolTable) Hoist(names []string) {
+	for _, name := range names {
+		if _, ok := t.store[name]; ok && t.store[name].Scope != FreeScope {
+			continue
+		}
+		if _, ok := t.hoisted[name]; ok {
+			continue
+		}
+		t.hoisted[name] = t.allocate(name)
+	}
+}
+
+// Capture records names referred to by function literals nested in the
+// frame's code, locals with those names are allocated as cells.
+func (t *SymbolTable) Capture(names []string) {
+	for _, name := range names {
+		t.frame.captured[name] = true
+	}
+}
+
+func (t *SymbolTable) Resolve(name string) (Symbol, bool) {
+	return t.resolve(name, false)
+}
+
+func (t *SymbolTable) resolve(name string, nested bool) (Symbol, bool) {
+	if sym, ok := t.store[name]; ok {
+		return sym, true
+	}
+	if nested {
+		if sym, ok := t.hoisted[name]; ok {
+			return sym, true
+		}
+	}
+	if t.Outer == nil {
+		return Symbol{}, false
+	}
+
+	sym, ok := t.Outer.resolve(name, nested || !t.block)
+	if !ok || t.block || sym.Scope == GlobalScope {
+		return sym, ok
+	}
+	return t.defineFree(sym), true
+}
+
+func (t *SymbolTable) defineFree(original Symbol) Symbol {
+	for i, free := range t.FreeSymbols {
+		if free == original {
+			return Symbol{Name: original.Name, Scope: FreeScope, Index: i}
+		}
+	}
+	t.FreeSymbols = append(t.FreeSymbols, original)
+	sym := Symbol{Name: original.Name, Scope: FreeScope, Index: len(t.FreeSymbols) - 1}
+	t.store[original.Name] = sym
+	return sym
+}
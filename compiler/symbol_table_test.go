@@ -0,0 +1,71 @@
+package compiler
+
+import "testing"
+
+func TestResolveScopes(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	outer := NewEnclosedSymbolTable(global)
+	outer.Capture([]string{"c"})
+	outer.DefineParameter("b")
+	outer.Hoist([]string{"c"})
+
+	inner := NewEnclosedSymbolTable(outer)
+	inner.Define("d")
+
+	tests := []struct {
+		table    *SymbolTable
+		name     string
+		expected Symbol
+	}{
+		{global, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{outer, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{outer, "b", Symbol{Name: "b", Scope: LocalScope, Index: 0}},
+		{inner, "d", Symbol{Name: "d", Scope: LocalScope, Index: 0}},
+		// c is declared later in outer, only nested functions see it
+		{inner, "c", Symbol{Name: "c", Scope: FreeScope, Index: 0}},
+		{inner, "b", Symbol{Name: "b", Scope: FreeScope, Index: 1}},
+	}
+	for _, tt := range tests {
+		sym, ok := tt.table.Resolve(tt.name)
+		if !ok {
+			t.Errorf("name %s not resolvable", tt.name)
+			continue
+		}
+		if sym != tt.expected {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.name, tt.expected, sym)
+		}
+	}
+
+	if _, ok := outer.Resolve("c"); ok {
+		t.Errorf("hoisted c resolvable before it is declared")
+	}
+	expectedFree := []Symbol{
+		{Name: "c", Scope: LocalScope, Index: 1, Cell: true},
+		{Name: "b", Scope: LocalScope, Index: 0},
+	}
+	for i, sym := range expectedFree {
+		if inner.FreeSymbols[i] != sym {
+			t.Errorf("wrong free symbol %d. want=%+v, got=%+v", i, sym, inner.FreeSymbols[i])
+		}
+	}
+}
+
+func TestBlockScopeSharesFrame(t *testing.T) {
+	fn := NewEnclosedSymbolTable(NewSymbolTable())
+	fn.Define("a")
+	block := newBlockSymbolTable(fn)
+	block.Define("a")
+	block.Define("b")
+
+	if sym, _ := block.Resolve("a"); sym.Index != 1 || sym.Scope != LocalScope {
+		t.Errorf("a in block should shadow with its own slot, got=%+v", sym)
+	}
+	if sym, _ := fn.Resolve("a"); sym.Index != 0 {
+		t.Errorf("a in function should keep slot 0, got=%+v", sym)
+	}
+	if n := len(fn.LocalNames()); n != 3 {
+		t.Errorf("frame should hold 3 locals, got=%d", n)
+	}
+}
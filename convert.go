@@ -0,0 +1,336 @@
+package bilang
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/dedisuryadi/bilang/evaluator"
+)
+
+var (
+	objectType = reflect.TypeOf((*evaluator.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// ToObject converts a Go value to a Bilang one: nil to nihil, booleans,
+// strings, integers and floats of any size to BOOLEAN, STRING, INTEGER
+// and FLOAT, slices and arrays to ARRAY, maps to HASH and functions to
+// builtins, see NewBuiltin. An evaluator.Object is returned as is.
+func ToObject(v interface{}) (evaluator.Object, error) {
+	switch v := v.(type) {
+	case nil:
+		return evaluator.NIHIL, nil
+	case evaluator.Object:
+		return v, nil
+	case bool:
+		return evaluator.NativeBool(v), nil
+	case string:
+		return &evaluator.String{Value: v}, nil
+	case int:
+		return &evaluator.Integer{Value: int64(v)}, nil
+	case int64:
+		return &evaluator.Integer{Value: v}, nil
+	case float64:
+		return &evaluator.Float{Value: v}, nil
+	}
+	return valueToObject(reflect.ValueOf(v))
+}
+
+func valueToObject(v reflect.Value) (evaluator.Object, error) {
+	if v.Type().Implements(objectType) {
+		return v.Interface().(evaluator.Object), nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return evaluator.NativeBool(v.Bool()), nil
+	case reflect.String:
+		return &evaluator.String{Value: v.String()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &evaluator.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows INTEGER", v.Uint())
+		}
+		return &evaluator.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &evaluator.Float{Value: v.Float()}, nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return evaluator.NIHIL, nil
+		}
+		arr := &evaluator.Array{Elements: make([]evaluator.Object, v.Len())}
+		for i := range arr.Elements {
+			elem, err := valueToObject(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			arr.Elements[i] = elem
+		}
+		return arr, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return evaluator.NIHIL, nil
+		}
+		hash := &evaluator.Hash{Pairs: make(map[evaluator.HashKey]evaluator.HashPair, v.Len())}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := valueToObject(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			hk, ok := key.(evaluator.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+			val, err := valueToObject(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			hash.Pairs[hk.HashKey()] = evaluator.HashPair{Key: key, Value: val}
+		}
+		return hash, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return evaluator.NIHIL, nil
+		}
+		return newBuiltin("fn", v)
+
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return evaluator.NIHIL, nil
+		}
+		if v.Kind() == reflect.Interface {
+			return valueToObject(v.Elem())
+		}
+	}
+	return nil, fmt.Errorf("unsupported Go type %s", v.Type())
+}
+
+// FromObject converts a Bilang value to a Go one: nihil to nil, BOOLEAN,
+// STRING, INTEGER and FLOAT to bool, string, int64 and float64, ARRAY to
+// []interface{} and HASH to map[string]interface{}, or to
+// map[interface{}]interface{} when some of its keys are not STRING.
+// Other values, such as functions, are returned as evaluator.Object.
+func FromObject(obj evaluator.Object) interface{} {
+	switch obj := obj.(type) {
+	case nil, *evaluator.Null:
+		return nil
+	case *evaluator.Boolean:
+		return obj.Value
+	case *evaluator.String:
+		return obj.Value
+	case *evaluator.Integer:
+		return obj.Value
+	case *evaluator.Float:
+		return obj.Value
+	case *evaluator.Array:
+		arr := make([]interface{}, len(obj.Elements))
+		for i, elem := range obj.Elements {
+			arr[i] = FromObject(elem)
+		}
+		return arr
+	case *evaluator.Hash:
+		strings := make(map[string]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, ok := pair.Key.(*evaluator.String)
+			if !ok {
+				break
+			}
+			strings[key.Value] = FromObject(pair.Value)
+		}
+		if len(strings) == len(obj.Pairs) {
+			return strings
+		}
+		hash := make(map[interface{}]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			hash[FromObject(pair.Key)] = FromObject(pair.Value)
+		}
+		return hash
+	}
+	return obj
+}
+
+// NewBuiltin wraps the Go function fn as a builtin called name. Its
+// arguments are converted from Bilang values to the parameter types of
+// fn: an evaluator.Object parameter takes any value as is, interface{}
+// takes any value converted by FromObject. The wrong number or type of
+// arguments is a TypeError. fn may return
+// nothing, a value, an error, or a value and an error: a non-nil error
+// becomes a RuntimeError of the script, and so does a panic.
+func NewBuiltin(name string, fn interface{}) (*evaluator.Builtin, error) {
+	if fn, ok := fn.(evaluator.BuiltinFunction); ok {
+		return &evaluator.Builtin{Fn: fn}, nil
+	}
+	if fn, ok := fn.(func(...evaluator.Object) evaluator.Object); ok {
+		return &evaluator.Builtin{Fn: fn}, nil
+	}
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("bilang: %s is %T, not a function", name, fn)
+	}
+	return newBuiltin(name, v)
+}
+
+func newBuiltin(name string, fn reflect.Value) (*evaluator.Builtin, error) {
+	t := fn.Type()
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	if t.NumOut() > 2 || t.NumOut() == 2 && !returnsError {
+		return nil, fmt.Errorf("bilang: %s must return at most a value and an error, got %s", name, t)
+	}
+
+	return &evaluator.Builtin{Fn: func(args ...evaluator.Object) (result evaluator.Object) {
+		in, errObj := builtinArgs(name, t, args)
+		if errObj != nil {
+			return errObj
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				result = evaluator.NewError("%s: panic: %v", name, r)
+			}
+		}()
+
+		out := fn.Call(in)
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return evaluator.NewError("%s: %s", name, err)
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return evaluator.NIHIL
+		}
+		obj, err := valueToObject(out[0])
+		if err != nil {
+			return evaluator.NewError("%s: %s", name, err)
+		}
+		return obj
+	}}, nil
+}
+
+// builtinArgs converts the arguments of a call to the parameters of a
+// function of type t.
+func builtinArgs(name string, t reflect.Type, args []evaluator.Object) ([]reflect.Value, *evaluator.Error) {
+	want := t.NumIn()
+	if t.IsVariadic() {
+		if len(args) < want-1 {
+			return nil, evaluator.NewErrorKind(evaluator.TypeError, "wrong number of arguments. got=%d, want at least %d", len(args), want-1)
+		}
+	} else if len(args) != want {
+		return nil, evaluator.NewErrorKind(evaluator.TypeError, "wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var param reflect.Type
+		if t.IsVariadic() && i >= want-1 {
+			param = t.In(want - 1).Elem()
+		} else {
+			param = t.In(i)
+		}
+		v, err := toValue(arg, param)
+		if err != nil {
+			return nil, evaluator.NewErrorKind(evaluator.TypeError, "argument %d to `%s`: %s", i+1, name, err)
+		}
+		in[i] = v
+	}
+	return in, nil
+}
+
+// toValue converts obj to a Go value of type t.
+func toValue(obj evaluator.Object, t reflect.Type) (reflect.Value, error) {
+	if obj == nil {
+		obj = evaluator.NIHIL
+	}
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		v := reflect.New(t).Elem()
+		if g := FromObject(obj); g != nil {
+			v.Set(reflect.ValueOf(g))
+		}
+		return v, nil
+	}
+	if v := reflect.ValueOf(obj); v.Type().AssignableTo(t) {
+		return v, nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch obj := obj.(type) {
+	case *evaluator.Null:
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+			return v, nil
+		}
+	case *evaluator.Boolean:
+		if t.Kind() == reflect.Bool {
+			v.SetBool(obj.Value)
+			return v, nil
+		}
+	case *evaluator.String:
+		if t.Kind() == reflect.String {
+			v.SetString(obj.Value)
+			return v, nil
+		}
+	case *evaluator.Integer:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(obj.Value) {
+				return v, fmt.Errorf("%d overflows %s", obj.Value, t)
+			}
+			v.SetInt(obj.Value)
+			return v, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if obj.Value < 0 || v.OverflowUint(uint64(obj.Value)) {
+				return v, fmt.Errorf("%d overflows %s", obj.Value, t)
+			}
+			v.SetUint(uint64(obj.Value))
+			return v, nil
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(obj.Value))
+			return v, nil
+		}
+	case *evaluator.Float:
+		if t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64 {
+			v.SetFloat(obj.Value)
+			return v, nil
+		}
+	case *evaluator.Array:
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, len(obj.Elements), len(obj.Elements)))
+			for i, elem := range obj.Elements {
+				ev, err := toValue(elem, t.Elem())
+				if err != nil {
+					return v, err
+				}
+				v.Index(i).Set(ev)
+			}
+			return v, nil
+		}
+	case *evaluator.Hash:
+		if t.Kind() == reflect.Map {
+			v.Set(reflect.MakeMapWithSize(t, len(obj.Pairs)))
+			for _, pair := range obj.Pairs {
+				kv, err := toValue(pair.Key, t.Key())
+				if err != nil {
+					return v, err
+				}
+				ev, err := toValue(pair.Value, t.Elem())
+				if err != nil {
+					return v, err
+				}
+				v.SetMapIndex(kv, ev)
+			}
+			return v, nil
+		}
+	}
+
+	if t.Kind() == reflect.Interface {
+		if g := reflect.ValueOf(FromObject(obj)); g.Type().AssignableTo(t) {
+			v.Set(g)
+			return v, nil
+		}
+	}
+	return v, fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+}
@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// assertBuiltin are the assertions of `bilang test`, see
+// RegisterAssertions. A failed assertion is an AssertionError at the call,
+// which ends the uji_* function.
+var assertBuiltin = map[string]*Builtin{
+	"pastikan": {
+		Fn: func(args ...Object) Object {
+			if len(args) < 1 {
+				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if isTruthy(args[0]) {
+				return _NULL
+			}
+			return assertionFailed("pastikan", args[1:], "kondisi bernilai %s", args[0].Inspect())
+		},
+	},
+	"pastikan_sama": {
+		Fn: func(args ...Object) Object {
+			if len(args) < 2 {
+				return NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if equal(args[0], args[1]) {
+				return _NULL
+			}
+			return assertionFailed("pastikan_sama", args[2:], "harapan %s, didapat %s", quote(args[0]), quote(args[1]))
+		},
+	},
+}
+
+// RegisterAssertions adds the assertions pastikan and pastikan_sama to r.
+// They aren't standard builtins: `bilang test` adds them to the scripts
+// running tests only.
+func RegisterAssertions(r *Registry) {
+	for name, b := range assertBuiltin {
+		r.Register(name, b.Fn)
+	}
+}
+
+// assertionFailed returns the AssertionError of the assertion name, the
+// message given by the script comes before the details.
+func assertionFailed(name string, msg []Object, format string, a ...interface{}) *Error {
+	prefix := name + " gagal: "
+	for _, m := range msg {
+		prefix += m.Inspect() + " "
+	}
+	if len(msg) > 0 {
+		prefix = prefix[:len(prefix)-1] + ": "
+	}
+	return NewErrorKind(AssertionError, "%s", prefix+fmt.Sprintf(format, a...))
+}
+
+// quote shows obj like Inspect but with its strings quoted, so "1" and 1
+// can be told apart, and the pairs of hashes sorted.
+func quote(obj Object) string {
+	switch obj := obj.(type) {
+	case *String:
+		return strconv.Quote(obj.Value)
+	case *Array:
+		elements := make([]string, len(obj.Elements))
+		for i, e := range obj.Elements {
+			elements[i] = quote(e)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *Hash:
+		pairs := make([]string, 0, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			pairs = append(pairs, quote(pair.Key)+": "+quote(pair.Value))
+		}
+		sort.Strings(pairs)
+		return "{" + strings.Join(pairs, ", ") + "}"
+	}
+	return obj.Inspect()
+}
+
+// equal reports whether a and b hold the same value: arrays and hashes
+// are compared element by element, other values like == compares them.
+func equal(a, b Object) bool {
+	switch a := a.(type) {
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !equal(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			other, ok := b.Pairs[key]
+			if !ok || !equal(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	if a == b {
+		return true
+	}
+	if a.Type() == NULL || b.Type() == NULL {
+		return a.Type() == b.Type()
+	}
+	eq, ok := evalInfixExpression("==", a, b).(*Boolean)
+	return ok && eq.Value
+}
@@ -1,9 +1,13 @@
 package evaluator
 
 import (
-	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
 )
 
+// builtins are the standard functions outside of a namespace, every
+// Registry starts with them. They must not be modified, see NewRegistry.
 var builtins = map[string]*Builtin{
 	"panjang": {
 		Fn: func(args ...Object) Object {
@@ -12,10 +16,10 @@ var builtins = map[string]*Builtin{
 			}
 			switch arg := args[0].(type) {
 			case *String:
-				return &Float{Value: float64(len(arg.Value))}
+				return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 
 			case *Array:
-				return &Float{Value: float64(len(arg.Elements))}
+				return &Integer{Value: int64(len(arg.Elements))}
 
 			default:
 				return NewError("argument to `panjang` not supported, got %s", args[0].Type())
@@ -87,26 +91,85 @@ var builtins = map[string]*Builtin{
 			return &Array{Elements: newElements}
 		},
 	},
-	"stdout": {
+	"bulat": {
 		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *Integer:
+				return arg
+			case *Float:
+				// truncate toward zero, like Go's int64(f)
+				if math.IsNaN(arg.Value) || arg.Value >= math.MaxInt64 || arg.Value < math.MinInt64 {
+					return NewError("cannot convert %G to INTEGER", arg.Value)
+				}
+				return &Integer{Value: int64(arg.Value)}
+			case *String:
+				i, err := strconv.ParseInt(arg.Value, 10, 64)
+				if err != nil {
+					return NewError("cannot convert %q to INTEGER", arg.Value)
+				}
+				return &Integer{Value: i}
+			default:
+				return NewError("argument to `bulat` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"pecahan": {
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *Integer:
+				return integerToFloat(arg)
+			case *Float:
+				return arg
+			case *String:
+				f, err := strconv.ParseFloat(arg.Value, 64)
+				if err != nil {
+					return NewError("cannot convert %q to FLOAT", arg.Value)
+				}
+				return &Float{Value: f}
+			default:
+				return NewError("argument to `pecahan` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"stdout": {
+		RuntimeFn: func(rt *Runtime, args ...Object) Object {
 			for _, arg := range args {
-				fmt.Print(arg.Inspect())
+				if err := rt.Print(arg.Inspect()); err != nil {
+					return err
+				}
 			}
 			return _NULL
 		},
 	},
+	"baca_baris": {
+		RuntimeFn: func(rt *Runtime, args ...Object) Object {
+			if len(args) != 0 {
+				return NewError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+			line, ok, err := rt.ReadLine()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return _NULL
+			}
+			return &String{Value: line}
+		},
+	},
 	"println": {
-		Fn: func(args ...Object) Object {
+		RuntimeFn: func(rt *Runtime, args ...Object) Object {
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				if err := rt.Print(arg.Inspect() + "\n"); err != nil {
+					return err
+				}
 			}
 			return _NULL
 		},
 	},
 }
-
-func init() {
-	for k, v := range mathBuiltin {
-		builtins[k] = v
-	}
-}
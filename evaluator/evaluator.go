@@ -1,8 +1,12 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"math"
+	"strings"
+	"sync"
 
 	"github.com/dedisuryadi/bilang/ast"
 	"github.com/dedisuryadi/bilang/token"
@@ -16,19 +20,165 @@ var (
 	_CONTINUE = &Continue{}
 )
 
+// Script evaluates programs in a global environment of its own. It is
+// safe for concurrent use: runs take turns, and the setters wait for the
+// current run to finish, so builtins must not call them. Global,
+// SetGlobal and DefineKonst don't wait. Parsed programs are never
+// modified, many scripts may run the same one at once.
 type Script struct {
-	konst map[string]struct{}
+	mu       sync.Mutex // held by a run and by the setters
+	konstMu  sync.RWMutex
+	konst    map[string]struct{} // guarded by konstMu, see isKonst
+	exports  map[string]struct{}
+	file     string
+	modules  *Modules
+	builtins *Registry
+	globals  *Environment
+	loops    int // tiap loops enclosing the current function body
+	tries    int // coba bodies enclosing the current function body
+	calls    []call
+	rt       *Runtime
+	regexes  map[*ast.RegExLiteral]Object // compiled literals, see regex
 }
 
 func NewScript() *Script {
-	return &Script{konst: make(map[string]struct{})}
+	return &Script{
+		konst:    make(map[string]struct{}),
+		exports:  make(map[string]struct{}),
+		modules:  NewModules(),
+		builtins: NewRegistry(),
+		globals:  NewEnvironment(),
+		rt:       NewRuntime(),
+	}
+}
+
+// Run evaluates program in the script's global environment, which keeps
+// its variables between calls.
+func (s *Script) Run(program *ast.Program) Object {
+	return s.RunContext(context.Background(), program)
+}
+
+// RunContext is Run stopping with a LimitError once ctx is done or the
+// run exceeds the script's Limits.
+func (s *Script) RunContext(ctx context.Context, program *ast.Program) Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rt.Start(ctx)
+	return s.Eval(program, s.globals)
+}
+
+// Runtime returns the runtime the script shares with its modules.
+func (s *Script) Runtime() *Runtime {
+	return s.rt
+}
+
+// SetStdout sends the output of stdout and println to w, os.Stdout by
+// default.
+func (s *Script) SetStdout(w io.Writer) {
+	s.rt.SetStdout(w)
+}
+
+// SetStdin makes baca_baris read from r, os.Stdin by default.
+func (s *Script) SetStdin(r io.Reader) {
+	s.rt.SetStdin(r)
+}
+
+// SetLimits bounds every run of the script, see Limits.
+func (s *Script) SetLimits(l Limits) {
+	s.rt.SetLimits(l)
+}
+
+// DefineKonst binds a host provided value as a global konstanta, e.g. argv.
+func (s *Script) DefineKonst(name string, val Object) {
+	s.globals.Set(name, val)
+	s.addKonst(name)
+}
+
+// Global returns the value of a global variable or konstanta.
+func (s *Script) Global(name string) (Object, bool) {
+	return s.globals.Get(name)
+}
+
+// IsKonst reports whether the global name is a konstanta.
+func (s *Script) IsKonst(name string) bool {
+	return s.isKonst(name)
+}
+
+// GlobalNames returns the names of the global variables and konstanta,
+// sorted.
+func (s *Script) GlobalNames() []string {
+	return s.globals.Names()
+}
+
+// SetGlobal assigns a global variable for the host. Unlike var in a
+// script it may change the type of the variable, but konstanta are still
+// read only.
+func (s *Script) SetGlobal(name string, val Object) *Error {
+	if s.isKonst(name) {
+		return NewErrorKind(TypeError, "konstanta %s tidak bisa ditugaskan kembali", name)
+	}
+	s.globals.Set(name, val)
+	return nil
+}
+
+// Builtins returns the registry of the functions the script can call,
+// hosts may change it to sandbox the script.
+func (s *Script) Builtins() *Registry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.builtins
+}
+
+// SetBuiltins replaces the registry of the script, e.g. with one shared
+// by every script of a sandbox.
+func (s *Script) SetBuiltins(r *Registry) {
+	s.mu.Lock()
+	s.builtins = r
+	s.mu.Unlock()
+}
+
+// Reset forgets every global, konstanta and imported module, as if the
+// script were new. The builtins, module loader, limits and stdio stay.
+func (s *Script) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.konstMu.Lock()
+	s.konst = make(map[string]struct{})
+	s.konstMu.Unlock()
+	s.exports = make(map[string]struct{})
+	s.globals = NewEnvironment()
+	s.regexes = nil
+	s.modules.Reset()
 }
 
 func (s *Script) Free() {
+	s.konstMu.Lock()
 	s.konst = nil
+	s.konstMu.Unlock()
+}
+
+func (s *Script) isKonst(name string) bool {
+	s.konstMu.RLock()
+	_, ok := s.konst[name]
+	s.konstMu.RUnlock()
+	return ok
+}
+
+func (s *Script) addKonst(name string) {
+	s.konstMu.Lock()
+	s.konst[name] = struct{}{}
+	s.konstMu.Unlock()
 }
 
+// Eval evaluates node in env, each node counts as a step of the run.
 func (s *Script) Eval(node ast.Node, env *Environment) Object {
+	if err := s.rt.Step(); err != nil {
+		return s.errorAt(err, node.Pos())
+	}
+	return s.eval(node, env)
+}
+
+func (s *Script) eval(node ast.Node, env *Environment) Object {
 	switch node := node.(type) {
 	case *ast.VarStatement:
 		val := s.Eval(node.Value, env)
@@ -36,13 +186,13 @@ func (s *Script) Eval(node ast.Node, env *Environment) Object {
 			return val
 		}
 		name := node.Name.Value
-		if _, ok := s.konst[name]; ok {
-			return &Error{Message: fmt.Sprintf("konstanta %s tidak bisa ditugaskan kembali", name)}
+		if s.isKonst(name) {
+			return s.errorAt(NewErrorKind(TypeError, "konstanta %s tidak bisa ditugaskan kembali", name), node.Token.Pos())
 		}
 		if v, ok := env.Get(name); ok {
 			from, to := v.Type(), val.Type()
 			if from != to {
-				return &Error{Message: fmt.Sprintf("perubahan tipe variabel %s dari %s menjadi %s tidak diizinkan", name, from, to)}
+				return s.errorAt(NewErrorKind(TypeError, "perubahan tipe variabel %s dari %s menjadi %s tidak diizinkan", name, from, to), node.Token.Pos())
 			}
 		}
 		env.Set(name, val)
@@ -53,80 +203,96 @@ func (s *Script) Eval(node ast.Node, env *Environment) Object {
 			return val
 		}
 		konst := node.Name.Value
-		if _, ok := s.konst[konst]; ok {
-			return &Error{Message: fmt.Sprintf("konstanta %s tidak bisa ditugaskan kembali", konst)}
+		if s.isKonst(konst) {
+			return s.errorAt(NewErrorKind(TypeError, "konstanta %s tidak bisa ditugaskan kembali", konst), node.Token.Pos())
 		}
 		env.Set(konst, val)
-		s.konst[konst] = struct{}{}
+		s.addKonst(konst)
 
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &Function{Parameters: params, Env: env, Body: body}
+		return s.alloc(&Function{Name: FunctionName(node), File: s.file, Parameters: params, Env: env, Body: body}, node.Pos())
 
 	case *ast.CallExpression:
-		fn := s.Eval(node.Function, env)
-		if isError(fn) {
-			return fn
-		}
-		args := s.evalExpression(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-		return s.applyFunction(fn, args)
+		return s.evalCallExpression(node, env, false)
 
 	case *ast.MethodCallExpression:
-		if obj, ok := node.Object.(*ast.Identifier); ok {
-			if call, ok := node.Call.(*ast.CallExpression); ok {
-				fn, _ := call.Function.(*ast.Identifier)
-				obj.Value += "." + fn.Value
-				ident := evalIdentifier(obj, env)
-				args := s.evalExpression(call.Arguments, env)
-				if len(args) == 1 && isError(args[0]) {
-					return args[0]
-				}
-				return s.applyFunction(ident, args)
-			}
-		}
-		return NewError("invalid method call expression")
+		return s.errorAt(s.evalMethodCallExpression(node, env), node.Pos())
 
 	case *ast.StringLiteral:
-		return &String{Value: node.Value}
+		return s.alloc(&String{Value: node.Value}, node.Pos())
+
+	case *ast.InterpolatedString:
+		parts := s.evalExpression(node.Parts, env)
+		if len(parts) == 1 && isError(parts[0]) {
+			return parts[0]
+		}
+		return s.alloc(interpolate(parts), node.Pos())
+
+	case *ast.RegExLiteral:
+		return s.errorAt(s.regex(node), node.Pos())
 
 	case *ast.LoopLiteral:
 		return s.evalLoopExpression(node, env)
 	case *ast.BreakExpression:
+		if s.loops == 0 {
+			return s.errorAt(NewError("usai hanya bisa digunakan di dalam tiap"), node.Token.Pos())
+		}
 		return _BREAK
 	case *ast.ContinueExpression:
+		if s.loops == 0 {
+			return s.errorAt(NewError("lanjut hanya bisa digunakan di dalam tiap"), node.Token.Pos())
+		}
 		return _CONTINUE
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return s.errorAt(s.evalIdentifier(node, env), node.Token.Pos())
 
 	case *ast.PilahExpression:
-		return s.evalPilahExpression(node, env)
+		return s.evalPilahExpression(node, env, false)
 
 	case *ast.PilihStatement:
-		val := s.Eval(node.ReturnValue, env)
+		var val Object
+		if len(s.calls) > 0 && s.tries == 0 {
+			val = s.evalTail(node.ReturnValue, env)
+		} else {
+			val = s.Eval(node.ReturnValue, env)
+		}
 		if isError(val) {
 			return val
 		}
 		return &ReturnValue{Value: val}
 
+	case *ast.LemparStatement:
+		return s.evalLemparStatement(node, env)
+
+	case *ast.ImporStatement:
+		return s.errorAt(s.evalImporStatement(node, env), node.Token.Pos())
+
+	case *ast.EksporStatement:
+		return s.evalEksporStatement(node, env)
+
+	case *ast.CobaExpression:
+		return s.evalCobaExpression(node, env)
+
 	case *ast.ExpressionStatement:
 		return s.Eval(node.Expression, env)
 
 	case *ast.Program:
 		return s.evalProgram(node, env)
 
+	case *ast.IntegerLiteral:
+		return s.alloc(&Integer{Value: node.Value}, node.Pos())
+
 	case *ast.FloatLiteral:
-		return &Float{Value: node.Value}
+		return s.alloc(&Float{Value: node.Value}, node.Pos())
 
 	case *ast.PrefixExpression:
 		right := s.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return s.alloc(s.errorAt(evalPrefixExpression(node.Operator, right), node.Token.Pos()), node.Token.Pos())
 
 	case *ast.InfixExpression:
 		left := s.Eval(node.Left, env)
@@ -137,23 +303,26 @@ func (s *Script) Eval(node ast.Node, env *Environment) Object {
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return s.alloc(s.errorAt(evalInfixExpression(node.Operator, left, right), node.Token.Pos()), node.Token.Pos())
 
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
+	case *ast.NihilLiteral:
+		return _NULL
+
 	case *ast.BlockStatement:
 		return s.evalBlockStatement(node, env)
 
 	case *ast.JikaExpression:
-		return s.evalJikaExpression(node, env)
+		return s.evalJikaExpression(node, env, false)
 
 	case *ast.ArrayLiteral:
 		elements := s.evalExpression(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
-		return &Array{Elements: elements}
+		return s.alloc(&Array{Elements: elements}, node.Pos())
 
 	case *ast.Pipe:
 		return s.evalPipeExpression(node, env)
@@ -167,7 +336,7 @@ func (s *Script) Eval(node ast.Node, env *Environment) Object {
 		if isError(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return s.errorAt(evalIndexExpression(left, index), node.Token.Pos())
 
 	case *ast.HashLiteral:
 		return s.evalHashLiteral(node, env)
@@ -176,9 +345,42 @@ func (s *Script) Eval(node ast.Node, env *Environment) Object {
 	return nil
 }
 
+// evalMethodCallExpression handles the dot syntax: `obj.name` reads a
+// member and `obj.name(args)` calls it, see evalMember.
+func (s *Script) evalMethodCallExpression(mc *ast.MethodCallExpression, env *Environment) Object {
+	obj, ok := mc.Object.(*ast.Identifier)
+	if !ok {
+		return NewError("invalid method call expression")
+	}
+
+	switch call := mc.Call.(type) {
+	case *ast.Identifier:
+		return s.evalMember(obj, call.Value, env)
+
+	case *ast.CallExpression:
+		name, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			return NewError("invalid method call expression")
+		}
+		fn := s.evalMember(obj, name.Value, env)
+		if isError(fn) {
+			return fn
+		}
+		args := s.evalExpression(call.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return s.errorAt(s.applyFunction(fn, args, call.Pos()), call.Pos())
+	}
+
+	return NewError("invalid method call expression")
+}
+
 func (s *Script) evalHashLiteral(node *ast.HashLiteral, env *Environment) Object {
+	// in source order, so the last of duplicate keys wins like in the vm
 	pairs := make(map[HashKey]HashPair)
-	for k, v := range node.Pairs {
+	for _, k := range node.Keys() {
+		v := node.Pairs[k]
 		key := s.Eval(k, env)
 		if isError(key) {
 			return key
@@ -186,7 +388,7 @@ func (s *Script) evalHashLiteral(node *ast.HashLiteral, env *Environment) Object
 
 		pk, ok := key.(Hashable)
 		if !ok {
-			return NewError("unusable as hash key: %s", key.Type())
+			return s.errorAt(NewErrorKind(TypeError, "unusable as hash key: %s", key.Type()), node.Token.Pos())
 		}
 
 		value := s.Eval(v, env)
@@ -197,17 +399,21 @@ func (s *Script) evalHashLiteral(node *ast.HashLiteral, env *Environment) Object
 		pairs[pk.HashKey()] = HashPair{Key: key, Value: value}
 	}
 
-	return &Hash{Pairs: pairs}
+	return s.alloc(&Hash{Pairs: pairs}, node.Pos())
 }
 
 func evalIndexExpression(left, index Object) Object {
 	switch {
-	case left.Type() == ARRAY && index.Type() == FLOAT:
+	case left.Type() == ARRAY && index.Type() == INTEGER:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == ARRAY:
+		return NewErrorKind(TypeError, "array index must be INTEGER, got %s", index.Type())
 	case left.Type() == HASH:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == EXCEPTION:
+		return evalExceptionIndexExpression(left, index)
 	default:
-		return NewError("index operator not supported: %s", left.Type())
+		return NewErrorKind(TypeError, "index operator not supported: %s", left.Type())
 	}
 }
 
@@ -216,7 +422,7 @@ func evalHashIndexExpression(hash, index Object) Object {
 
 	key, ok := index.(Hashable)
 	if !ok {
-		return NewError("unusable as hash key: %s", index.Type())
+		return NewErrorKind(TypeError, "unusable as hash key: %s", index.Type())
 	}
 
 	pair, ok := hashObject.Pairs[key.HashKey()]
@@ -227,12 +433,34 @@ func evalHashIndexExpression(hash, index Object) Object {
 	return pair.Value
 }
 
+// evalExceptionIndexExpression exposes a caught error to scripts:
+// e["pesan"], e["jenis"], e["baris"] and e["kolom"].
+func evalExceptionIndexExpression(exception, index Object) Object {
+	err := exception.(*Exception).Err
+	key, ok := index.(*String)
+	if !ok {
+		return NewErrorKind(TypeError, "exception index must be STRING, got %s", index.Type())
+	}
+	switch key.Value {
+	case "pesan":
+		return &String{Value: err.Message}
+	case "jenis":
+		return &String{Value: err.Kind}
+	case "baris":
+		return &Integer{Value: int64(err.Line)}
+	case "kolom":
+		return &Integer{Value: int64(err.Col)}
+	default:
+		return _NULL
+	}
+}
+
 func evalArrayIndexExpression(array, index Object) Object {
 	arrayObj := array.(*Array)
-	idx := index.(*Float).Value
+	idx := index.(*Integer).Value
 	max := int64(len(arrayObj.Elements) - 1)
 
-	if idx < 0 || int64(idx) > max {
+	if idx < 0 || idx > max {
 		return _NULL
 	}
 
@@ -267,7 +495,7 @@ func (s *Script) evalBlockStatement(block *ast.BlockStatement, env *Environment)
 		result = s.Eval(statement, env)
 		if result != nil {
 			rt := result.Type()
-			if rt == RETURN || rt == ERROR {
+			if rt == RETURN || rt == ERROR || rt == BREAK || rt == CONTINUE {
 				return result
 			}
 		}
@@ -282,16 +510,22 @@ func evalPrefixExpression(operator string, right Object) Object {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return NewError("unknown operator: %s%s", operator, right.Type())
+		return NewErrorKind(TypeError, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
 func evalMinusPrefixOperatorExpression(right Object) Object {
-	if right.Type() != FLOAT {
-		return NewError("unknown operator: -%s", right.Type())
+	switch right := right.(type) {
+	case *Integer:
+		if right.Value == math.MinInt64 {
+			return NewErrorKind(ArithmeticError, "integer overflow: -(%d)", right.Value)
+		}
+		return &Integer{Value: -right.Value}
+	case *Float:
+		return &Float{Value: -right.Value}
+	default:
+		return NewErrorKind(TypeError, "unknown operator: -%s", right.Type())
 	}
-	value := right.(*Float).Value
-	return &Float{Value: -value}
 }
 
 func evalBangOperatorExpression(right Object) Object {
@@ -307,16 +541,30 @@ func evalBangOperatorExpression(right Object) Object {
 	}
 }
 
+// evalInfixExpression requires both operands to share a type, with one
+// exception: an INTEGER mixed with a FLOAT is promoted to FLOAT first,
+// so `1 + 0.5` is 1.5 and `1 == 1.0` is benar.
 func evalInfixExpression(operator string, left, right Object) Object {
 	switch {
+	case operator == "=~" || operator == "!~":
+		return evalMatchExpression(operator, left, right)
+	case left.Type() == INTEGER && right.Type() == FLOAT:
+		return evalFloatInfixExpression(operator, integerToFloat(left), right)
+	case left.Type() == FLOAT && right.Type() == INTEGER:
+		return evalFloatInfixExpression(operator, left, integerToFloat(right))
 	case left.Type() != right.Type():
-		return NewError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return NewErrorKind(TypeError, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	case left.Type() == INTEGER && right.Type() == INTEGER:
+		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == FLOAT && right.Type() == FLOAT:
 		return evalFloatInfixExpression(operator, left, right)
 
 	case left.Type() == STRING && right.Type() == STRING:
 		return evalStringInfixExpression(operator, left, right)
 
+	case left.Type() == REGEX && right.Type() == REGEX:
+		return evalRegexInfixExpression(operator, left, right)
+
 	case left.Type() == BOOLEAN && right.Type() == BOOLEAN:
 		lVal := left.(*Boolean).Value
 		rVal := right.(*Boolean).Value
@@ -330,11 +578,11 @@ func evalInfixExpression(operator string, left, right Object) Object {
 		case "!=":
 			return nativeBoolToBooleanObject(left != right)
 		default:
-			return NewError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+			return NewErrorKind(TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 		}
 
 	default:
-		return NewError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return NewErrorKind(TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -347,10 +595,74 @@ func evalStringInfixExpression(operator string, left, right Object) Object {
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	default:
-		return NewError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return NewErrorKind(TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalIntegerInfixExpression follows Go semantics: `/` truncates toward
+// zero and `%` takes the sign of the dividend. Unlike Go, overflow and
+// division by zero are reported as errors instead of wrapping or panicking.
+func evalIntegerInfixExpression(operator string, left, right Object) Object {
+	leftVal := left.(*Integer).Value
+	rightVal := right.(*Integer).Value
+	switch operator {
+	case "%":
+		if rightVal == 0 {
+			return NewErrorKind(ArithmeticError, "division by zero: %d %% %d", leftVal, rightVal)
+		}
+		return &Integer{Value: leftVal % rightVal}
+	case "+":
+		sum := leftVal + rightVal
+		if (sum > leftVal) != (rightVal > 0) {
+			return NewErrorKind(ArithmeticError, "integer overflow: %d + %d", leftVal, rightVal)
+		}
+		return &Integer{Value: sum}
+	case "-":
+		diff := leftVal - rightVal
+		if (diff < leftVal) != (rightVal > 0) {
+			return NewErrorKind(ArithmeticError, "integer overflow: %d - %d", leftVal, rightVal)
+		}
+		return &Integer{Value: diff}
+	case "*":
+		if leftVal == 0 || rightVal == 0 {
+			return &Integer{Value: 0}
+		}
+		product := leftVal * rightVal
+		if product/rightVal != leftVal ||
+			(leftVal == -1 && rightVal == math.MinInt64) ||
+			(rightVal == -1 && leftVal == math.MinInt64) {
+			return NewErrorKind(ArithmeticError, "integer overflow: %d * %d", leftVal, rightVal)
+		}
+		return &Integer{Value: product}
+	case "/":
+		if rightVal == 0 {
+			return NewErrorKind(ArithmeticError, "division by zero: %d / %d", leftVal, rightVal)
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return NewErrorKind(ArithmeticError, "integer overflow: %d / %d", leftVal, rightVal)
+		}
+		return &Integer{Value: leftVal / rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return NewErrorKind(TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+func integerToFloat(obj Object) *Float {
+	return &Float{Value: float64(obj.(*Integer).Value)}
+}
+
 func evalFloatInfixExpression(operator string, left, right Object) Object {
 	leftVal := left.(*Float).Value
 	rightVal := right.(*Float).Value
@@ -378,26 +690,52 @@ func evalFloatInfixExpression(operator string, left, right Object) Object {
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return NewError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return NewErrorKind(TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func (s *Script) evalJikaExpression(je *ast.JikaExpression, env *Environment) Object {
+func (s *Script) evalJikaExpression(je *ast.JikaExpression, env *Environment, tail bool) Object {
 	cond := s.Eval(je.Condition, env)
 	if isError(cond) {
 		return cond
 	}
 
 	if isTruthy(cond) {
-		return s.Eval(je.Consequence, env)
+		return s.evalBranch(je.Consequence, env, tail)
 	} else if je.Alternative != nil {
-		return s.Eval(je.Alternative, env)
+		return s.evalBranch(je.Alternative, env, tail)
 	} else {
 		return _NULL
 	}
 }
 
-func (s *Script) evalPilahExpression(ps *ast.PilahExpression, env *Environment) Object {
+func (s *Script) evalCobaExpression(ce *ast.CobaExpression, env *Environment) Object {
+	s.tries++
+	result := s.Eval(ce.Body, env)
+	s.tries--
+	err, ok := result.(*Error)
+	if !ok || !err.Recoverable() {
+		return result
+	}
+
+	scope := NewEnclosedEnvironment(env)
+	if ce.Param != nil {
+		scope.Set(ce.Param.Value, &Exception{Err: err})
+	}
+	return s.Eval(ce.Handler, scope)
+}
+
+// evalLemparStatement raises an error from a script, see Throw.
+func (s *Script) evalLemparStatement(ls *ast.LemparStatement, env *Environment) Object {
+	val := s.Eval(ls.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	return s.errorAt(Throw(val), ls.Token.Pos())
+}
+
+func (s *Script) evalPilahExpression(ps *ast.PilahExpression, env *Environment, tail bool) Object {
 	target := s.Eval(ps.Target, env)
 	if isError(target) {
 		return target
@@ -405,71 +743,92 @@ func (s *Script) evalPilahExpression(ps *ast.PilahExpression, env *Environment)
 
 	for i, v := range ps.Conditions {
 		if v.Token.Type == token.UNDERSCORE {
-			return s.Eval(ps.Values[i], env)
+			return s.evalBranch(ps.Values[i], env, tail)
 		}
 		cond := s.Eval(v, env)
-		val := evalInfixExpression("==", target, cond)
-		if isTruthy(val) {
-			return s.Eval(ps.Values[i], env)
+		if isError(cond) {
+			return cond
+		}
+		if Match(target, cond) {
+			return s.evalBranch(ps.Values[i], env, tail)
 		}
 	}
 
 	return _NULL
 }
 
+// evalPipeExpression calls the right side with the value of the left
+// side as its first argument: `x |> f(y)` is f(x, y) and `x |> f` is f(x).
+// The value is copied by PipeValue, one that can't be piped gives nihil.
+// The AST is left untouched, so a program can be evaluated any number of
+// times, also concurrently.
 func (s *Script) evalPipeExpression(p *ast.Pipe, env *Environment) Object {
 	left := s.Eval(p.Left, env)
-
-	// Convert the type object back to an expression, so it can be passed to the FunctionCall arg
-	argument := obj2Expression(left)
-	if argument == nil {
+	if isError(left) {
+		return left
+	}
+	arg, ok := PipeValue(left)
+	if !ok {
 		return _NULL
 	}
 
-	// The right side operator should be a function.
-	switch rightFunc := p.Right.(type) {
-	case *ast.MethodCallExpression:
-		switch rightFunc.Call.(type) {
-		case *ast.Identifier:
-			//e.g.
-			//x = ["hello", "world"] |> strings.upper    : rightFunc.Call.(type) == *ast.Identifier
-			//x = ["hello", "world"] |> strings.upper()  : rightFunc.Call.(type) == *ast.CallExpression
-			//so here we convert *ast.Identifier to *ast.CallExpression
-			rightFunc.Call = &ast.CallExpression{Token: p.Token, Function: rightFunc.Call}
-		}
-		rightFunc.Call.(*ast.CallExpression).Arguments = append([]ast.Expression{argument}, rightFunc.Call.(*ast.CallExpression).Arguments...)
-		return s.Eval(rightFunc, env)
+	var (
+		fn   Object
+		rest []ast.Expression
+		pos  = p.Token.Pos()
+	)
+	switch right := p.Right.(type) {
+	case *ast.Identifier:
+		fn, pos = s.Eval(right, env), right.Pos()
 
 	case *ast.CallExpression:
-		rightFunc.Arguments = append([]ast.Expression{argument}, rightFunc.Arguments...)
-		return s.Eval(rightFunc, env)
+		fn, rest, pos = s.Eval(right.Function, env), right.Arguments, right.Pos()
 
-	case *ast.Identifier:
-		fn := s.Eval(rightFunc, env)
-		if isError(fn) {
-			return fn
+	case *ast.MethodCallExpression:
+		// x |> strings.upper or x |> strings.upper(), see evalMethodCallExpression
+		obj, ok := right.Object.(*ast.Identifier)
+		if !ok {
+			return s.errorAt(NewError("invalid method call expression"), right.Pos())
 		}
-		args := s.evalExpression([]ast.Expression{argument}, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		switch call := right.Call.(type) {
+		case *ast.Identifier:
+			fn = s.evalMember(obj, call.Value, env)
+		case *ast.CallExpression:
+			name, ok := call.Function.(*ast.Identifier)
+			if !ok {
+				return s.errorAt(NewError("invalid method call expression"), call.Pos())
+			}
+			fn, rest, pos = s.evalMember(obj, name.Value, env), call.Arguments, call.Pos()
+		default:
+			return s.errorAt(NewError("invalid method call expression"), right.Pos())
 		}
-		return s.applyFunction(fn, args)
 
 	default:
 		// TODO: handle lambda
-		fmt.Printf("unhandled pipe %T %s\n", rightFunc, rightFunc)
+		return _NULL
+	}
+	if isError(fn) {
+		return s.errorAt(fn, pos)
 	}
 
-	return _NULL
+	args := []Object{arg}
+	if len(rest) > 0 {
+		more := s.evalExpression(rest, env)
+		if len(more) == 1 && isError(more[0]) {
+			return more[0]
+		}
+		args = append(args, more...)
+	}
+	return s.errorAt(s.applyFunction(fn, args, pos), pos)
 }
 
-func evalIdentifier(node *ast.Identifier, env *Environment) Object {
-	if b, ok := builtins[node.Value]; ok {
+func (s *Script) evalIdentifier(node *ast.Identifier, env *Environment) Object {
+	if b, ok := s.builtins.Lookup(node.Value); ok {
 		return b
 	}
 	val, ok := env.Get(node.Value)
 	if !ok {
-		return NewError("identifier not found: " + node.Value)
+		return NewErrorKind(NameError, "identifier not found: %s", node.Value)
 	}
 	return val
 }
@@ -486,94 +845,138 @@ func (s *Script) evalExpression(exps []ast.Expression, env *Environment) []Objec
 	return result
 }
 
-func (s *Script) applyFunction(fn Object, args []Object) Object {
+// applyFunction calls fn, pos is the position of the call expression and
+// ends up in tracebacks. Calls in tail position of the body come back as
+// a tailCall and are made here in a loop, replacing the current frame, so
+// tail recursion runs in constant Go stack.
+func (s *Script) applyFunction(fn Object, args []Object, pos token.Pos) Object {
 	switch fn := fn.(type) {
 	case *Function:
 		if paramLen, argsLen := len(fn.Parameters), len(args); paramLen != argsLen {
 			return NewError("invalid length between function parameter=%d & args=%d", paramLen, argsLen)
 		}
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := s.Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		if max := s.rt.MaxDepth(); len(s.calls) >= max {
+			return StackOverflow(max)
+		}
+		s.calls = append(s.calls, call{fn: fn, args: args, pos: pos})
+		loops, tries := s.loops, s.tries
+		var result Object
+		for {
+			s.loops, s.tries = 0, 0
+			result = unwrapReturnValue(s.evalTail(fn.Body, extendFunctionEnv(fn, args)))
+			tc, ok := result.(*tailCall)
+			if !ok {
+				break
+			}
+			fn, args = tc.fn, tc.args
+			s.calls[len(s.calls)-1].fn, s.calls[len(s.calls)-1].args = fn, args
+		}
+		s.loops, s.tries = loops, tries
+		s.calls[len(s.calls)-1] = call{}
+		s.calls = s.calls[:len(s.calls)-1]
+		return result
 
 	case *Builtin:
-		return fn.Fn(args...)
+		result := fn.Call(s.rt, args...)
+		if err := s.rt.Alloc(result); err != nil {
+			return err
+		}
+		return result
 
 	default:
-		return NewError("not a function: %s", fn.Type())
+		return NewErrorKind(TypeError, "not a function: %s", fn.Type())
 	}
 }
 
-func (s *Script) evalLoopExpression(node *ast.LoopLiteral, env *Environment) Object {
-	iter := evalIdentifier(node.Iter, env)
-	if iterable, ok := iter.(Iterable); !ok || !iterable.Iter() {
-		return NewError("identifier %s is not iterable", node.Iter)
-	}
-
-	complete := len(node.KV) > 1
-	switch iter := iter.(type) {
-	case *String:
-		for k, v := range []rune(iter.Value) {
-			scope := NewEnclosedEnvironment(env)
-			scope.Set(node.KV[0].Value, &Float{Value: float64(k)})
-			if complete {
-				scope.Set(node.KV[1].Value, &String{Value: string(v)})
-			}
-			res := s.evalBlockStatement(node.Body, scope)
-			if res.Type() == ERROR {
-				return res
-			}
-			if _, ok := res.(*Continue); ok {
-				continue
-			}
-			if _, ok := res.(*Break); ok {
-				break
-			}
-		}
+// evalCallExpression calls the function, in tail position a call of a
+// Function is returned as a tailCall for applyFunction to make instead.
+func (s *Script) evalCallExpression(node *ast.CallExpression, env *Environment, tail bool) Object {
+	fn := s.Eval(node.Function, env)
+	if isError(fn) {
+		return fn
+	}
+	args := s.evalExpression(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	if f, ok := fn.(*Function); ok && tail && len(f.Parameters) == len(args) {
+		return &tailCall{fn: f, args: args}
+	}
+	return s.errorAt(s.applyFunction(fn, args, node.Pos()), node.Pos())
+}
 
-	case *Hash:
-		for _, v := range iter.Pairs {
-			scope := NewEnclosedEnvironment(env)
-			scope.Set(node.KV[0].Value, v.Key)
-			if complete {
-				scope.Set(node.KV[1].Value, v.Value)
-			}
-			res := s.evalBlockStatement(node.Body, scope)
-			if res.Type() == ERROR {
-				return res
-			}
-			if _, ok := res.(*Continue); ok {
-				continue
+// evalTail evaluates node in tail position of a function body: its value
+// is returned from the function, so calls there can reuse the frame. jika
+// and pilah pass the tail position on to their branches.
+func (s *Script) evalTail(node ast.Node, env *Environment) Object {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		var result Object
+		for i, statement := range node.Statements {
+			if i == len(node.Statements)-1 {
+				return s.evalTail(statement, env)
 			}
-			if _, ok := res.(*Break); ok {
-				break
+			result = s.Eval(statement, env)
+			if result != nil {
+				rt := result.Type()
+				if rt == RETURN || rt == ERROR || rt == BREAK || rt == CONTINUE {
+					return result
+				}
 			}
 		}
+		return result
+	case *ast.ExpressionStatement:
+		return s.evalTail(node.Expression, env)
+	case *ast.CallExpression:
+		return s.evalCallExpression(node, env, true)
+	case *ast.JikaExpression:
+		return s.evalJikaExpression(node, env, true)
+	case *ast.PilahExpression:
+		return s.evalPilahExpression(node, env, true)
+	}
+	return s.Eval(node, env)
+}
 
-	case *Array:
-		for k, v := range iter.Elements {
-			scope := NewEnclosedEnvironment(env)
-			scope.Set(node.KV[0].Value, &Float{Value: float64(k)})
-			if complete {
-				scope.Set(node.KV[1].Value, v)
-			}
-			res := s.evalBlockStatement(node.Body, scope)
-			if res.Type() == ERROR {
-				return res
-			}
-			if _, ok := res.(*Continue); ok {
-				continue
-			}
-			if _, ok := res.(*Break); ok {
-				break
-			}
-		}
+// evalBranch evaluates a branch of jika or pilah, in tail position when
+// the jika or pilah is.
+func (s *Script) evalBranch(node ast.Node, env *Environment, tail bool) Object {
+	if tail {
+		return s.evalTail(node, env)
+	}
+	return s.Eval(node, env)
+}
 
-	default:
-		return NewError("type %s is not iterable", iter)
+func (s *Script) evalLoopExpression(node *ast.LoopLiteral, env *Environment) Object {
+	iter := s.evalIdentifier(node.Iter, env)
+	if isError(iter) {
+		return s.errorAt(iter, node.Iter.Pos())
+	}
+	it, ok := NewIterator(iter)
+	if !ok {
+		return s.errorAt(NewErrorKind(TypeError, "identifier %s is not iterable", node.Iter), node.Iter.Pos())
 	}
 
-	return _NULL
+	s.loops++
+	defer func() { s.loops-- }()
+	for {
+		k, v, ok := it.Next()
+		if !ok {
+			return _NULL
+		}
+		scope := NewEnclosedEnvironment(env)
+		if len(node.KV) > 0 {
+			scope.Set(node.KV[0].Value, k)
+		}
+		if len(node.KV) > 1 {
+			scope.Set(node.KV[1].Value, v)
+		}
+		switch res := s.evalBlockStatement(node.Body, scope).(type) {
+		case *Error, *ReturnValue:
+			return res
+		case *Break:
+			return _NULL
+		}
+	}
 }
 
 func extendFunctionEnv(fn *Function, args []Object) *Environment {
@@ -601,6 +1004,10 @@ func isTruthy(obj Object) bool {
 		return false
 	default:
 		switch obj := obj.(type) {
+		case *Integer:
+			if obj.Value > 0 {
+				return true
+			}
 		case *Float:
 			if obj.Value > 0 {
 				return true
@@ -611,8 +1018,32 @@ func isTruthy(obj Object) bool {
 }
 
 func NewError(format string, a ...interface{}) *Error {
-	return &Error{Message: fmt.Sprintf(format, a...)}
+	return NewErrorKind(RuntimeError, format, a...)
 }
+
+func NewErrorKind(kind, format string, a ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
+
+// alloc counts obj against the limits of the run, see Runtime.Alloc.
+func (s *Script) alloc(obj Object, pos token.Pos) Object {
+	if err := s.rt.Alloc(obj); err != nil {
+		return s.errorAt(err, pos)
+	}
+	return obj
+}
+
+// errorAt stamps pos, the file and the call stack on obj when it is an
+// Error without a position, so the innermost failing node decides where
+// the error is reported.
+func (s *Script) errorAt(obj Object, pos token.Pos) Object {
+	if err, ok := obj.(*Error); ok && err.Line == 0 && pos.IsValid() {
+		err.File, err.Line, err.Col = s.currentFile(), pos.Line, pos.Col
+		err.Stack = s.stack(pos)
+	}
+	return obj
+}
+
 func isError(obj Object) bool {
 	if obj != nil {
 		return obj.Type() == ERROR
@@ -620,40 +1051,12 @@ func isError(obj Object) bool {
 	return false
 }
 
-// Convert an Object to an ast.Expression.
-func obj2Expression(obj Object) ast.Expression {
-	switch value := obj.(type) {
-	case *Boolean:
-		return &ast.Boolean{Value: value.Value}
-	case *Float:
-		return &ast.FloatLiteral{Value: value.Value}
-	case *String:
-		return &ast.StringLiteral{Value: value.Value}
-	case *Null:
-		return &ast.NihilLiteral{}
-	case *Array:
-		array := &ast.ArrayLiteral{}
-		for _, v := range value.Elements {
-			result := obj2Expression(v)
-			if result == nil {
-				return nil
-			}
-			array.Elements = append(array.Elements, result)
-		}
-		return array
-	case *Hash:
-		hash := &ast.HashLiteral{}
-		hash.Pairs = make(map[ast.Expression]ast.Expression)
-		for hk := range value.Pairs { //hk:hash key
-			v, _ := value.Pairs[hk]
-			key := &ast.StringLiteral{Value: v.Key.Inspect()}
-			result := obj2Expression(v.Value)
-			if result == nil {
-				return nil
-			}
-			hash.Pairs[key] = result
-		}
-		return hash
+// interpolate joins the parts of an interpolated string, each shown as
+// Inspect shows it, so strings appear without quotes.
+func interpolate(parts []Object) *String {
+	var out strings.Builder
+	for _, part := range parts {
+		out.WriteString(part.Inspect())
 	}
-	return nil
+	return &String{Value: out.String()}
 }
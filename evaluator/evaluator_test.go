@@ -1,17 +1,62 @@
-package evaluator
+package evaluator_test
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/dedisuryadi/bilang/ast"
+	. "github.com/dedisuryadi/bilang/evaluator"
 	"github.com/dedisuryadi/bilang/lexer"
 	"github.com/dedisuryadi/bilang/parser"
+	"github.com/dedisuryadi/bilang/token"
+	"github.com/dedisuryadi/bilang/vm"
 )
 
-func TestEvalFloatExpression(t *testing.T) {
+// backend selects the Script the tests run on, TestMain runs the whole
+// suite once with the evaluator and once with the bytecode vm.
+var backend string
+
+func TestMain(m *testing.M) {
+	code := 0
+	for _, backend = range []string{"eval", "vm"} {
+		if c := m.Run(); c != 0 {
+			fmt.Printf("FAIL: backend %s\n", backend)
+			code = c
+		}
+	}
+	os.Exit(code)
+}
+
+type script interface {
+	SetFile(path string)
+	SetModuleLoader(loader ModuleLoader)
+	SetMaxDepth(n int)
+	SetLimits(l Limits)
+	SetStdout(w io.Writer)
+	SetStdin(r io.Reader)
+	Builtins() *Registry
+	Run(program *ast.Program) Object
+	RunContext(ctx context.Context, program *ast.Program) Object
+}
+
+func newScript() script {
+	if backend == "vm" {
+		return vm.NewScript()
+	}
+	return NewScript()
+}
+
+func TestEvalIntegerExpression(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected float64
+		expected int64
 	}{
 		{"5", 5},
 		{"10", 10},
@@ -30,12 +75,112 @@ func TestEvalFloatExpression(t *testing.T) {
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
 	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"7 / 2", 3},
+		{"-7 / 2", -3},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7 % -3", 1},
+		{"9223372036854775807 - 1", 9223372036854775806},
+		{"-9223372036854775807 - 1", -9223372036854775807 - 1},
+		{"1 / 0", "division by zero: 1 / 0"},
+		{"1 % 0", "division by zero: 1 % 0"},
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+		{"-(-9223372036854775807 - 1)", "integer overflow: -(-9223372036854775808)"},
+		{"(-9223372036854775807 - 1) / -1", "integer overflow: -9223372036854775808 / -1"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"-2.5", -2.5},
+		{"1e3", 1000},
+		{"0.1 + 0.2 * 2", 0.5},
+		{"7.0 / 2", 3.5},
+		{"2 * 1.5", 3},
+		{"5.5 % 2", 1.5},
+		{"math.Max(0x10, 2.5)", 16},
+	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
 		testFloatObject(t, evaluated, tt.expected)
 	}
 }
 
+func TestMixedNumberPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"pecahan(7) / 2", 3.5},
+		{"7 / pecahan(2)", 3.5},
+		{"1 + pecahan(1) / 2", 1.5},
+		{"pecahan(7) % 2", 1.0},
+		{"bulat(pecahan(7) / 2)", 3},
+		{"bulat(-pecahan(7) / 2)", -3},
+		{`bulat("42")`, 42},
+		{`pecahan("2.5")`, 2.5},
+		{"1 == pecahan(1)", true},
+		{"1 < pecahan(3) / 2", true},
+		{"var a = 1; a = pecahan(1); a", "perubahan tipe variabel a dari INTEGER menjadi FLOAT tidak diizinkan"},
+		{`bulat("satu")`, `cannot convert "satu" to INTEGER`},
+		{`bulat(benar)`, "argument to `bulat` not supported, got BOOLEAN"},
+		{`[1, 2, 3][pecahan(1)]`, "array index must be INTEGER, got FLOAT"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected, tt.input)
+		case string:
+			errObj, ok := evaluated.(*Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func testEval(input string) Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -43,9 +188,20 @@ func testEval(input string) Object {
 	if err != nil {
 		panic(err)
 	}
-	env := NewEnvironment()
-	script := NewScript()
-	return script.Eval(program, env)
+	return newScript().Run(program)
+}
+
+func testIntegerObject(t *testing.T, obj Object, expected int64) bool {
+	result, ok := obj.(*Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+	return true
 }
 
 func testFloatObject(t *testing.T, obj Object, expected float64) bool {
@@ -170,14 +326,14 @@ func TestIfElseExpressions(t *testing.T) {
 		evaluated := testEval(tt.input)
 		integer, ok := tt.expected.(int)
 		if ok {
-			testFloatObject(t, evaluated, float64(integer))
+			testIntegerObject(t, evaluated, int64(integer))
 		} else {
 			testNullObject(t, evaluated)
 		}
 	}
 }
 func testNullObject(t *testing.T, obj Object) bool {
-	if obj != _NULL {
+	if obj != NIHIL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
 		return false
 	}
@@ -187,7 +343,7 @@ func testNullObject(t *testing.T, obj Object) bool {
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected float64
+		expected int64
 	}{
 		{"pilih 10;", 10},
 		{"pilih 10; 9;", 10},
@@ -204,7 +360,7 @@ jika (10 > 1) {
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-		testFloatObject(t, evaluated, tt.expected)
+		testIntegerObject(t, evaluated, tt.expected)
 	}
 }
 
@@ -227,11 +383,11 @@ func TestErrorHandling(t *testing.T) {
 		},
 		{
 			"5 + benar;",
-			"type mismatch: FLOAT + BOOLEAN",
+			"type mismatch: INTEGER + BOOLEAN",
 		},
 		{
 			"5 + benar; 5;",
-			"type mismatch: FLOAT + BOOLEAN",
+			"type mismatch: INTEGER + BOOLEAN",
 		},
 		{
 			"-benar",
@@ -277,7 +433,7 @@ pilih 1;
 func TestVarStatements(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected float64
+		expected int64
 		wantErr  bool
 	}{
 		{input: "var a = 5; a = \"foo\"; a;", wantErr: true},
@@ -291,7 +447,7 @@ func TestVarStatements(t *testing.T) {
 	for _, tt := range tests {
 		res := testEval(tt.input)
 		if !tt.wantErr {
-			testFloatObject(t, res, tt.expected)
+			testIntegerObject(t, res, tt.expected)
 			continue
 		}
 		_, ok := res.(*Error)
@@ -304,7 +460,7 @@ func TestVarStatements(t *testing.T) {
 func TestKonstStatements(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected float64
+		expected int64
 		wantErr  bool
 	}{
 		{input: "konst a = 5; a = 10; a;", wantErr: true},
@@ -317,7 +473,7 @@ func TestKonstStatements(t *testing.T) {
 	for _, tt := range tests {
 		res := testEval(tt.input)
 		if !tt.wantErr {
-			testFloatObject(t, res, tt.expected)
+			testIntegerObject(t, res, tt.expected)
 			continue
 		}
 		_, ok := res.(*Error)
@@ -330,6 +486,17 @@ func TestKonstStatements(t *testing.T) {
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 	evaluated := testEval(input)
+	if backend == "vm" {
+		// the vm returns a closure over compiled code, which shows its source
+		if evaluated == nil || evaluated.Type() != FUNCTION {
+			t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+		}
+		expected := "fn(x) {\n(x + 2)\n}"
+		if evaluated.Inspect() != expected {
+			t.Fatalf("function is not %q. got=%q", expected, evaluated.Inspect())
+		}
+		return
+	}
 	fn, ok := evaluated.(*Function)
 	if !ok {
 		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
@@ -349,7 +516,7 @@ func TestFunctionObject(t *testing.T) {
 func TestFunctionApplication(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected float64
+		expected int64
 	}{
 		{"var identity = fn(x) { x; }; identity(5);", 5},
 		{"var identity = fn(x) { pilih x; }; identity(5);", 5},
@@ -366,7 +533,7 @@ func TestFunctionApplication(t *testing.T) {
 		{"var addTo = x => y => x+y; var addFive = addTo(5); addFive(10);", 15},
 	}
 	for _, tt := range tests {
-		testFloatObject(t, testEval(tt.input), tt.expected)
+		testIntegerObject(t, testEval(tt.input), tt.expected)
 	}
 }
 
@@ -453,7 +620,7 @@ fn(y) { x + y };
 };
 var addTwo = newAdder(2);
 addTwo(2);`
-	testFloatObject(t, testEval(input), 4)
+	testIntegerObject(t, testEval(input), 4)
 }
 
 func TestStringLiteral(t *testing.T) {
@@ -480,24 +647,57 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var nama = "dedi"; var umur = 30; "halo ${nama}, umur ${umur}"`, "halo dedi, umur 30"},
+		{`var x = 1.5; "${x * 2}${benar == benar}${[1, "a"]}"`, "3benar[1, a]"},
+		{`var f = fn(n) { fn() { "n=${n}" } }; f(2)()`, "n=2"},
+		{`"${ {"k": "v"}["k"] } ${"dalam ${1 + 1}"}"`, "v dalam 2"},
+		{`"\${x} \u{1F600}"`, "${x} 😀"},
+		{"`baris ${x}\\n\nkedua`", "baris ${x}\\n\nkedua"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*String)
+		if !ok {
+			t.Errorf("%s: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("%s: String has wrong value. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+
+	errObj, ok := testEval(`"a ${y} b"`).(*Error)
+	if !ok || errObj.Message != "identifier not found: y" || errObj.Line != 1 || errObj.Col != 6 {
+		t.Errorf("wrong error for undefined variable in interpolation. got=%+v", errObj)
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected interface{}
 	}{
-		{`math.Min(10, 0)`, 0},
-		{`math.Max(0, 1)`, 1},
+		{`math.Min(10, 0)`, 0.0},
+		{`math.Max(0, 1)`, 1.0},
 		{`panjang("")`, 0},
 		{`panjang("four")`, 4},
 		{`panjang("hello world")`, 11},
-		{`panjang(1)`, "argument to `panjang` not supported, got FLOAT"},
+		{`panjang("héllo wörld 😀")`, 13},
+		{`panjang(1)`, "argument to `panjang` not supported, got INTEGER"},
 		{`panjang("one", "two")`, "wrong number of arguments. got=2, want=1"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
 		switch expected := tt.expected.(type) {
 		case int:
-			testFloatObject(t, evaluated, float64(expected))
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
 		case string:
 			errObj, ok := evaluated.(*Error)
 			if !ok {
@@ -511,6 +711,58 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the message of the AssertionError, "" when it holds
+		pos      token.Pos
+	}{
+		{`pastikan(1 < 2)`, "", token.Pos{}},
+		{`pastikan(1 > 2)`, "pastikan gagal: kondisi bernilai salah", token.Pos{Line: 1, Col: 1}},
+		{`var x = 0; pastikan(x, "x", "positif")`, "pastikan gagal: x positif: kondisi bernilai 0", token.Pos{Line: 1, Col: 12}},
+		{`pastikan_sama(2, 1 + 1)`, "", token.Pos{}},
+		{`pastikan_sama(2, 2.0)`, "", token.Pos{}},
+		{`pastikan_sama([1, [2]], [1, [2]])`, "", token.Pos{}},
+		{`pastikan_sama({"a": [1]}, {"a": [1]})`, "", token.Pos{}},
+		{`pastikan_sama(awal([]), akhir([]))`, "", token.Pos{}},
+		{"var f = fn() { 1 }\npastikan_sama(f, f)", "", token.Pos{}},
+		{`pastikan_sama(3, 1 + 1)`, "pastikan_sama gagal: harapan 3, didapat 2", token.Pos{Line: 1, Col: 1}},
+		{`pastikan_sama("1", 1, "bulat")`, `pastikan_sama gagal: bulat: harapan "1", didapat 1`, token.Pos{Line: 1, Col: 1}},
+		{`pastikan_sama({"b": 1, "a": "x"}, {"a": 1})`, `pastikan_sama gagal: harapan {"a": "x", "b": 1}, didapat {"a": 1}`, token.Pos{Line: 1, Col: 1}},
+		{`pastikan_sama([1], [1, 2])`, "pastikan_sama gagal: harapan [1], didapat [1, 2]", token.Pos{Line: 1, Col: 1}},
+		{`pastikan(salah, "50% done")`, "pastikan gagal: 50% done: kondisi bernilai salah", token.Pos{Line: 1, Col: 1}},
+		{`pastikan_sama("%d", "%s", "%v")`, `pastikan_sama gagal: %v: harapan "%d", didapat "%s"`, token.Pos{Line: 1, Col: 1}},
+	}
+	for _, tt := range tests {
+		program, err := parser.New(lexer.New(tt.input)).ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newScript()
+		RegisterAssertions(s.Builtins())
+		evaluated := s.Run(program)
+		errObj, ok := evaluated.(*Error)
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("%q: unexpected error %s", tt.input, errObj.Message)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != AssertionError || errObj.Message != tt.expected || errObj.Pos() != tt.pos {
+			t.Errorf("%q: expected %s %q at %s, got %s %q at %s", tt.input, AssertionError, tt.expected, tt.pos, errObj.Kind, errObj.Message, errObj.Pos())
+		}
+	}
+
+	// only the scripts of `bilang test` have them
+	if errObj, ok := testEval(`pastikan(benar)`).(*Error); !ok || errObj.Message != "identifier not found: pastikan" {
+		t.Errorf("pastikan is a standard builtin")
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 	evaluated := testEval(input)
@@ -521,9 +773,9 @@ func TestArrayLiterals(t *testing.T) {
 	if len(result.Elements) != 3 {
 		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
 	}
-	testFloatObject(t, result.Elements[0], 1)
-	testFloatObject(t, result.Elements[1], 4)
-	testFloatObject(t, result.Elements[2], 6)
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
 }
 
 func TestArrayIndexExpressions(t *testing.T) {
@@ -615,7 +867,7 @@ func TestArrayIndexExpressions(t *testing.T) {
 		evaluated := testEval(tt.input)
 		integer, ok := tt.expected.(int)
 		if ok {
-			testFloatObject(t, evaluated, float64(integer))
+			testIntegerObject(t, evaluated, int64(integer))
 		} else {
 			testNullObject(t, evaluated)
 		}
@@ -641,9 +893,9 @@ salah: 6
 		(&String{Value: "one"}).HashKey():   1,
 		(&String{Value: "two"}).HashKey():   2,
 		(&String{Value: "three"}).HashKey(): 3,
-		(&Float{Value: 4}).HashKey():        4,
-		_TRUE.HashKey():                     5,
-		_FALSE.HashKey():                    6,
+		(&Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                      5,
+		FALSE.HashKey():                     6,
 	}
 	if len(result.Pairs) != len(expected) {
 		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
@@ -653,7 +905,26 @@ salah: 6
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
-		testFloatObject(t, pair.Value, float64(expectedValue))
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashLiteralOrder(t *testing.T) {
+	// the order of a map is random, so a few runs are needed to catch it
+	for i := 0; i < 20; i++ {
+		testIntegerObject(t, testEval(`{"a": 1, "a": 2, "a": 3, "a": 4}["a"]`), 4)
+
+		program, err := parser.New(lexer.New(`var f = fn(x) { stdout(x, " "); x }; {f("k1"): f(1), f("k2"): f(2), f("k3"): f(3)}`)).ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		s := newScript()
+		s.SetStdout(&out)
+		s.Run(program)
+		if expected := "k1 1 k2 2 k3 3 "; out.String() != expected {
+			t.Fatalf("pairs evaluated out of order. expected=%q, got=%q", expected, out.String())
+		}
 	}
 }
 
@@ -682,6 +953,26 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{5: 5}[5]`,
 			5,
 		},
+		{
+			`{5: 5}[5.0]`,
+			5,
+		},
+		{
+			`{5.0: 5}[5]`,
+			5,
+		},
+		{
+			`{5: 5}[5.5]`,
+			nil,
+		},
+		{
+			`{1: 1, 1.0: 2}[1]`,
+			2,
+		},
+		{
+			`{-0.0: 5}[0]`,
+			5,
+		},
 		{
 			`{benar: 5}[benar]`,
 			5,
@@ -695,9 +986,375 @@ func TestHashIndexExpressions(t *testing.T) {
 		evaluated := testEval(tt.input)
 		integer, ok := tt.expected.(int)
 		if ok {
-			testFloatObject(t, evaluated, float64(integer))
+			testIntegerObject(t, evaluated, int64(integer))
 		} else {
 			testNullObject(t, evaluated)
 		}
 	}
 }
+
+func TestCobaTangkap(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`coba { 1 + 1 } tangkap (e) { 0 }`, 2},
+		{`coba { 1 / 0 } tangkap (e) { -1 }`, -1},
+		{`coba { 1 / 0 } tangkap { -1 }`, -1},
+		{`coba { 1 / 0 } tangkap (e) { e["pesan"] }`, "division by zero: 1 / 0"},
+		{`coba { 1 / 0 } tangkap (e) { e["jenis"] }`, "ArithmeticError"},
+		{`coba { x } tangkap (e) { e["jenis"] }`, "NameError"},
+		{`coba { 1 + "a" } tangkap (e) { e["jenis"] }`, "TypeError"},
+		{`coba { lempar "gagal" } tangkap (e) { e["pesan"] }`, "gagal"},
+		{`coba { lempar "gagal" } tangkap (e) { e["jenis"] }`, "Error"},
+		{`coba { lempar {"pesan": "tidak valid", "jenis": "ValidasiError"} } tangkap (e) { e["jenis"] + ": " + e["pesan"] }`, "ValidasiError: tidak valid"},
+		{`coba { coba { 1 / 0 } tangkap (e) { lempar e } } tangkap (e) { e["jenis"] }`, "ArithmeticError"},
+		{`coba { 1 / 0 } tangkap (e) { var arr = [e]; awal(arr)["pesan"] }`, "division by zero: 1 / 0"},
+		{`
+var bagi = fn(a, b) {
+	jika (b == 0) { lempar "pembagi nol" }
+	a / b
+}
+var aman = fn(a, b) { coba { bagi(a, b) } tangkap (e) { 0 } }
+aman(10, 2) + aman(1, 0)
+`, 5},
+		{`
+var f = fn() {
+	coba { pilih 1 } tangkap (e) { 2 }
+	3
+}
+f()
+`, 1},
+		{`
+1 + 1
+1 / 0
+`, "ERROR: division by zero: 1 / 0"},
+		{`lempar "tidak tertangkap"`, "ERROR: tidak tertangkap"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated == nil || evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, expected, evaluated)
+			}
+		}
+	}
+}
+
+func TestLoopControl(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`var f = fn(a) { tiap i, v di a { jika (v == 2) { pilih i } }; -1 }; f([1, 2, 3])`, 1},
+		{`var f = fn(a) { tiap i, v di a { jika (v > 1) { usai }; jika (v == 3) { pilih "tidak" } }; "ok" }; f([1, 2, 3])`, "ok"},
+		{`var f = fn(a) { tiap i, v di a { jika (v < 3) { lanjut }; pilih v }; 0 }; f([1, 2, 3])`, 3},
+		{`var f = fn(a) { tiap i, v di a { coba { jika (v == 2) { usai } } tangkap { 0 }; jika (v == 3) { pilih "tidak" } }; "ok" }; f([1, 2, 3])`, "ok"},
+		{`var f = fn(a) { tiap i, v di a { var g = fn() { v * 10 }; jika (v == 2) { pilih g } } }; f([1, 2, 3])()`, 20},
+		{`var f = fn(s) { tiap i, c di s { jika (c == "l") { pilih i } } }; f("halo")`, 2},
+		{`var a = [1]; tiap i di a { }`, nil},
+		{`usai`, "usai hanya bisa digunakan di dalam tiap"},
+		{`var a = [1]; tiap i di a { var f = fn() { lanjut }; f() }`, "lanjut hanya bisa digunakan di dalam tiap"},
+		{`tiap i di x { i }`, "identifier not found: x"},
+		{`var x = 1; tiap i di x { i }`, "identifier x is not iterable"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			var got string
+			switch obj := evaluated.(type) {
+			case *String:
+				got = obj.Value
+			case *Error:
+				got = obj.Message
+			}
+			if got != expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, expected, evaluated)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`var f = fn() { var fakt = fn(n) { jika (n < 1) { 1 } atau { n * fakt(n - 1) } }; fakt(5) }; f()`, 120},
+		{`
+var f = fn(n) {
+	var genap = fn(n) { jika (n == 0) { benar } atau { ganjil(n - 1) } }
+	var ganjil = fn(n) { jika (n == 0) { salah } atau { genap(n - 1) } }
+	genap(n)
+}
+f(10)
+`, true},
+		{`var tambah = fn(a) { fn(b) { fn(c) { a + b + c } } }; tambah(1)(2)(3)`, 6},
+		{`var x = 1; var f = fn() { x = 2; x }; f() + x`, 3},
+		{`var f = fn() { var a = b; var b = 1; a }; f()`, "identifier not found: b"},
+		{`var f = fn() { var x = 1; var g = fn() { x }; x = 2; g() }; f()`, 2},
+		{`var f = fn(x) { var g = fn() { x }; g }; f(7)()`, 7},
+		{`coba { 1 / 0 } tangkap (e) { var x = 1 }; x`, "identifier not found: x"},
+		{`y |> panjang`, "identifier not found: y"},
+		{`pilah 1 { y -> 2 }`, "identifier not found: y"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected, tt.input)
+		case string:
+			errObj, ok := evaluated.(*Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%v", tt.input, expected, evaluated)
+			}
+		}
+	}
+}
+
+func TestCaughtErrorPosition(t *testing.T) {
+	input := `var e = coba {
+	1 + 1
+	10 / 0
+} tangkap (e) { e };
+[e["baris"], e["kolom"]]`
+	evaluated := testEval(input)
+	pos, ok := evaluated.(*Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	testIntegerObject(t, pos.Elements[0], 3)
+	testIntegerObject(t, pos.Elements[1], 5)
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input     string
+		line, col int
+	}{
+		{"var a = 1;\nb", 2, 1},
+		{`"a" - 1`, 1, 5},
+		{"var a = 1;\n  panjang(a, a)", 2, 3},
+		{`[1]["a"]`, 1, 4},
+		{"var x = fn(a) { a + benar };\nx(1)", 1, 19},
+		{"konst k = 1;\nk = 2", 2, 1},
+		{`coba { 1 } tangkap (e) { e }; lempar "x"`, 1, 31},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		err, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if err.Pos() != (token.Pos{Line: tt.line, Col: tt.col}) {
+			t.Errorf("%q: wrong position for %q. expected=%d:%d, got=%s", tt.input, err.Message, tt.line, tt.col, err.Pos())
+		}
+	}
+}
+
+func TestTraceback(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"var bagi = fn(a, b) { a / b };\nvar f = fn(xs) { var r = bagi(awal(xs), 0); r };\nf([1, 2])",
+			"bagi(1, 0)\n\t1:25\nf([1, 2])\n\t2:26\nmain\n\t3:1",
+		},
+		{
+			"var g = fn(f) { 1 + f(2) };\ng(fn(x) { x / \"nol\" })",
+			"fn@2:3(2)\n\t2:13\ng(fn(x) { (x / nol) })\n\t1:21\nmain\n\t2:1",
+		},
+		{
+			"var f = x => x + benar;\nf(\"sangat panjang sekali untuk ditampilkan\")",
+			"f(\"sangat panjang seka...)\n\t1:16\nmain\n\t2:1",
+		},
+		{"1 / 0", "main\n\t1:3"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		err, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if got := err.Traceback(); got != tt.expected {
+			t.Errorf("%q: wrong traceback.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	input := `var turun = fn(n) { 1 + turun(n - 1) }; turun(0)`
+	s := newScript()
+	s.SetMaxDepth(100)
+	program, err := parser.New(lexer.New(input)).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluated := s.Run(program)
+	errObj, ok := evaluated.(*Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "stack overflow: kedalaman pemanggilan melebihi 100" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+	if len(errObj.Stack) != 101 || errObj.Stack[0].String() != "turun(-99)" {
+		t.Errorf("wrong stack, %d frames starting at %v", len(errObj.Stack), errObj.Stack[0])
+	}
+	if !strings.Contains(errObj.Traceback(), "...51 frame dilewati...") {
+		t.Errorf("traceback not elided:\n%s", errObj.Traceback())
+	}
+
+	caught := testEval(`var f = fn() { 1 + f() }; coba { f() } tangkap (e) { e["pesan"] }`)
+	if str, ok := caught.(*String); !ok || !strings.HasPrefix(str.Value, "stack overflow") {
+		t.Errorf("stack overflow not caught. got=%T (%+v)", caught, caught)
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"var hitung = fn(n, acc) { jika (n == 0) { pilih acc }; hitung(n - 1, acc + 1) }; hitung(1000000, 0)", 1000000},
+		{"var hitung = fn(n) { jika (n > 0) { hitung(n - 1) } atau { \"selesai\" } }; hitung(1000000)", "selesai"},
+		{"var hitung = fn(n) { jika (n > 0) { pilih hitung(n - 1) }; n }; hitung(1000000)", 0},
+		{`var genap = fn(n) { pilah n { 0 -> benar
+			_ -> ganjil(n - 1) } }
+		var ganjil = fn(n) { pilah n { 0 -> salah
+			_ -> genap(n - 1) } }
+		genap(1000001)`, false},
+		{"var f = fn(xs) { tiap i, x di xs { pilih g(x) } }; var g = x => x * 2; f([21, 0])", 42},
+		{"var h = fn(n) { coba { pilih k(n) } tangkap (e) { e[\"pesan\"] } }; var k = n => n / 0; h(1)", "division by zero: 1 / 0"},
+		{"var kali = fn(x) { x * 2 }; var f = fn(n) { kali(n) }; f(21)", 42},
+		{"var f = fn() { panjang([1, 2]) }; f()", 2},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected, tt.input)
+		case string:
+			str, ok := evaluated.(*String)
+			if !ok || str.Value != expected {
+				t.Errorf("%q: wrong result. want=%q, got=%T (%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestRegex(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"ERROR db" =~ /^ERROR/`, "benar"},
+		{`"INFO db" =~ ~^ERROR~`, "salah"},
+		{`"INFO db" !~ /^ERROR/`, "benar"},
+		{`/a\/b/`, `/a\/b/`},
+		{`/x+/ == regex.baru("x+")`, "benar"},
+		{`regex.cari("timeout after 30s", /\d+s/)`, "30s"},
+		{`regex.cari("timeout", /\d+/)`, "nihil"},
+		{`regex.cari_semua("1 a 22 b 333", /\d+/)`, "[1, 22, 333]"},
+		{`regex.grup("2024-01-02", /(\d+)-(\d+)-(\d+)/)`, "[2024-01-02, 2024, 01, 02]"},
+		{`regex.grup("ab", /a(x)?(b)/)`, "[ab, nihil, b]"},
+		{`regex.grup_semua("a=1, b=2", /(\w)=(\d)/)`, "[[a=1, a, 1], [b=2, b, 2]]"},
+		{`regex.grup_nama("ERROR db: x", /(?P<level>[A-Z]+) (?P<modul>\w+):/)["modul"]`, "db"},
+		{`regex.grup_nama("x", /(?P<a>y)/)`, "nihil"},
+		{`regex.ganti("30s 5s", /(\d+)s/, "\${1} detik")`, "30 detik 5 detik"},
+		{`regex.pisah("a, b,c", /,\s*/)`, "[a, b, c]"},
+		{`"db: x" |> regex.cari(/\w+:/)`, "db:"},
+		{`pilah "ERROR x" { /^INFO/ -> "info"
+			/^ERROR/ -> "error"
+			_ -> "lain" }`, "error"},
+		{`1 =~ /x/`, "ERROR: type mismatch: INTEGER =~ REGEX"},
+		{`/(/`, "ERROR: regex tidak valid /(/: error parsing regexp: missing closing ): `(`"},
+		{`regex.cari(/x/, "x")`, "ERROR: fungsi regex.cari hanya bisa menerima STRING dan REGEX, didapat: REGEX, STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong result. want=%q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+
+	// a literal is compiled once, every evaluation returns the same Regex
+	evaluated := testEval(`var f = fn() { /x/ }; [f(), f()]`)
+	arr, ok := evaluated.(*Array)
+	if !ok || len(arr.Elements) != 2 || arr.Elements[0] != arr.Elements[1] {
+		t.Errorf("literal compiled more than once. got=%v", evaluated)
+	}
+}
+
+func TestEvaluationDoesNotModifyProgram(t *testing.T) {
+	input := `
+var kurang = fn(a, b) { a - b }
+var f = fn(x) { x |> kurang(1) }
+var g = fn(x) { x |> math.Max(2) }
+var hasil = [f(3), f(3), g(1), g(5), 4 |> kurang(2)]
+hasil
+`
+	program, err := parser.New(lexer.New(input)).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := program.String()
+	for i := 0; i < 2; i++ {
+		if got := newScript().Run(program); got.Inspect() != "[2, 2, 2, 5, 2]" {
+			t.Errorf("run %d: wrong result. got=%s", i, got.Inspect())
+		}
+	}
+	if after := program.String(); after != before {
+		t.Errorf("program modified by evaluation.\nbefore=%s\nafter=%s", before, after)
+	}
+}
+
+// TestConcurrentRuns is meant for go test -race: many scripts run one
+// parsed program, and goroutines share a script.
+func TestConcurrentRuns(t *testing.T) {
+	program, err := parser.New(lexer.New(`
+var ganda = fn(x) { x * 2 }
+var jumlah = fn(arr, acc) { jika (panjang(arr) == 0) { pilih acc }; jumlah(ekor(arr), acc + awal(arr)) }
+var angka = [1, 2, 3]
+angka |> jumlah(0) |> ganda
+`)).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	shared := newScript()
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if got := newScript().Run(program); got.Inspect() != "12" {
+				t.Errorf("own script: wrong result. got=%s", got.Inspect())
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			shared.SetLimits(Limits{MaxSteps: 1 << 20})
+			shared.SetStdout(io.Discard)
+			if got := shared.Run(program); got.Inspect() != "12" {
+				t.Errorf("shared script: wrong result. got=%s", got.Inspect())
+			}
+		}()
+	}
+	wg.Wait()
+}
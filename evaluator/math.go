@@ -4,12 +4,29 @@ import (
 	"math"
 )
 
+// asFloat accepts both INTEGER and FLOAT arguments, so every math.*
+// function works on either kind of number and returns a FLOAT like Go.
+func asFloat(obj Object) (*Float, bool) {
+	switch obj := obj.(type) {
+	case *Float:
+		return obj, true
+	case *Integer:
+		return integerToFloat(obj), true
+	}
+	return nil, false
+}
+
 var mathBuiltin = map[string]*Builtin{
 	"math.Abs": {
 		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return NewError("fungsi math.Abs parameter sebanyak 1, didapat: %d", len(args))
+			}
 			switch arg := args[0].(type) {
 			case *Float:
 				return &Float{Value: math.Abs(arg.Value)}
+			case *Integer:
+				return &Float{Value: math.Abs(float64(arg.Value))}
 			default:
 				return NewError("fungsi math.Abs hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -21,7 +38,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Acos parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Acos hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -34,7 +51,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Acosh parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Acosh hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -47,7 +64,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Asin parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Asin hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -60,7 +77,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Asinh parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Asinh hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -73,7 +90,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Atan parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Atan hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -86,11 +103,11 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 2 {
 				return NewError("fungsi math.Atan2 parameter sebanyak 2, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Atan2 hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.Atan2 hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
@@ -103,7 +120,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Atanh parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Atanh hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -116,7 +133,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Cbrt parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Cbrt hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -129,7 +146,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Ceil parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Ceil hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -142,11 +159,11 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 2 {
 				return NewError("fungsi math.Copysign parameter sebanyak 2, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Copysign hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.Copysign hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
@@ -159,7 +176,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Cos parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Cos hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -172,7 +189,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Cosh parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Cosh hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -185,11 +202,11 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 2 {
 				return NewError("fungsi math.Dim parameter sebanyak 2, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Dim hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.Dim hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
@@ -202,7 +219,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Erf parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Erf hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -215,7 +232,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Erfc parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Erfc hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -228,7 +245,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Erfcinv parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Erfcinv hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -241,7 +258,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Erfinv parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Erfinv hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -254,7 +271,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Exp parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Exp hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -267,7 +284,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Exp2 parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Exp2 hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -280,7 +297,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Expm1 parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Expm1 hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -293,15 +310,15 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 3 {
 				return NewError("fungsi math.FMA parameter sebanyak 3, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.FMA hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.FMA hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
-			z, ok := args[2].(*Float)
+			z, ok := asFloat(args[2])
 			if !ok {
 				return NewError("fungsi math.FMA hanya bisa menerima ANGKA, didapat: %s", args[2].Type())
 			}
@@ -314,7 +331,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Floor parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Floor hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -327,7 +344,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Gamma parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Gamma hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -340,11 +357,11 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 2 {
 				return NewError("fungsi math.Hypot parameter sebanyak 2, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Hypot hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.Hypot hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
@@ -357,7 +374,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.J0 parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.J0 hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -370,7 +387,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.J1 parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.J1 hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -383,7 +400,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Log parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Log hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -396,7 +413,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Log10 parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Log10 hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -409,7 +426,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Log1p parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Log1p hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -422,7 +439,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Log2 parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Log2 hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -435,7 +452,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Logb parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Logb hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -448,11 +465,11 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 2 {
 				return NewError("fungsi math.Max parameter sebanyak 2, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Max hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.Max hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
@@ -465,11 +482,11 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 2 {
 				return NewError("fungsi math.Min parameter sebanyak 2, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Min hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.Min hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
@@ -482,11 +499,11 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 2 {
 				return NewError("fungsi math.Mod parameter sebanyak 2, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Mod hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.Mod hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
@@ -499,11 +516,11 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 2 {
 				return NewError("fungsi math.Pow parameter sebanyak 2, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Pow hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.Pow hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
@@ -516,11 +533,11 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 2 {
 				return NewError("fungsi math.Remainder parameter sebanyak 2, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Remainder hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
-			y, ok := args[1].(*Float)
+			y, ok := asFloat(args[1])
 			if !ok {
 				return NewError("fungsi math.Remainder hanya bisa menerima ANGKA, didapat: %s", args[1].Type())
 			}
@@ -533,7 +550,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Round parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Round hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -546,7 +563,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.RoundToEven parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.RoundToEven hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -559,7 +576,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Sin parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Sin hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -572,7 +589,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Sinh parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Sinh hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -585,7 +602,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Sqrt parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Sqrt hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -598,7 +615,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Tan parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Tan hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -611,7 +628,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Tanh parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Tanh hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -624,7 +641,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Trunc parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Trunc hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -637,7 +654,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Y0 parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Y0 hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
@@ -650,7 +667,7 @@ var mathBuiltin = map[string]*Builtin{
 			if len(args) != 1 {
 				return NewError("fungsi math.Y1 parameter sebanyak 1, didapat: %d", len(args))
 			}
-			x, ok := args[0].(*Float)
+			x, ok := asFloat(args[0])
 			if !ok {
 				return NewError("fungsi math.Y1 hanya bisa menerima ANGKA, didapat: %s", args[0].Type())
 			}
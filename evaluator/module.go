@@ -0,0 +1,216 @@
+package evaluator
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+const MODULE = "MODULE"
+
+// ModuleLoader reads and parses the module file at path. The evaluator
+// can't depend on the parser (the parser tests import the evaluator), so
+// hosts provide one, usually parser.ParseFile.
+type ModuleLoader func(path string) (*ast.Program, error)
+
+// Module is the namespace created by impor. Only names declared with
+// ekspor are visible through it, e.g. `mat.tambah(1, 2)`.
+type Module struct {
+	Name    string
+	Path    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() Type      { return MODULE }
+func (m *Module) Inspect() string { return "modul " + m.Name }
+
+// Modules is shared by a script and every module it imports, so each
+// file is evaluated once no matter how many times it is imported.
+type Modules struct {
+	loader ModuleLoader
+	cache  map[string]*Module
+	stack  []string // files currently being imported, to detect cycles
+}
+
+func NewModules() *Modules {
+	return &Modules{cache: make(map[string]*Module)}
+}
+
+// SetLoader enables impor, without a loader Import returns an error.
+func (m *Modules) SetLoader(loader ModuleLoader) {
+	m.loader = loader
+}
+
+// Reset forgets every imported module, they are evaluated again the next
+// time they are imported.
+func (m *Modules) Reset() {
+	m.cache = make(map[string]*Module)
+}
+
+// ModuleRunner runs the program of the module at path and returns its
+// exported names, or the Error that stopped it.
+type ModuleRunner func(path string, program *ast.Program) (map[string]Object, Object)
+
+// Import resolves path against the directory of from, the file doing the
+// import, and returns the cached Module or loads it and runs it with run.
+func (m *Modules) Import(from, path string, run ModuleRunner) Object {
+	if m.loader == nil {
+		return NewError("impor %s: module loader belum diatur", path)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return NewError("impor %s: %s", path, err)
+	}
+
+	if mod, ok := m.cache[abs]; ok {
+		return mod
+	}
+	for i, p := range m.stack {
+		if p == abs {
+			var cycle []string
+			for _, q := range append(m.stack[i:len(m.stack):len(m.stack)], abs) {
+				cycle = append(cycle, filepath.Base(q))
+			}
+			return NewError("impor siklis: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	program, err := m.loader(abs)
+	if err != nil {
+		return NewError("impor %s: %s", path, err)
+	}
+
+	m.stack = append(m.stack, abs)
+	defer func() { m.stack = m.stack[:len(m.stack)-1] }()
+
+	exports, errObj := run(abs, program)
+	if errObj != nil {
+		return errObj
+	}
+	mod := &Module{
+		Name:    strings.TrimSuffix(filepath.Base(abs), filepath.Ext(abs)),
+		Path:    abs,
+		Exports: exports,
+	}
+	m.cache[abs] = mod
+	return mod
+}
+
+// ImportName returns the name impor binds a module to, either the alias
+// or the file name without its extension.
+func ImportName(is *ast.ImporStatement) (string, *Error) {
+	if is.Alias != nil {
+		return is.Alias.Value, nil
+	}
+	name := strings.TrimSuffix(filepath.Base(is.Path.Value), filepath.Ext(is.Path.Value))
+	if !isIdentifier(name) {
+		return "", NewError("nama modul %q tidak valid, gunakan alias: impor nama %q", name, is.Path.Value)
+	}
+	return name, nil
+}
+
+// SetFile tells the script which file it is evaluating, relative imports
+// are resolved against its directory.
+func (s *Script) SetFile(path string) {
+	s.mu.Lock()
+	s.file = path
+	s.mu.Unlock()
+}
+
+// SetModuleLoader enables impor, without a loader impor returns an error.
+func (s *Script) SetModuleLoader(loader ModuleLoader) {
+	s.mu.Lock()
+	s.modules.SetLoader(loader)
+	s.mu.Unlock()
+}
+
+func (s *Script) evalImporStatement(is *ast.ImporStatement, env *Environment) Object {
+	name, errObj := ImportName(is)
+	if errObj != nil {
+		return errObj
+	}
+
+	mod := s.modules.Import(s.file, is.Path.Value, s.runModule)
+	if isError(mod) {
+		return mod
+	}
+	env.Set(name, mod)
+	return nil
+}
+
+func (s *Script) runModule(path string, program *ast.Program) (map[string]Object, Object) {
+	child := &Script{
+		konst:    make(map[string]struct{}),
+		exports:  make(map[string]struct{}),
+		file:     path,
+		modules:  s.modules,
+		builtins: s.builtins,
+		globals:  NewEnvironment(),
+		rt:       s.rt,
+	}
+	// Eval rather than Run, the module is part of the importer's run.
+	if result := child.Eval(program, child.globals); isError(result) {
+		return nil, result
+	}
+	exports := make(map[string]Object, len(child.exports))
+	for name := range child.exports {
+		if val, ok := child.globals.Get(name); ok {
+			exports[name] = val
+		}
+	}
+	return exports, nil
+}
+
+func (s *Script) evalEksporStatement(es *ast.EksporStatement, env *Environment) Object {
+	result := s.Eval(es.Statement, env)
+	if isError(result) {
+		return result
+	}
+	switch stmt := es.Statement.(type) {
+	case *ast.VarStatement:
+		s.exports[stmt.Name.Value] = struct{}{}
+	case *ast.KonstStatement:
+		s.exports[stmt.Name.Value] = struct{}{}
+	}
+	return result
+}
+
+// evalMember resolves `obj.name`, see Member.
+func (s *Script) evalMember(obj *ast.Identifier, name string, env *Environment) Object {
+	val, _ := env.Get(obj.Value)
+	return Member(s.builtins, obj.Value, val, name)
+}
+
+// Member resolves `obj.name` where val is the value bound to obj, or nil
+// when obj is not a variable: an exported name when val is a module,
+// otherwise a namespaced builtin of r such as math.Max.
+func Member(r *Registry, obj string, val Object, name string) Object {
+	if mod, ok := val.(*Module); ok {
+		if export, ok := mod.Exports[name]; ok {
+			return export
+		}
+		return NewErrorKind(NameError, "%s tidak diekspor oleh modul %s", name, mod.Name)
+	}
+	if b, ok := r.Lookup(obj + "." + name); ok {
+		return b
+	}
+	return NewErrorKind(NameError, "identifier not found: %s.%s", obj, name)
+}
+
+// isIdentifier reports whether name lexes to a single identifier, so it
+// can name a module the way a variable is named.
+func isIdentifier(name string) bool {
+	l := lexer.New(name)
+	tok, err := l.NextToken()
+	if err != nil || tok.Type != token.IDENT || tok.Literal != name {
+		return false
+	}
+	tok, err = l.NextToken()
+	return err == nil && tok.Type == token.EOF
+}
@@ -0,0 +1,202 @@
+package evaluator_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dedisuryadi/bilang/ast"
+	. "github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+)
+
+func writeModules(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func testEvalModule(t *testing.T, dir, input string, loader ModuleLoader) Object {
+	program, err := parser.New(lexer.New(input)).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := newScript()
+	script.SetFile(filepath.Join(dir, "main.bi"))
+	script.SetModuleLoader(loader)
+	return script.Run(program)
+}
+
+func TestImporEkspor(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"mat.bi": `
+			konst PI = 3.14
+			var rahasia = 42
+			ekspor konst tau = PI * 2
+			ekspor var tambah = fn(a, b) { a + b }
+			ekspor var kuadrat = x => x * x
+		`,
+		"lib/teks.bi": `
+			impor "sapa.bi"
+			ekspor var halo = fn(nama) { sapa.awalan + nama }
+		`,
+		"lib/sapa.bi":  `ekspor konst awalan = "halo "`,
+		"nama-aneh.bi": `ekspor var x = 1`,
+		"café.bi":      `ekspor var x = 2`,
+		"mat2.bi":      `ekspor var x = 3`,
+		"jika.bi":      `ekspor var x = 4`,
+		"2mat.bi":      `ekspor var x = 5`,
+	})
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`impor "mat.bi"; mat.tambah(1, 2)`, 3},
+		{`impor "mat.bi"; mat.tau`, 6.28},
+		{`impor "mat.bi"; 4 |> mat.kuadrat`, 16},
+		{`impor m "mat.bi"; m.tambah(2, 2)`, 4},
+		{`impor "mat.bi"; var f = fn(x) { mat.tambah(x, 1) }; f(1) + f(2)`, 5},
+		{`impor "mat.bi"; konst PI = 3; PI`, 3},
+		{`impor "lib/teks.bi"; teks.halo("dunia")`, "halo dunia"},
+		{`impor "mat.bi"; mat.rahasia`, "rahasia tidak diekspor oleh modul mat"},
+		{`impor "mat.bi"; mat.PI`, "PI tidak diekspor oleh modul mat"},
+		{`impor "nama-aneh.bi"`, `nama modul "nama-aneh" tidak valid, gunakan alias: impor nama "nama-aneh.bi"`},
+		{`impor aneh "nama-aneh.bi"; aneh.x`, 1},
+		{`impor "café.bi"; café.x`, 2},
+		{`impor "mat2.bi"; mat2.x`, 3},
+		{`impor "jika.bi"`, `nama modul "jika" tidak valid, gunakan alias: impor nama "jika.bi"`},
+		{`impor "2mat.bi"`, `nama modul "2mat" tidak valid, gunakan alias: impor nama "2mat.bi"`},
+		{`math.Max(1, 2) + math.Max(1, 2)`, 4.0},
+	}
+	for _, tt := range tests {
+		evaluated := testEvalModule(t, dir, tt.input, parser.ParseFile)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *String:
+				if obj.Value != expected {
+					t.Errorf("wrong string for %q. expected=%q, got=%q", tt.input, expected, obj.Value)
+				}
+			case *Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, obj.Message)
+				}
+			default:
+				t.Errorf("unexpected object for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestEksporInsideFunction(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"inner.bi": "var f = fn() { ekspor var z = 3 }\nf()",
+	})
+	evaluated := testEvalModule(t, dir, `impor "inner.bi"; println(inner.z)`, parser.ParseFile)
+	errObj, ok := evaluated.(*Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := filepath.Join(dir, "inner.bi") + ":1:16: ekspor is only allowed at the top level of a module"
+	if !strings.Contains(errObj.Message, expected) {
+		t.Errorf("wrong error message. expected %q in %q", expected, errObj.Message)
+	}
+
+	// a tree built by a host isn't checked by the parser, the name is
+	// exported only if it ends up a global
+	loader := func(path string) (*ast.Program, error) {
+		program, err := parser.ParseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		body := program.Statements[0].(*ast.VarStatement).Value.(*ast.FunctionLiteral).Body
+		body.Statements[0] = &ast.EksporStatement{Statement: body.Statements[0]}
+		return program, nil
+	}
+	dir = writeModules(t, map[string]string{
+		"inner.bi": "var f = fn() { var z = 3 }\nf()",
+	})
+	evaluated = testEvalModule(t, dir, `impor "inner.bi"; println(inner.z)`, loader)
+	if errObj, ok := evaluated.(*Error); !ok || errObj.Message != "z tidak diekspor oleh modul inner" {
+		t.Errorf("expected z not to be exported. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestImporCache(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.bi":    `impor "util.bi"; ekspor var x = util.n`,
+		"b.bi":    `impor "util.bi"; ekspor var y = util.n`,
+		"util.bi": `ekspor var n = 1`,
+	})
+	loads := map[string]int{}
+	loader := func(path string) (*ast.Program, error) {
+		loads[filepath.Base(path)]++
+		return parser.ParseFile(path)
+	}
+	evaluated := testEvalModule(t, dir, `impor "a.bi"; impor "b.bi"; impor "a.bi"; a.x + b.y`, loader)
+	testIntegerObject(t, evaluated, 2)
+	for name, n := range loads {
+		if n != 1 {
+			t.Errorf("module %s loaded %d times, want 1", name, n)
+		}
+	}
+}
+
+func TestImporCycle(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.bi": `impor "b.bi"`,
+		"b.bi": `impor "c.bi"`,
+		"c.bi": `impor "a.bi"`,
+	})
+	evaluated := testEvalModule(t, dir, `impor "a.bi"`, parser.ParseFile)
+	errObj, ok := evaluated.(*Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := "impor siklis: a.bi -> b.bi -> c.bi -> a.bi"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func TestImporWithoutLoader(t *testing.T) {
+	evaluated := testEval(`impor "mat.bi"`)
+	errObj, ok := evaluated.(*Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "impor mat.bi: module loader belum diatur" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestImporErrorPosition(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"rusak.bi": "var a = 1;\nekspor var b = a / 0",
+	})
+	evaluated := testEvalModule(t, dir, `impor "rusak.bi"`, parser.ParseFile)
+	errObj, ok := evaluated.(*Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.File != filepath.Join(dir, "rusak.bi") || errObj.Line != 2 || errObj.Col != 18 {
+		t.Errorf("wrong error position. expected=%s:2:18, got=%s:%d:%d",
+			filepath.Join(dir, "rusak.bi"), errObj.File, errObj.Line, errObj.Col)
+	}
+}
@@ -4,29 +4,38 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/token"
 )
 
 type Type string
 
 const (
-	FLOAT    = "FLOAT"
-	BOOLEAN  = "BOOLEAN"
-	NULL     = "NULL"
-	RETURN   = "RETURN"
-	ERROR    = "ERROR"
-	FUNCTION = "FUNCTION"
-	STRING   = "STRING"
-	BUILTIN  = "BUILTIN"
-	ARRAY    = "ARRAY"
-	HASH     = "HASH"
-	benar    = "benar"
-	salah    = "salah"
-	VOID     = "VOID"
-	BREAK    = "BREAK"
-	CONTINUE = "CONTINUE"
+	INTEGER   = "INTEGER"
+	FLOAT     = "FLOAT"
+	BOOLEAN   = "BOOLEAN"
+	NULL      = "NULL"
+	RETURN    = "RETURN"
+	ERROR     = "ERROR"
+	EXCEPTION = "EXCEPTION"
+	FUNCTION  = "FUNCTION"
+	STRING    = "STRING"
+	BUILTIN   = "BUILTIN"
+	ARRAY     = "ARRAY"
+	HASH      = "HASH"
+	REGEX     = "REGEX"
+	benar     = "benar"
+	salah     = "salah"
+	VOID      = "VOID"
+	BREAK     = "BREAK"
+	CONTINUE  = "CONTINUE"
+	TAIL_CALL = "TAIL_CALL"
 )
 
 type Object interface {
@@ -42,11 +51,29 @@ type BuiltinFunction func(args ...Object) Object
 
 type Builtin struct {
 	Fn BuiltinFunction
+	// RuntimeFn, when set, is called instead of Fn with the Runtime of the
+	// calling script, for builtins such as println that write its output.
+	RuntimeFn func(rt *Runtime, args ...Object) Object
+}
+
+// Call calls the builtin for a script running in rt.
+func (b *Builtin) Call(rt *Runtime, args ...Object) Object {
+	if b.RuntimeFn != nil {
+		return b.RuntimeFn(rt, args...)
+	}
+	return b.Fn(args...)
 }
 
 func (b *Builtin) Type() Type      { return BUILTIN }
 func (b *Builtin) Inspect() string { return "builtin function" }
 
+type Integer struct {
+	Value int64
+}
+
+func (i *Integer) Type() Type      { return INTEGER }
+func (i *Integer) Inspect() string { return strconv.FormatInt(i.Value, 10) }
+
 type Float struct {
 	Value float64
 }
@@ -78,14 +105,48 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() Type      { return RETURN }
 func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
+// Kinds of Error, visible to scripts as e["jenis"] inside tangkap.
+const (
+	RuntimeError    = "RuntimeError"
+	TypeError       = "TypeError"
+	NameError       = "NameError"
+	ArithmeticError = "ArithmeticError"
+	ThrownError     = "Error"
+	LimitError      = "LimitError"     // see Limits, tangkap can't catch most
+	AssertionError  = "AssertionError" // a failed pastikan or pastikan_sama
+)
+
+// Error aborts evaluation until it is caught by coba/tangkap or reaches
+// the top of the program. File, Line and Col point at the innermost node
+// that failed, Line and Col are zero when the position is unknown.
 type Error struct {
 	Message string
+	Kind    string
+	File    string
+	Line    int
+	Col     int
+	Stack   []StackFrame // innermost call first, see Traceback
+	Cause   error        // ErrLimit or the context's error for a LimitError
 }
 
+func (e *Error) Pos() token.Pos { return token.Pos{Line: e.Line, Col: e.Col} }
+
 func (e *Error) Type() Type      { return ERROR }
 func (e *Error) Inspect() string { return "ERROR: " + e.Message }
 
+// Exception is an Error caught by tangkap. Unlike Error it is an ordinary
+// value, so it can be stored, passed around and thrown again with lempar.
+type Exception struct {
+	Err *Error
+}
+
+func (e *Exception) Type() Type      { return EXCEPTION }
+func (e *Exception) Inspect() string { return e.Err.Kind + ": " + e.Err.Message }
+
+// Environment binds the variables of a scope. It is safe for concurrent
+// use, closures shared between scripts may run at the same time.
 type Environment struct {
+	mu    sync.RWMutex
 	store map[string]Object
 	outer *Environment
 }
@@ -102,18 +163,37 @@ func NewEnvironment() *Environment {
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
 	obj, ok := e.store[name]
+	e.mu.RUnlock()
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
 	return obj, ok
 }
 func (e *Environment) Set(name string, val Object) Object {
+	e.mu.Lock()
 	e.store[name] = val
+	e.mu.Unlock()
 	return val
 }
 
+// Names returns the names bound in this scope, without the outer ones,
+// sorted.
+func (e *Environment) Names() []string {
+	e.mu.RLock()
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	e.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
 type Function struct {
+	Name       string // see FunctionName
+	File       string // the function was defined in
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
@@ -185,9 +265,17 @@ func (b *Boolean) HashKey() HashKey {
 	}
 	return HashKey{Type: b.Type(), Value: value}
 }
-func (i *Float) HashKey() HashKey {
+func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
+func (f *Float) HashKey() HashKey {
+	// 1 == 1.0, so a float holding an integer shares its key; this covers
+	// -0 and +0 too
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return (&Integer{Value: int64(f.Value)}).HashKey()
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
 func (s *String) HashKey() HashKey {
 	h := fnv.New64()
 	_, _ = h.Write([]byte(s.Value))
@@ -229,3 +317,13 @@ type Continue struct{}
 
 func (c *Continue) Inspect() string { return "lanjut" }
 func (c *Continue) Type() Type      { return CONTINUE }
+
+// tailCall is a call in tail position that applyFunction has yet to make,
+// it never escapes the function body it was returned from.
+type tailCall struct {
+	fn   *Function
+	args []Object
+}
+
+func (tc *tailCall) Inspect() string { return tc.fn.Name }
+func (tc *tailCall) Type() Type      { return TAIL_CALL }
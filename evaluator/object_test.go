@@ -1,6 +1,9 @@
 package evaluator
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +20,28 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestNumberHashKey(t *testing.T) {
+	one := &Integer{Value: 1}
+	oneAgain := &Integer{Value: 1}
+	oneFloat := &Float{Value: 1}
+	oneHalf := &Float{Value: 1.5}
+	if one.HashKey() != oneAgain.HashKey() {
+		t.Errorf("integers with same value have different hash keys")
+	}
+	if oneFloat.HashKey() == oneHalf.HashKey() {
+		t.Errorf("floats with different value have same hash keys")
+	}
+	if one.HashKey() != oneFloat.HashKey() {
+		t.Errorf("equal integer and float have different hash keys")
+	}
+	if one.HashKey() == oneHalf.HashKey() {
+		t.Errorf("integer and float with different value have same hash keys")
+	}
+	if (&Float{Value: 0}).HashKey() != (&Float{Value: math.Copysign(0, -1)}).HashKey() {
+		t.Errorf("positive and negative zero have different hash keys")
+	}
+	if (&Float{Value: math.Inf(1)}).HashKey() == (&Float{Value: math.Inf(-1)}).HashKey() {
+		t.Errorf("infinities of different sign have same hash keys")
+	}
+}
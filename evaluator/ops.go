@@ -0,0 +1,161 @@
+package evaluator
+
+// The functions in this file expose the evaluator's semantics to the
+// bytecode vm, so both backends compute exactly the same values and
+// errors for every operator, builtin and statement.
+
+// NIHIL, TRUE and FALSE are the only instances of their types, other
+// backends must return them rather than allocating new ones.
+var (
+	NIHIL = _NULL
+	TRUE  = _TRUE
+	FALSE = _FALSE
+)
+
+// Infix applies a binary operator such as "+" or "==".
+func Infix(operator string, left, right Object) Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+// Prefix applies "!" or "-".
+func Prefix(operator string, right Object) Object {
+	return evalPrefixExpression(operator, right)
+}
+
+// Interpolate joins the values of the parts of an interpolated string.
+func Interpolate(parts []Object) *String {
+	return interpolate(parts)
+}
+
+// Index evaluates left[index].
+func Index(left, index Object) Object {
+	return evalIndexExpression(left, index)
+}
+
+func IsTruthy(obj Object) bool {
+	return isTruthy(obj)
+}
+
+func NativeBool(value bool) *Boolean {
+	if value {
+		return _TRUE
+	}
+	return _FALSE
+}
+
+// Match reports whether a pilah condition selects its branch. Values of
+// different types never match, they don't raise a type mismatch, except a
+// REGEX condition which matches the STRING targets it finds a match in.
+func Match(target, cond Object) bool {
+	if re, ok := cond.(*Regex); ok {
+		str, ok := target.(*String)
+		return ok && re.Value.MatchString(str.Value)
+	}
+	return isTruthy(evalInfixExpression("==", target, cond))
+}
+
+// Throw converts the operand of lempar into the Error it raises. A STRING
+// becomes the message, a HASH may set "pesan" and "jenis", and a caught
+// EXCEPTION is rethrown untouched so it keeps its original kind and
+// position.
+func Throw(val Object) *Error {
+	switch val := val.(type) {
+	case nil:
+		return NewErrorKind(ThrownError, "%s", _NULL.Inspect())
+	case *Exception:
+		return val.Err
+	case *String:
+		return NewErrorKind(ThrownError, "%s", val.Value)
+	case *Hash:
+		err := NewErrorKind(ThrownError, "%s", val.Inspect())
+		if pesan, ok := val.Pairs[(&String{Value: "pesan"}).HashKey()]; ok {
+			err.Message = pesan.Value.Inspect()
+		}
+		if jenis, ok := val.Pairs[(&String{Value: "jenis"}).HashKey()]; ok {
+			err.Kind = jenis.Value.Inspect()
+		}
+		return err
+	default:
+		return NewErrorKind(ThrownError, "%s", val.Inspect())
+	}
+}
+
+// PipeValue copies the left operand of |> the way it is handed to the
+// function on the right: arrays are copied, hash keys become STRINGs and
+// values that can't be piped, such as functions, report false.
+func PipeValue(obj Object) (Object, bool) {
+	switch value := obj.(type) {
+	case *Boolean, *Integer, *Float, *String, *Null:
+		return value, true
+	case *Array:
+		elements := make([]Object, len(value.Elements))
+		for i, e := range value.Elements {
+			v, ok := PipeValue(e)
+			if !ok {
+				return nil, false
+			}
+			elements[i] = v
+		}
+		return &Array{Elements: elements}, true
+	case *Hash:
+		pairs := make(map[HashKey]HashPair, len(value.Pairs))
+		for _, pair := range value.Pairs {
+			v, ok := PipeValue(pair.Value)
+			if !ok {
+				return nil, false
+			}
+			key := &String{Value: pair.Key.Inspect()}
+			pairs[key.HashKey()] = HashPair{Key: key, Value: v}
+		}
+		return &Hash{Pairs: pairs}, true
+	}
+	return nil, false
+}
+
+// Iterator walks a value the way tiap does: the characters of a STRING and
+// the elements of an ARRAY with their index, or the pairs of a HASH.
+type Iterator struct {
+	keys   func(i int) Object
+	values func(i int) Object
+	len    int
+	i      int
+}
+
+// NewIterator reports false when obj is not iterable.
+func NewIterator(obj Object) (*Iterator, bool) {
+	index := func(i int) Object { return &Integer{Value: int64(i)} }
+	switch obj := obj.(type) {
+	case *String:
+		runes := []rune(obj.Value)
+		return &Iterator{len: len(runes), keys: index, values: func(i int) Object {
+			return &String{Value: string(runes[i])}
+		}}, true
+	case *Array:
+		elements := obj.Elements
+		return &Iterator{len: len(elements), keys: index, values: func(i int) Object {
+			return elements[i]
+		}}, true
+	case *Hash:
+		pairs := make([]HashPair, 0, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			pairs = append(pairs, pair)
+		}
+		return &Iterator{
+			len:    len(pairs),
+			keys:   func(i int) Object { return pairs[i].Key },
+			values: func(i int) Object { return pairs[i].Value },
+		}, true
+	}
+	return nil, false
+}
+
+// Next returns the next key and value, ok is false once the iterator is
+// exhausted.
+func (it *Iterator) Next() (key, value Object, ok bool) {
+	if it.i >= it.len {
+		return nil, nil, false
+	}
+	i := it.i
+	it.i++
+	return it.keys(i), it.values(i), true
+}
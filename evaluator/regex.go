@@ -0,0 +1,220 @@
+package evaluator
+
+import (
+	"regexp"
+
+	"github.com/dedisuryadi/bilang/ast"
+)
+
+// Regex is a compiled regular expression, the value of /pola/ and ~pola~
+// literals. It uses the RE2 syntax of Go's regexp package.
+type Regex struct {
+	Value *regexp.Regexp
+}
+
+func (r *Regex) Type() Type      { return REGEX }
+func (r *Regex) Inspect() string { return "/" + r.Value.String() + "/" }
+
+// NewRegex compiles pattern, an invalid pattern is an error.
+func NewRegex(pattern string) Object {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return NewError("regex tidak valid /%s/: %s", pattern, err)
+	}
+	return &Regex{Value: re}
+}
+
+// regex returns the Regex of a literal, compiling it the first time the
+// literal is evaluated only.
+func (s *Script) regex(node *ast.RegExLiteral) Object {
+	if re, ok := s.regexes[node]; ok {
+		return re
+	}
+	re := NewRegex(node.Value)
+	if s.regexes == nil {
+		s.regexes = make(map[*ast.RegExLiteral]Object)
+	}
+	s.regexes[node] = re
+	return re
+}
+
+// evalMatchExpression applies =~ and !~, which match a STRING against a
+// REGEX.
+func evalMatchExpression(operator string, left, right Object) Object {
+	str, ok := left.(*String)
+	re, isRegex := right.(*Regex)
+	if !ok || !isRegex {
+		return NewErrorKind(TypeError, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	}
+	matched := re.Value.MatchString(str.Value)
+	if operator == "!~" {
+		matched = !matched
+	}
+	return nativeBoolToBooleanObject(matched)
+}
+
+// evalRegexInfixExpression compares regexes by their pattern.
+func evalRegexInfixExpression(operator string, left, right Object) Object {
+	leftVal := left.(*Regex).Value.String()
+	rightVal := right.(*Regex).Value.String()
+	switch operator {
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return NewErrorKind(TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// regexArgs checks the arguments of the regex.* function name, a STRING
+// followed by a REGEX and then want-2 more arguments.
+func regexArgs(name string, want int, args []Object) (string, *regexp.Regexp, *Error) {
+	if len(args) != want {
+		return "", nil, NewError("fungsi regex.%s parameter sebanyak %d, didapat: %d", name, want, len(args))
+	}
+	str, ok := args[0].(*String)
+	re, isRegex := args[1].(*Regex)
+	if !ok || !isRegex {
+		return "", nil, NewError("fungsi regex.%s hanya bisa menerima STRING dan REGEX, didapat: %s, %s", name, args[0].Type(), args[1].Type())
+	}
+	return str.Value, re.Value, nil
+}
+
+// groups turns the submatch indexes of a match into an ARRAY, the whole
+// match first. Groups that did not take part in the match are nihil.
+func groups(s string, loc []int) *Array {
+	elements := make([]Object, len(loc)/2)
+	for i := range elements {
+		if loc[2*i] < 0 {
+			elements[i] = _NULL
+			continue
+		}
+		elements[i] = &String{Value: s[loc[2*i]:loc[2*i+1]]}
+	}
+	return &Array{Elements: elements}
+}
+
+func strings2Array(strs []string) *Array {
+	elements := make([]Object, len(strs))
+	for i, s := range strs {
+		elements[i] = &String{Value: s}
+	}
+	return &Array{Elements: elements}
+}
+
+var regexBuiltin = map[string]*Builtin{
+	//baru(pola string) regex
+	"regex.baru": {
+		Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return NewError("fungsi regex.baru parameter sebanyak 1, didapat: %d", len(args))
+			}
+			pattern, ok := args[0].(*String)
+			if !ok {
+				return NewError("fungsi regex.baru hanya bisa menerima STRING, didapat: %s", args[0].Type())
+			}
+			return NewRegex(pattern.Value)
+		},
+	},
+	//cari(s string, re regex) string, nihil jika tidak ketemu
+	"regex.cari": {
+		Fn: func(args ...Object) Object {
+			s, re, err := regexArgs("cari", 2, args)
+			if err != nil {
+				return err
+			}
+			loc := re.FindStringIndex(s)
+			if loc == nil {
+				return _NULL
+			}
+			return &String{Value: s[loc[0]:loc[1]]}
+		},
+	},
+	//cari_semua(s string, re regex) []string
+	"regex.cari_semua": {
+		Fn: func(args ...Object) Object {
+			s, re, err := regexArgs("cari_semua", 2, args)
+			if err != nil {
+				return err
+			}
+			return strings2Array(re.FindAllString(s, -1))
+		},
+	},
+	//grup(s string, re regex) [cocokan, grup1, grup2, ...], nihil jika tidak ketemu
+	"regex.grup": {
+		Fn: func(args ...Object) Object {
+			s, re, err := regexArgs("grup", 2, args)
+			if err != nil {
+				return err
+			}
+			loc := re.FindStringSubmatchIndex(s)
+			if loc == nil {
+				return _NULL
+			}
+			return groups(s, loc)
+		},
+	},
+	//grup_semua(s string, re regex) [][cocokan, grup1, grup2, ...]
+	"regex.grup_semua": {
+		Fn: func(args ...Object) Object {
+			s, re, err := regexArgs("grup_semua", 2, args)
+			if err != nil {
+				return err
+			}
+			matches := re.FindAllStringSubmatchIndex(s, -1)
+			elements := make([]Object, len(matches))
+			for i, loc := range matches {
+				elements[i] = groups(s, loc)
+			}
+			return &Array{Elements: elements}
+		},
+	},
+	//grup_nama(s string, re regex) {nama: grup}, nihil jika tidak ketemu
+	"regex.grup_nama": {
+		Fn: func(args ...Object) Object {
+			s, re, err := regexArgs("grup_nama", 2, args)
+			if err != nil {
+				return err
+			}
+			loc := re.FindStringSubmatchIndex(s)
+			if loc == nil {
+				return _NULL
+			}
+			values := groups(s, loc).Elements
+			pairs := make(map[HashKey]HashPair)
+			for i, name := range re.SubexpNames() {
+				if name == "" {
+					continue
+				}
+				key := &String{Value: name}
+				pairs[key.HashKey()] = HashPair{Key: key, Value: values[i]}
+			}
+			return &Hash{Pairs: pairs}
+		},
+	},
+	//ganti(s string, re regex, pengganti string) string, $1 dan \${nama} diganti grupnya
+	"regex.ganti": {
+		Fn: func(args ...Object) Object {
+			s, re, err := regexArgs("ganti", 3, args)
+			if err != nil {
+				return err
+			}
+			repl, ok := args[2].(*String)
+			if !ok {
+				return NewError("fungsi regex.ganti hanya bisa menerima pengganti STRING, didapat: %s", args[2].Type())
+			}
+			return &String{Value: re.ReplaceAllString(s, repl.Value)}
+		},
+	},
+	//pisah(s string, re regex) []string
+	"regex.pisah": {
+		Fn: func(args ...Object) Object {
+			s, re, err := regexArgs("pisah", 2, args)
+			if err != nil {
+				return err
+			}
+			return strings2Array(re.Split(s, -1))
+		},
+	},
+}
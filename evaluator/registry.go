@@ -0,0 +1,87 @@
+package evaluator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds the builtins a script can call, by name: "panjang", or
+// "math.Max" for a function in a namespace, which scripts call with the
+// dot syntax. Every Script owns one, so a host can add, replace or remove
+// functions for one sandbox without touching the others. It is safe for
+// concurrent use.
+type Registry struct {
+	mu  sync.RWMutex
+	fns map[string]*Builtin
+}
+
+// NewRegistry returns a registry with the standard builtins: panjang,
+// push, println and the others, and the math.* and regex.* namespaces.
+func NewRegistry() *Registry {
+	r := &Registry{fns: make(map[string]*Builtin, len(builtins)+len(mathBuiltin)+len(regexBuiltin))}
+	for _, std := range []map[string]*Builtin{builtins, mathBuiltin, regexBuiltin} {
+		for name, b := range std {
+			r.fns[name] = b
+		}
+	}
+	return r
+}
+
+// Register adds fn as the builtin called name, replacing the builtin
+// already called so in this registry only.
+func (r *Registry) Register(name string, fn BuiltinFunction) {
+	r.mu.Lock()
+	r.fns[name] = &Builtin{Fn: fn}
+	r.mu.Unlock()
+}
+
+// Remove removes the builtins called names. A name ending in ".*" removes
+// a whole namespace, e.g. "math.*".
+func (r *Registry) Remove(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		if ns := strings.TrimSuffix(name, "*"); ns != name && strings.HasSuffix(ns, ".") {
+			for fn := range r.fns {
+				if strings.HasPrefix(fn, ns) {
+					delete(r.fns, fn)
+				}
+			}
+			continue
+		}
+		delete(r.fns, name)
+	}
+}
+
+// Lookup returns the builtin called name.
+func (r *Registry) Lookup(name string) (*Builtin, bool) {
+	r.mu.RLock()
+	b, ok := r.fns[name]
+	r.mu.RUnlock()
+	return b, ok
+}
+
+// Names returns the names of every builtin, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.fns))
+	for name := range r.fns {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Clone returns a registry with the same builtins, which can then be
+// changed independently.
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	clone := &Registry{fns: make(map[string]*Builtin, len(r.fns))}
+	for name, b := range r.fns {
+		clone.fns[name] = b
+	}
+	return clone
+}
@@ -0,0 +1,128 @@
+package evaluator_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+)
+
+func runScript(t *testing.T, s script, input string) Object {
+	program, err := parser.New(lexer.New(input)).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Run(program)
+}
+
+func TestRegistry(t *testing.T) {
+	sandbox := newScript()
+	r := sandbox.Builtins()
+	r.Register("teks.ulang", func(args ...Object) Object {
+		s := args[0].(*String).Value
+		return &String{Value: s + s}
+	})
+	r.Register("panjang", func(args ...Object) Object {
+		return &Integer{Value: -1}
+	})
+	r.Remove("println", "math.*")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`teks.ulang("ab")`, "abab"},
+		{`panjang("abc")`, "-1"},
+		{`println(1)`, "ERROR: identifier not found: println"},
+		{`math.Abs(-1)`, "ERROR: identifier not found: math.Abs"},
+		{`regex.cari("baa", /a+/)`, "aa"},
+	}
+	for _, tt := range tests {
+		if got := runScript(t, sandbox, tt.input).Inspect(); got != tt.expected {
+			t.Errorf("sandbox %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	// the changes are local to the sandbox
+	other := newScript()
+	tests = []struct {
+		input    string
+		expected string
+	}{
+		{`teks.ulang("ab")`, "ERROR: identifier not found: teks.ulang"},
+		{`panjang("abc")`, "3"},
+		{`math.Abs(-1)`, "1"},
+	}
+	for _, tt := range tests {
+		if got := runScript(t, other, tt.input).Inspect(); got != tt.expected {
+			t.Errorf("other %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRegistryClone(t *testing.T) {
+	r := NewRegistry()
+	r.Register("satu", func(args ...Object) Object { return &Integer{Value: 1} })
+	clone := r.Clone()
+	clone.Remove("satu", "panjang")
+
+	if _, ok := r.Lookup("satu"); !ok {
+		t.Errorf("removing from the clone changed the original")
+	}
+	if _, ok := clone.Lookup("panjang"); ok {
+		t.Errorf("panjang not removed from the clone")
+	}
+	names := clone.Names()
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Fatalf("names not sorted: %q >= %q", names[i-1], names[i])
+		}
+	}
+}
+
+func TestSignature(t *testing.T) {
+	for _, name := range NewRegistry().Names() {
+		if _, ok := Signature(name); !ok {
+			t.Errorf("standard builtin %s has no signature", name)
+		}
+	}
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"panjang", "panjang(x string|array) integer"},
+		{"math.Max", "math.Max(x, y angka) float"},
+		{"math.Sqrt", "math.Sqrt(x angka) float"},
+		{"regex.baru", "regex.baru(pola string) regex"},
+	}
+	for _, tt := range tests {
+		if got, _ := Signature(tt.name); got != tt.expected {
+			t.Errorf("Signature(%q): expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+	}
+	if sig, ok := Signature("math.Tidak"); ok {
+		t.Errorf("unknown builtin has a signature %q", sig)
+	}
+}
+
+func TestRegistryConcurrentUse(t *testing.T) {
+	r := NewRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("f%d", i)
+			for j := 0; j < 100; j++ {
+				r.Register(name, func(args ...Object) Object { return NIHIL })
+				r.Lookup("panjang")
+				r.Names()
+				r.Remove(name)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
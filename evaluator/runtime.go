@@ -0,0 +1,216 @@
+package evaluator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Limits bounds what a script may use in one run, zero means unlimited.
+type Limits struct {
+	MaxSteps   int64 // nodes evaluated, or instructions run by the vm
+	MaxDepth   int   // nested function calls, zero means DefaultMaxDepth
+	MaxObjects int64 // values allocated
+	MaxBytes   int64 // approximate size of the values allocated
+	MaxOutput  int64 // bytes written by stdout and println
+}
+
+var (
+	// ErrLimit is the Cause of the errors raised for exceeded Limits.
+	ErrLimit = errors.New("limit exceeded")
+
+	// ErrStackOverflow is the Cause of a stack overflow. Unlike the other
+	// limits it can be caught, unwinding to tangkap frees the stack.
+	ErrStackOverflow = fmt.Errorf("stack overflow: %w", ErrLimit)
+)
+
+// Runtime is what a script shares with the modules it imports and the
+// builtins it calls: the context of the current run, the limits and how
+// much of them was used, and the input and output.
+//
+// The setters are safe to call while a script runs, a run keeps the
+// settings it started with and changes apply to the next one.
+type Runtime struct {
+	mu       sync.Mutex
+	settings settings // guarded by mu
+
+	run     settings // copied by Start, only the run uses it
+	ctx     context.Context
+	done    <-chan struct{}
+	steps   int64
+	objects int64
+	bytes   int64
+	written int64
+}
+
+type settings struct {
+	limits Limits
+	out    io.Writer
+	in     *bufio.Reader
+}
+
+// NewRuntime returns a Runtime without limits that writes to os.Stdout
+// and reads from os.Stdin.
+func NewRuntime() *Runtime {
+	rt := &Runtime{settings: settings{out: os.Stdout, in: bufio.NewReader(os.Stdin)}}
+	rt.Start(context.Background())
+	return rt
+}
+
+// Start begins a run under ctx, the limits apply to each run afresh.
+func (rt *Runtime) Start(ctx context.Context) {
+	rt.mu.Lock()
+	rt.run = rt.settings
+	rt.mu.Unlock()
+	rt.ctx, rt.done = ctx, ctx.Done()
+	rt.steps, rt.objects, rt.bytes, rt.written = 0, 0, 0, 0
+}
+
+// Limits returns the limits of the next runs.
+func (rt *Runtime) Limits() Limits {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.settings.limits
+}
+
+// SetLimits sets the limits of the next runs.
+func (rt *Runtime) SetLimits(l Limits) {
+	rt.mu.Lock()
+	rt.settings.limits = l
+	rt.mu.Unlock()
+}
+
+// SetMaxDepth changes only the MaxDepth of the limits.
+func (rt *Runtime) SetMaxDepth(n int) {
+	rt.mu.Lock()
+	rt.settings.limits.MaxDepth = n
+	rt.mu.Unlock()
+}
+
+// MaxDepth is how deep function calls may nest in the current run.
+func (rt *Runtime) MaxDepth() int {
+	if n := rt.run.limits.MaxDepth; n > 0 {
+		return n
+	}
+	return DefaultMaxDepth
+}
+
+// SetStdout sends the output of stdout and println to w.
+func (rt *Runtime) SetStdout(w io.Writer) {
+	rt.mu.Lock()
+	rt.settings.out = w
+	rt.mu.Unlock()
+}
+
+// SetStdin makes baca_baris read from r. Wrap r in a *bufio.Reader to
+// share it with the host, it is used as is, otherwise the runtime may
+// read ahead of the line it returns.
+func (rt *Runtime) SetStdin(r io.Reader) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	rt.mu.Lock()
+	rt.settings.in = br
+	rt.mu.Unlock()
+}
+
+// Step counts a step of the run. It fails when the steps are used up or
+// the context is done, which is checked at the first step and every 256
+// steps after it.
+func (rt *Runtime) Step() *Error {
+	rt.steps++
+	if max := rt.run.limits.MaxSteps; max > 0 && rt.steps > max {
+		return limitExceeded(ErrLimit, "step limit exceeded: more than %d steps", max)
+	}
+	if rt.done == nil || rt.steps&0xff != 1 {
+		return nil
+	}
+	select {
+	case <-rt.done:
+		return limitExceeded(rt.ctx.Err(), "evaluation stopped: %s", rt.ctx.Err())
+	default:
+		return nil
+	}
+}
+
+// Alloc counts obj as a newly allocated value. It fails when the objects
+// or bytes are used up. Errors and the singletons such as NIHIL are free.
+func (rt *Runtime) Alloc(obj Object) *Error {
+	switch obj {
+	case nil, _NULL, _TRUE, _FALSE, _BREAK, _CONTINUE:
+		return nil
+	}
+	if _, ok := obj.(*Error); ok {
+		return nil
+	}
+	rt.objects++
+	rt.bytes += sizeOf(obj)
+	if max := rt.run.limits.MaxObjects; max > 0 && rt.objects > max {
+		return limitExceeded(ErrLimit, "object limit exceeded: more than %d objects", max)
+	}
+	if max := rt.run.limits.MaxBytes; max > 0 && rt.bytes > max {
+		return limitExceeded(ErrLimit, "memory limit exceeded: more than %d bytes", max)
+	}
+	return nil
+}
+
+// Print writes s to the output of the script. It fails when that would
+// exceed MaxOutput, writing nothing.
+func (rt *Runtime) Print(s string) *Error {
+	if max := rt.run.limits.MaxOutput; max > 0 && rt.written+int64(len(s)) > max {
+		rt.written = max
+		return limitExceeded(ErrLimit, "output limit exceeded: more than %d bytes", max)
+	}
+	rt.written += int64(len(s))
+	if _, err := io.WriteString(rt.run.out, s); err != nil {
+		return NewError("%s", err)
+	}
+	return nil
+}
+
+// ReadLine reads the next line of the input without its line ending, ok
+// is false once the input is exhausted.
+func (rt *Runtime) ReadLine() (line string, ok bool, err *Error) {
+	line, readErr := rt.run.in.ReadString('\n')
+	if readErr != nil && readErr != io.EOF {
+		return "", false, NewError("%s", readErr)
+	}
+	if readErr == io.EOF && line == "" {
+		return "", false, nil
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	return line, true, nil
+}
+
+// sizeOf estimates the memory obj takes, without the values it refers to,
+// which were counted when they were allocated.
+func sizeOf(obj Object) int64 {
+	switch obj := obj.(type) {
+	case *String:
+		return 16 + int64(len(obj.Value))
+	case *Array:
+		return 24 + 16*int64(len(obj.Elements))
+	case *Hash:
+		return 48 + 64*int64(len(obj.Pairs))
+	}
+	return 16
+}
+
+func limitExceeded(cause error, format string, a ...interface{}) *Error {
+	err := NewErrorKind(LimitError, format, a...)
+	err.Cause = cause
+	return err
+}
+
+// Recoverable reports whether coba can catch the error: every error but
+// an exceeded limit, which would only fail again inside tangkap. A stack
+// overflow can be caught.
+func (e *Error) Recoverable() bool {
+	return e.Cause == nil || e.Cause == ErrStackOverflow
+}
@@ -0,0 +1,112 @@
+package evaluator_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		limits   Limits
+		input    string
+		expected string
+	}{
+		{Limits{MaxSteps: 1000}, `var f = fn() { f() }; f()`, "step limit exceeded: more than 1000 steps"},
+		{Limits{MaxObjects: 100}, `var f = fn(n) { jika (n == 0) { pilih 0 }; f(n - 1) }; f(1000)`, "object limit exceeded: more than 100 objects"},
+		{Limits{MaxBytes: 1 << 16}, `var f = fn(s) { f(s + s) }; f("ab")`, "memory limit exceeded: more than 65536 bytes"},
+		{Limits{MaxBytes: 1 << 16}, `var f = fn(a) { f(push(a, 1)) }; f([])`, "memory limit exceeded: more than 65536 bytes"},
+		{Limits{MaxOutput: 5}, `println("halo dunia")`, "output limit exceeded: more than 5 bytes"},
+		{Limits{MaxSteps: 1000}, `coba { var f = fn() { f() }; f() } tangkap (e) { 1 }`, "step limit exceeded: more than 1000 steps"},
+	}
+	for _, tt := range tests {
+		s := newScript()
+		s.SetLimits(tt.limits)
+		errObj, ok := runScript(t, s, tt.input).(*Error)
+		if !ok {
+			t.Errorf("%q: no error returned", tt.input)
+			continue
+		}
+		if errObj.Kind != LimitError || errObj.Message != tt.expected {
+			t.Errorf("%q: wrong error. expected=%s: %q, got=%s: %q", tt.input, LimitError, tt.expected, errObj.Kind, errObj.Message)
+		}
+		if !errors.Is(errObj.Cause, ErrLimit) {
+			t.Errorf("%q: wrong cause. got=%v", tt.input, errObj.Cause)
+		}
+		if errObj.Line == 0 {
+			t.Errorf("%q: error has no position", tt.input)
+		}
+	}
+}
+
+func TestLimitsApplyToEachRun(t *testing.T) {
+	s := newScript()
+	s.SetLimits(Limits{MaxSteps: 200})
+	for i := 0; i < 3; i++ {
+		if got := runScript(t, s, `var a = [1, 2, 3]; a[1] + 1`); got.Inspect() != "3" {
+			t.Fatalf("run %d: expected=3, got=%s", i, got.Inspect())
+		}
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	program, err := parser.New(lexer.New(`var f = fn(n) { f(n + 1) }; f(0)`)).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	errObj, ok := newScript().RunContext(ctx, program).(*Error)
+	if !ok || errObj.Kind != LimitError || !errors.Is(errObj.Cause, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to stop the script. got=%+v", errObj)
+	}
+	if errObj.Message != "evaluation stopped: context deadline exceeded" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	errObj, ok = newScript().RunContext(ctx, program).(*Error)
+	if !ok || !errors.Is(errObj.Cause, context.Canceled) {
+		t.Fatalf("expected a cancelled context to stop the script. got=%+v", errObj)
+	}
+}
+
+func TestStdio(t *testing.T) {
+	var out bytes.Buffer
+	s := newScript()
+	s.SetStdout(&out)
+	s.SetStdin(strings.NewReader("satu\ndua\n"))
+	input := `
+var baca = fn(baris) {
+	var b = baca_baris()
+	jika (!b) { pilih baris }
+	baca(push(baris, b))
+}
+var baris = baca([])
+stdout(panjang(baris), ":")
+println(baris, "selesai")
+`
+	if got, ok := runScript(t, s, input).(*Error); ok {
+		t.Fatal(got.Inspect())
+	}
+	if expected := "2:[satu, dua]\nselesai\n"; out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+
+	// the output limit keeps what fits
+	out.Reset()
+	s.SetLimits(Limits{MaxOutput: 8})
+	runScript(t, s, `stdout("halo"); stdout(" dunia")`)
+	if out.String() != "halo" {
+		t.Errorf("wrong limited output. got=%q", out.String())
+	}
+}
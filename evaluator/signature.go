@@ -0,0 +1,55 @@
+package evaluator
+
+import "strings"
+
+// signatures describe how the standard builtins and the assertions are
+// called, for editors. angka is an INTEGER or a FLOAT.
+var signatures = map[string]string{
+	"panjang":    "panjang(x string|array) integer",
+	"awal":       "awal(a array) elemen pertama, nihil jika kosong",
+	"akhir":      "akhir(a array) elemen terakhir, nihil jika kosong",
+	"ekor":       "ekor(a array) array tanpa elemen pertama, nihil jika kosong",
+	"push":       "push(a array, x) array baru dengan x di akhir",
+	"bulat":      "bulat(x integer|float|string) integer",
+	"pecahan":    "pecahan(x integer|float|string) float",
+	"stdout":     "stdout(x...) tulis x tanpa baris baru",
+	"println":    "println(x...) tulis x, masing-masing diikuti baris baru",
+	"baca_baris": "baca_baris() string, nihil di akhir input",
+
+	"pastikan":      "pastikan(kondisi, pesan...) gagal jika kondisi tidak benar",
+	"pastikan_sama": "pastikan_sama(harapan, hasil, pesan...) gagal jika hasil tidak sama dengan harapan",
+
+	"math.Copysign":  "math.Copysign(x, y angka) float",
+	"math.Dim":       "math.Dim(x, y angka) float",
+	"math.FMA":       "math.FMA(x, y, z angka) float",
+	"math.Hypot":     "math.Hypot(p, q angka) float",
+	"math.Max":       "math.Max(x, y angka) float",
+	"math.Min":       "math.Min(x, y angka) float",
+	"math.Mod":       "math.Mod(x, y angka) float",
+	"math.Pow":       "math.Pow(x, y angka) float",
+	"math.Remainder": "math.Remainder(x, y angka) float",
+	"math.Atan2":     "math.Atan2(y, x angka) float",
+
+	"regex.baru":       "regex.baru(pola string) regex",
+	"regex.cari":       "regex.cari(s string, re regex) string, nihil jika tidak ketemu",
+	"regex.cari_semua": "regex.cari_semua(s string, re regex) []string",
+	"regex.grup":       "regex.grup(s string, re regex) [cocokan, grup1, grup2, ...], nihil jika tidak ketemu",
+	"regex.grup_semua": "regex.grup_semua(s string, re regex) [][cocokan, grup1, grup2, ...]",
+	"regex.grup_nama":  "regex.grup_nama(s string, re regex) {nama: grup}, nihil jika tidak ketemu",
+	"regex.ganti":      "regex.ganti(s string, re regex, pengganti string) string, $1 dan ${nama} diganti grupnya",
+	"regex.pisah":      "regex.pisah(s string, re regex) []string",
+}
+
+// Signature returns how the standard builtin or assertion called name is
+// called, e.g. "math.Max(x, y angka) float". Builtins registered by a host
+// have none.
+func Signature(name string) (string, bool) {
+	if sig, ok := signatures[name]; ok {
+		return sig, true
+	}
+	// the rest of math.* takes one number, like its Go counterpart
+	if _, ok := mathBuiltin[name]; ok && strings.HasPrefix(name, "math.") {
+		return name + "(x angka) float", true
+	}
+	return "", false
+}
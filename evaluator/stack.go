@@ -0,0 +1,138 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+// DefaultMaxDepth is how many function calls may be active at once before
+// a call fails with a stack overflow error, see Script.SetMaxDepth.
+const DefaultMaxDepth = 10000
+
+const (
+	maxArgs       = 5  // arguments shown per frame in a traceback
+	maxArgLength  = 20 // characters shown per argument
+	tracebackEdge = 25 // frames shown at each end of a long traceback
+)
+
+// StackFrame is one active call at the moment an error was raised.
+type StackFrame struct {
+	Function string    // name, fn@line:col when anonymous, main for the top level
+	Args     string    // summary of the arguments, see FormatArgs
+	File     string    // file the function was defined in
+	Pos      token.Pos // where the frame was executing
+}
+
+func (f StackFrame) String() string {
+	if f.Function == mainFrame {
+		return mainFrame
+	}
+	return f.Function + "(" + f.Args + ")"
+}
+
+const mainFrame = "main"
+
+// FunctionName is the name tracebacks use for a function literal: the
+// variable it was bound to, or fn@line:col for an anonymous function.
+func FunctionName(fn *ast.FunctionLiteral) string {
+	if fn.Name != "" {
+		return fn.Name
+	}
+	pos := fn.Pos()
+	return fmt.Sprintf("fn@%d:%d", pos.Line, pos.Col)
+}
+
+// FormatArgs summarises args for a traceback. Long values are cut short
+// and only the first few arguments are shown, like Go does.
+func FormatArgs(args []Object) string {
+	parts := make([]string, 0, len(args))
+	for i, arg := range args {
+		if i == maxArgs {
+			parts = append(parts, "...")
+			break
+		}
+		var s string
+		switch arg := arg.(type) {
+		case nil:
+			s = "nihil"
+		case *String:
+			s = fmt.Sprintf("%q", arg.Value)
+		default:
+			s = arg.Inspect()
+		}
+		if r := []rune(s); len(r) > maxArgLength {
+			s = string(r[:maxArgLength]) + "..."
+		}
+		parts = append(parts, strings.Join(strings.Fields(s), " "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Traceback renders the call stack of the error, most recent call first,
+// like the goroutine dumps of Go:
+//
+//	bagi(1, 0)
+//		skrip.bi:2:11
+//	main
+//		skrip.bi:4:5
+//
+// The middle of a very deep stack is elided.
+func (e *Error) Traceback() string {
+	var out strings.Builder
+	for i, f := range e.Stack {
+		if len(e.Stack) > 2*tracebackEdge && i >= tracebackEdge && i < len(e.Stack)-tracebackEdge {
+			if i == tracebackEdge {
+				fmt.Fprintf(&out, "...%d frame dilewati...\n", len(e.Stack)-2*tracebackEdge)
+			}
+			continue
+		}
+		out.WriteString(f.String())
+		out.WriteString("\n\t")
+		if f.File != "" {
+			out.WriteString(f.File)
+			out.WriteString(":")
+		}
+		fmt.Fprintf(&out, "%d:%d\n", f.Pos.Line, f.Pos.Col)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// call is an active call of a Function.
+type call struct {
+	fn   *Function
+	args []Object
+	pos  token.Pos // of the call expression, in the caller
+}
+
+// SetMaxDepth limits how deep function calls may nest, n <= 0 restores
+// DefaultMaxDepth.
+func (s *Script) SetMaxDepth(n int) {
+	s.rt.SetMaxDepth(n)
+}
+
+// StackOverflow is the error of a call nested deeper than max.
+func StackOverflow(max int) *Error {
+	return limitExceeded(ErrStackOverflow, "stack overflow: kedalaman pemanggilan melebihi %d", max)
+}
+
+// stack snapshots the active calls for an error raised at pos.
+func (s *Script) stack(pos token.Pos) []StackFrame {
+	frames := make([]StackFrame, 0, len(s.calls)+1)
+	for i := len(s.calls) - 1; i >= 0; i-- {
+		c := s.calls[i]
+		frames = append(frames, StackFrame{Function: c.fn.Name, Args: FormatArgs(c.args), File: c.fn.File, Pos: pos})
+		pos = c.pos
+	}
+	return append(frames, StackFrame{Function: mainFrame, File: s.file, Pos: pos})
+}
+
+// currentFile is the file the innermost active call was defined in.
+func (s *Script) currentFile() string {
+	if n := len(s.calls); n > 0 {
+		return s.calls[n-1].fn.File
+	}
+	return s.file
+}
@@ -0,0 +1,650 @@
+// Package format prints Bilang programs in their canonical layout: one
+// statement per line, blocks indented by four spaces, a space around
+// binary operators and only the parentheses the precedence requires.
+// Comments are kept where they were, and so are single blank lines.
+package format
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+const indent = "    "
+
+// Source formats src. Formatting is idempotent, formatting the result
+// again returns it unchanged. When src has syntax errors the error of the
+// parser is returned, see parser.Parser.Error.
+func Source(src []byte) ([]byte, error) {
+	return File("", src)
+}
+
+// File formats src like Source, its syntax errors are reported in the file
+// name.
+func File(name string, src []byte) ([]byte, error) {
+	p := parser.New(lexer.New(string(src)))
+	p.SetFile(name)
+	prog, err := p.ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+
+	pr := newPrinter(string(src), prog.Comments)
+	pr.lines(pr.statements(prog.Statements), token.Pos{Line: len(pr.offsets) + 1}, true)
+	if len(pr.buf) > 0 {
+		pr.buf = append(pr.buf, '\n')
+	}
+	return pr.buf, nil
+}
+
+// printer writes the tree of src to buf. It keeps the comments of src,
+// which the tree doesn't hold, in order: each is printed once everything
+// before it is.
+type printer struct {
+	src      string
+	offsets  []int                     // of the lines of src
+	tokens   []token.Token             // of src, without semicolons
+	closing  map[token.Pos]token.Token // the bracket closing each opening one
+	comments []token.Token
+	next     int // first comment not printed yet
+
+	buf    []byte
+	depth  int  // of indentation
+	bol    bool // at the beginning of a line, the indentation is not written yet
+	last   int  // source line of what was printed last, see startLine
+	single bool // no blank line may follow, at the start of a block
+}
+
+func newPrinter(src string, comments []token.Token) *printer {
+	p := &printer{src: src, offsets: []int{0}, closing: make(map[token.Pos]token.Token), comments: comments, single: true}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			p.offsets = append(p.offsets, i+1)
+		}
+	}
+
+	// the program parsed, so the brackets are balanced
+	var open []token.Token
+	l := lexer.New(src)
+	for {
+		tok, _ := l.NextToken()
+		switch tok.Type {
+		case token.EOF:
+			return p
+		case token.SEMICOLON:
+			continue
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			open = append(open, tok)
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			if len(open) > 0 {
+				p.closing[open[len(open)-1].Pos()] = tok
+				open = open[:len(open)-1]
+			}
+		}
+		p.tokens = append(p.tokens, tok)
+	}
+}
+
+// text returns the source of tok, literals are printed as they are
+// written.
+func (p *printer) text(tok token.Token) string {
+	return p.src[p.offset(tok.Pos()):p.offset(tok.End())]
+}
+
+// offset converts pos, whose column counts runes, to a byte offset.
+func (p *printer) offset(pos token.Pos) int {
+	if pos.Line > len(p.offsets) {
+		return len(p.src)
+	}
+	i := p.offsets[pos.Line-1]
+	for col := 1; col < pos.Col && i < len(p.src); col++ {
+		_, size := utf8.DecodeRuneInString(p.src[i:])
+		i += size
+	}
+	return i
+}
+
+// endLine returns the line of the last token before pos.
+func (p *printer) endLine(pos token.Pos) int {
+	i := sort.Search(len(p.tokens), func(i int) bool { return !p.tokens[i].Pos().Before(pos) })
+	if i == 0 {
+		return 0
+	}
+	return p.tokens[i-1].EndLine
+}
+
+func (p *printer) write(s string) {
+	if s == "" {
+		return
+	}
+	if p.bol {
+		p.buf = append(p.buf, strings.Repeat(indent, p.depth)...)
+		p.bol = false
+	}
+	p.buf = append(p.buf, s...)
+}
+
+// startLine starts a new line for what is at line in the source. One
+// blank line is kept when the source has any since the line printed last.
+func (p *printer) startLine(line int) {
+	if len(p.buf) > 0 {
+		if !p.single && line > p.last+1 {
+			p.buf = append(p.buf, '\n')
+		}
+		p.buf = append(p.buf, '\n')
+	}
+	p.bol = true
+	p.single = false
+}
+
+// item is a line of a block, or a case of pilah, or an element of a hash
+// or array literal spread over several lines.
+type item struct {
+	pos   token.Pos
+	print func()
+}
+
+func (p *printer) statements(stmts []ast.Statement) []item {
+	items := make([]item, len(stmts))
+	for i, stmt := range stmts {
+		stmt := stmt
+		items[i] = item{pos: stmt.Pos(), print: func() { p.stmt(stmt) }}
+	}
+	return items
+}
+
+// lines prints items one per line, with the comments among them, up to
+// close. Statements starting with a token that would continue the
+// statement before them get that one terminated with a semicolon.
+func (p *printer) lines(items []item, close token.Pos, stmts bool) {
+	semicolon := -1 // where the statement printed last ends in buf
+	for i, it := range items {
+		bound := close
+		if i+1 < len(items) {
+			bound = items[i+1].pos
+		}
+		p.leading(it.pos)
+		p.startLine(it.pos.Line)
+		start := len(p.buf)
+		it.print()
+		if stmts && semicolon >= 0 && continues(p.buf[start:]) {
+			p.buf = append(p.buf[:semicolon+1], p.buf[semicolon:]...)
+			p.buf[semicolon] = ';'
+		}
+		semicolon = len(p.buf)
+		p.last = p.endLine(bound)
+		p.trailing(p.last, bound)
+	}
+	p.leading(close)
+}
+
+// continues reports whether a statement printed as b would be parsed as
+// part of the statement before it, e.g. `-1` as `x - 1`.
+func continues(b []byte) bool {
+	s := strings.TrimLeft(string(b), " ")
+	return s != "" && strings.ContainsRune("([-/", rune(s[0]))
+}
+
+// leading prints the comments before pos, each on a line of its own.
+func (p *printer) leading(pos token.Pos) {
+	for p.next < len(p.comments) && p.comments[p.next].Pos().Before(pos) {
+		c := p.comments[p.next]
+		p.next++
+		p.startLine(c.Line)
+		p.write(comment(c))
+		p.last = c.Line + strings.Count(c.Literal, "\n")
+	}
+}
+
+// trailing prints the comments before pos that start on line at the latest
+// at the end of the current line, so they stay next to the code they
+// follow.
+func (p *printer) trailing(line int, pos token.Pos) {
+	for p.next < len(p.comments) && p.comments[p.next].Line <= line && p.comments[p.next].Pos().Before(pos) {
+		c := p.comments[p.next]
+		p.next++
+		p.write(" " + comment(c))
+		if end := c.Line + strings.Count(c.Literal, "\n"); end > p.last {
+			p.last = end
+		}
+	}
+}
+
+// pending reports whether a comment before pos is still to be printed.
+func (p *printer) pending(pos token.Pos) bool {
+	return p.next < len(p.comments) && p.comments[p.next].Pos().Before(pos)
+}
+
+// fits reports whether the comments between the bracket open and its
+// closing one, except those in brackets nested in them, can be printed
+// inline, see fitsLine.
+func (p *printer) fits(open token.Token) bool {
+	close := p.closing[open.Pos()]
+	i := sort.Search(len(p.tokens), func(i int) bool { return open.Pos().Before(p.tokens[i].Pos()) })
+	depth := 0
+	for _, c := range p.comments[p.next:] {
+		if !c.Pos().Before(close.Pos()) {
+			break
+		}
+		for ; i < len(p.tokens) && p.tokens[i].Pos().Before(c.Pos()); i++ {
+			switch p.tokens[i].Type {
+			case token.LPAREN, token.LBRACKET, token.LBRACE:
+				depth++
+			case token.RPAREN, token.RBRACKET, token.RBRACE:
+				depth--
+			}
+		}
+		if depth == 0 && !fitsLine(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// fitsLine reports whether the comment c can be followed by code on its
+// line: it isn't a // comment and doesn't span lines.
+func fitsLine(c token.Token) bool {
+	return !strings.HasPrefix(c.Literal, "//") && !strings.Contains(c.Literal, "\n")
+}
+
+// comma returns the position of the last comma before pos, the one
+// separating the item at pos from the one before it.
+func (p *printer) comma(pos token.Pos) token.Pos {
+	i := sort.Search(len(p.tokens), func(i int) bool { return !p.tokens[i].Pos().Before(pos) })
+	for i--; i >= 0; i-- {
+		if p.tokens[i].Type == token.COMMA {
+			return p.tokens[i].Pos()
+		}
+	}
+	return pos
+}
+
+// within prints the comments before pos where the line is at, separated
+// by spaces, with one before the first when lead is set and after the
+// last when tail is. It stops at a comment that can't be inline, which is
+// left to trailing.
+func (p *printer) within(pos token.Pos, lead, tail bool) {
+	for first := true; p.pending(pos) && fitsLine(p.comments[p.next]); first = false {
+		c := p.comments[p.next]
+		p.next++
+		if lead || !first {
+			p.write(" ")
+		}
+		p.write(comment(c))
+		if tail && !(p.pending(pos) && fitsLine(p.comments[p.next])) {
+			p.write(" ")
+		}
+	}
+}
+
+func comment(c token.Token) string {
+	if strings.HasPrefix(c.Literal, "//") {
+		return strings.TrimRight(c.Literal, " \t\r")
+	}
+	return c.Literal
+}
+
+// layout tells bracketed how to print its items.
+type layout struct {
+	inline bool   // on the line of the brackets, unless a comment is in between that doesn't fit, see fits
+	sep    string // between items, or "" for at most one item set off by spaces
+	last   bool   // the last item on a line of its own is followed by sep too
+	stmts  bool   // the items are statements, see lines
+}
+
+// bracketed prints items between the bracket open and its closing one,
+// either inline or one per line and indented. Empty brackets are always
+// printed inline. Inline, the comments among items separated by sep stay
+// where they are; a block with a comment is printed over several lines.
+func (p *printer) bracketed(open token.Token, items []item, l layout) {
+	close := p.closing[open.Pos()]
+	inline := l.inline || len(items) == 0
+	if l.sep == "" {
+		inline = inline && !p.pending(close.Pos())
+	} else {
+		inline = inline && p.fits(open)
+	}
+	if inline {
+		saved := *p
+		p.write(open.Literal)
+		pad := l.sep == "" && len(items) > 0
+		if pad {
+			p.write(" ")
+		}
+		for i, it := range items {
+			if i > 0 {
+				p.within(p.comma(it.pos), true, false)
+				p.write(l.sep + " ")
+			}
+			p.within(it.pos, false, true)
+			it.print()
+		}
+		p.within(close.Pos(), len(items) > 0, false)
+		if pad {
+			p.write(" ")
+		}
+		p.write(close.Literal)
+		// a statement spread over lines doesn't fit between `{ }`
+		if !pad || !bytes.ContainsRune(p.buf[len(saved.buf):], '\n') {
+			return
+		}
+		saved.buf = p.buf[:len(saved.buf)]
+		*p = saved
+	}
+
+	p.write(open.Literal)
+	first := close.Pos()
+	if len(items) > 0 {
+		first = items[0].pos
+	}
+	if l.sep != "" {
+		for i := range items {
+			if i < len(items)-1 || l.last {
+				print := items[i].print
+				items[i].print = func() {
+					print()
+					p.write(l.sep)
+				}
+			}
+		}
+	}
+	p.depth++
+	p.trailing(open.Line, first)
+	p.last, p.single = open.Line, true
+	p.lines(items, close.Pos(), l.stmts)
+	p.depth--
+	p.single = true
+	p.startLine(close.Line)
+	p.write(close.Literal)
+	p.last = close.Line
+}
+
+// block prints a block, on one line when it is on one line in the source
+// and holds at most one statement.
+func (p *printer) block(b *ast.BlockStatement) {
+	close := p.closing[b.Token.Pos()]
+	inline := b.Token.Line == close.Line && len(b.Statements) <= 1
+	p.bracketed(b.Token, p.statements(b.Statements), layout{inline: inline, stmts: true})
+}
+
+func (p *printer) stmt(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.VarStatement:
+		// an assignment is a VarStatement without the var keyword
+		if s.Token.Literal == "var" {
+			p.write("var ")
+		}
+		p.write(s.Name.Value + " = ")
+		p.expr(s.Value)
+	case *ast.KonstStatement:
+		p.write("konst " + s.Name.Value + " = ")
+		p.expr(s.Value)
+	case *ast.PilihStatement:
+		p.write("pilih ")
+		p.expr(s.ReturnValue)
+	case *ast.LemparStatement:
+		p.write("lempar ")
+		p.expr(s.Value)
+	case *ast.ImporStatement:
+		p.write("impor ")
+		if s.Alias != nil {
+			p.write(s.Alias.Value + " ")
+		}
+		p.write(p.text(s.Path.Token))
+	case *ast.EksporStatement:
+		p.write("ekspor ")
+		p.stmt(s.Statement)
+	case *ast.ExpressionStatement:
+		p.expr(s.Expression)
+	case *ast.BlockStatement:
+		p.block(s)
+	}
+}
+
+func (p *printer) expr(expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+	case *ast.IntegerLiteral:
+		p.write(p.text(e.Token))
+	case *ast.FloatLiteral:
+		p.write(p.text(e.Token))
+	case *ast.StringLiteral:
+		p.write(p.text(e.Token))
+	case *ast.InterpolatedString:
+		p.write(p.text(e.Token))
+	case *ast.RegExLiteral:
+		p.write(p.text(e.Token))
+	case *ast.Boolean, *ast.NihilLiteral, *ast.Wildcard, *ast.BreakExpression, *ast.ContinueExpression:
+		p.write(e.TokenLiteral())
+	case *ast.VarStatement:
+		p.stmt(e)
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		// parenthesized so `!~re~` doesn't turn into the !~ operator
+		_, re := e.Right.(*ast.RegExLiteral)
+		p.operand(e.Right, re || precedence(e.Right) < parser.PREFIX)
+	case *ast.InfixExpression:
+		p.infix(e.Left, e.Operator, e.Token.Type, e.Right)
+	case *ast.Pipe:
+		p.infix(e.Left, e.Token.Literal, e.Token.Type, e.Right)
+	case *ast.CallExpression:
+		p.operand(e.Function, precedence(e.Function) <= parser.PREFIX)
+		p.arguments(e)
+	case *ast.IndexExpression:
+		p.operand(e.Left, precedence(e.Left) <= parser.PREFIX)
+		p.write("[")
+		p.expr(e.Index)
+		p.write("]")
+	case *ast.MethodCallExpression:
+		p.operand(e.Object, precedence(e.Object) <= parser.PREFIX)
+		p.write(".")
+		p.expr(e.Call)
+	case *ast.ArrayLiteral:
+		p.array(e)
+	case *ast.HashLiteral:
+		p.hash(e)
+	case *ast.FunctionLiteral:
+		if e.Token.Type == token.FATARROW {
+			p.write(e.Parameters[0].Value + " => ")
+			p.stmt(e.Body.Statements[0])
+			return
+		}
+		p.write("fn")
+		p.params(e.Parameters)
+		p.write(" ")
+		p.block(e.Body)
+	case *ast.JikaExpression:
+		p.write("jika (")
+		p.expr(e.Condition)
+		p.write(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.write(" atau ")
+			p.block(e.Alternative)
+		}
+	case *ast.CobaExpression:
+		p.write("coba ")
+		p.block(e.Body)
+		p.write(" tangkap ")
+		if e.Param != nil {
+			p.write("(" + e.Param.Value + ") ")
+		}
+		p.block(e.Handler)
+	case *ast.LoopLiteral:
+		p.loop(e)
+	case *ast.PilahExpression:
+		p.pilah(e)
+	}
+}
+
+// infix prints an operation of a binary operator, parenthesizing the
+// operands that bind less tightly. Operators are left associative, so the
+// right operand is parenthesized on a tie as well.
+func (p *printer) infix(left ast.Expression, op string, typ token.Type, right ast.Expression) {
+	prec := parser.Precedence(typ)
+	parens := precedence(left) < prec || endsWithArrow(left)
+	// `/` after a string, a brace or a keyword starts a regex literal
+	if typ == token.SLASH && !parens && !endsWithOperand(left) {
+		parens = true
+	}
+	p.operand(left, parens)
+	p.write(" " + op + " ")
+	p.operand(right, precedence(right) <= prec)
+}
+
+func (p *printer) operand(e ast.Expression, parens bool) {
+	if parens {
+		p.write("(")
+		p.expr(e)
+		p.write(")")
+		return
+	}
+	p.expr(e)
+}
+
+// arguments prints the arguments of a call on one line, or an argument
+// per line when a comment among them doesn't fit in a line.
+func (p *printer) arguments(c *ast.CallExpression) {
+	items := make([]item, len(c.Arguments))
+	for i, e := range c.Arguments {
+		e := e
+		items[i] = item{pos: e.Pos(), print: func() { p.expr(e) }}
+	}
+	p.bracketed(c.Token, items, layout{inline: true, sep: ","})
+}
+
+func (p *printer) params(params []*ast.Identifier) {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.Value
+	}
+	p.write("(" + strings.Join(names, ", ") + ")")
+}
+
+// array prints an array literal on one line, or an element per line when
+// it is spread over several lines in the source.
+func (p *printer) array(a *ast.ArrayLiteral) {
+	items := make([]item, len(a.Elements))
+	for i, e := range a.Elements {
+		e := e
+		items[i] = item{pos: e.Pos(), print: func() { p.expr(e) }}
+	}
+	p.bracketed(a.Token, items, layout{inline: p.inline(a.Token), sep: ","})
+}
+
+// hash prints a hash literal like an array literal, pairs spread over
+// several lines end with a comma.
+func (p *printer) hash(h *ast.HashLiteral) {
+	keys := h.Keys()
+	items := make([]item, len(keys))
+	for i, k := range keys {
+		k, v := k, h.Pairs[k]
+		items[i] = item{pos: k.Pos(), print: func() {
+			p.expr(k)
+			p.write(": ")
+			p.expr(v)
+		}}
+	}
+	p.bracketed(h.Token, items, layout{inline: p.inline(h.Token), sep: ",", last: true})
+}
+
+// inline reports whether the brackets opened by open are on one line.
+func (p *printer) inline(open token.Token) bool {
+	return p.closing[open.Pos()].Line == open.Line
+}
+
+func (p *printer) loop(l *ast.LoopLiteral) {
+	p.write("tiap ")
+	for i, kv := range l.KV {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(kv.Value)
+	}
+	if len(l.KV) > 0 {
+		p.write(" ")
+	}
+	p.write("di " + l.Iter.Value + " ")
+	p.block(l.Body)
+}
+
+// pilah prints a case per line, unless the source has at most one case and
+// on one line.
+func (p *printer) pilah(pl *ast.PilahExpression) {
+	p.write("pilah ")
+	if pl.Target != nil {
+		p.expr(pl.Target.Expression)
+		p.write(" ")
+	}
+	items := make([]item, len(pl.Conditions))
+	for i, cond := range pl.Conditions {
+		cond, value := cond.Expression, pl.Values[i]
+		items[i] = item{pos: cond.Pos(), print: func() {
+			p.expr(cond)
+			p.write(" -> ")
+			p.expr(value)
+		}}
+	}
+	p.bracketed(pl.Lbrace, items, layout{inline: p.inline(pl.Lbrace) && len(items) <= 1})
+}
+
+// precedence returns how tightly e holds together as an operand, see
+// parser.Precedence. Literals, calls and the like never need parentheses.
+func precedence(e ast.Expression) uint8 {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.Pipe:
+		return parser.PIPE
+	case *ast.VarStatement:
+		return parser.ASSIGN
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.FunctionLiteral:
+		if e.Token.Type == token.FATARROW {
+			return parser.FATARROW
+		}
+	}
+	return parser.INDEX + 1
+}
+
+// endsWithArrow reports whether e ends with an unparenthesized `x => ...`,
+// whose body would take in whatever follows e.
+func endsWithArrow(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.FunctionLiteral:
+		return e.Token.Type == token.FATARROW
+	case *ast.InfixExpression:
+		return precedence(e.Right) > parser.Precedence(e.Token.Type) && endsWithArrow(e.Right)
+	case *ast.Pipe:
+		return precedence(e.Right) > parser.PIPE && endsWithArrow(e.Right)
+	}
+	return false
+}
+
+// endsWithOperand reports whether e as printed ends with a token after
+// which the lexer reads `/` as division: an identifier, a number, `)` or
+// `]`.
+func endsWithOperand(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.FloatLiteral, *ast.CallExpression, *ast.IndexExpression, *ast.ArrayLiteral:
+		return true
+	case *ast.MethodCallExpression:
+		return endsWithOperand(e.Call)
+	case *ast.PrefixExpression:
+		_, re := e.Right.(*ast.RegExLiteral)
+		return re || precedence(e.Right) < parser.PREFIX || endsWithOperand(e.Right)
+	case *ast.InfixExpression:
+		return precedence(e.Right) <= parser.Precedence(e.Token.Type) || endsWithOperand(e.Right)
+	case *ast.Pipe:
+		return precedence(e.Right) <= parser.PIPE || endsWithOperand(e.Right)
+	}
+	return false
+}
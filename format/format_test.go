@@ -0,0 +1,90 @@
+package format
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files")
+
+// TestGolden formats every testdata/*.input and compares the result with
+// the .golden file next to it. The result must format to itself and parse
+// to the same tree as the input.
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		src, err := ioutil.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Source(src)
+		if err != nil {
+			t.Errorf("%s: %s", input, err)
+			continue
+		}
+
+		golden := strings.TrimSuffix(input, ".input") + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: wrong output.\ngot:\n%s\nwant:\n%s", input, got, want)
+		}
+
+		again, err := Source(got)
+		if err != nil {
+			t.Errorf("%s: formatted source doesn't parse: %s", input, err)
+			continue
+		}
+		if !bytes.Equal(again, got) {
+			t.Errorf("%s: not idempotent.\nfirst:\n%s\nsecond:\n%s", input, got, again)
+		}
+		if a, b := tree(t, src), tree(t, got); a != b {
+			t.Errorf("%s: formatting changed the program.\nbefore:\n%s\nafter:\n%s", input, a, b)
+		}
+	}
+}
+
+var position = regexp.MustCompile(` \d+:\d+$`)
+
+// tree returns the AST of src without positions.
+func tree(t *testing.T, src []byte) string {
+	prog, err := parser.New(lexer.New(string(src))).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := ast.Fprint(&out, prog); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = position.ReplaceAllString(line, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := File("a.bi", []byte("var = 1"))
+	if err == nil || !strings.HasPrefix(err.Error(), "a.bi:1:5: expected identifier") {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
@@ -1,3 +1,13 @@
 module github.com/dedisuryadi/bilang
 
-go 1.16
+go 1.18
+
+require (
+	github.com/peterh/liner v1.2.2
+	golang.org/x/term v0.0.0-20210503060354-a79de5458b56
+)
+
+require (
+	github.com/mattn/go-runewidth v0.0.3 // indirect
+	golang.org/x/sys v0.0.0-20211117180635-dee7805ff2e1 // indirect
+)
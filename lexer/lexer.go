@@ -3,30 +3,73 @@ package lexer
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/dedisuryadi/bilang/token"
 )
 
 type Lexer struct {
-	ch           byte
+	ch           rune
 	position     int
 	readPosition int
 	col          int
 	line         int
 	input        string
 	prev         token.Token
+	comments     []token.Token
 }
 
+// IncompleteError is the error of a raw string or block comment that runs
+// to the end of the input, which more input could complete, e.g. the next
+// lines typed at the REPL.
+type IncompleteError struct {
+	msg string
+}
+
+func (e *IncompleteError) Error() string { return e.msg }
+
 func New(input string) *Lexer {
-	l := &Lexer{input: input, col: 1, line: 1}
+	return NewAt(input, 1, 1)
+}
+
+// NewAt returns a lexer for input that was found at line:col of a larger
+// source, such as the expressions embedded in an interpolated string, so
+// its tokens carry positions in that source.
+func NewAt(input string, line, col int) *Lexer {
+	l := &Lexer{input: input, col: col, line: line}
 	l.readChar()
 	return l
 }
 
+// NextToken returns the next token, with its start and end positions set.
 func (l *Lexer) NextToken() (token.Token, error) {
-	var tok token.Token
+	for {
+		l.skipWhitespace()
+		if l.ch != '/' || (l.peekChar() != '/' && l.peekChar() != '*') {
+			break
+		}
+		if err := l.skipComment(); err != nil {
+			pos := token.Token{Type: token.ILLEGAL, Line: l.line, Col: l.col, EndLine: l.line, EndCol: l.col}
+			return pos, err
+		}
+	}
 
-	l.skipWhitespace()
+	line, col := l.line, l.col
+	tok, err := l.scan()
+	tok.Line, tok.Col = line, col
+	tok.EndLine, tok.EndCol = l.line, l.col
+	if err == nil {
+		l.prev = tok
+	}
+	return tok, err
+}
+
+// scan reads the token starting at the current character and leaves the
+// lexer on the character following it.
+func (l *Lexer) scan() (token.Token, error) {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
@@ -38,6 +81,10 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			ch := l.ch
 			l.readChar()
 			tok = token.Token{Type: token.FATARROW, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '~' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.MATCH, Literal: string(ch) + string(l.ch)}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -46,8 +93,17 @@ func (l *Lexer) NextToken() (token.Token, error) {
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case '"':
-		str, err := l.readString()
+		typ, str, err := l.readString()
+		if err != nil {
+			tok.Type = token.ILLEGAL
+			return tok, err
+		}
+		tok.Literal = str
+		tok.Type = typ
+	case '`':
+		str, err := l.readRawString()
 		if err != nil {
+			tok.Type = token.ILLEGAL
 			return tok, err
 		}
 		tok.Literal = str
@@ -69,6 +125,10 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			ch := l.ch
 			l.readChar()
 			tok = token.Token{Type: token.NEQ, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '~' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.NOT_MATCH, Literal: string(ch) + string(l.ch)}
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
@@ -86,15 +146,26 @@ func (l *Lexer) NextToken() (token.Token, error) {
 		if l.prev.Type == token.RPAREN || // (a+c) / b
 			l.prev.Type == token.RBRACKET || // a[3] / b
 			l.prev.Type == token.IDENT || // a / b
-			l.prev.Type == token.INT { // 3 / b
+			l.prev.Type == token.INT || // 3 / b
+			l.prev.Type == token.FLOAT { // 3.5 / b
 			tok = newToken(token.SLASH, l.ch)
 		} else {
 			//regexp
-			tok.Literal = l.readRegex('/')
+			lit, err := l.readRegex('/')
+			if err != nil {
+				tok.Type = token.ILLEGAL
+				return tok, err
+			}
+			tok.Literal = lit
 			tok.Type = token.REGEX
 		}
 	case '~':
-		tok.Literal = l.readRegex('~')
+		lit, err := l.readRegex('~')
+		if err != nil {
+			tok.Type = token.ILLEGAL
+			return tok, err
+		}
+		tok.Literal = lit
 		tok.Type = token.REGEX
 
 	case '.':
@@ -134,19 +205,27 @@ func (l *Lexer) NextToken() (token.Token, error) {
 		} else if l.peekChar() == '|' {
 			tok = token.Token{Type: token.OR, Literal: string(l.ch) + string(l.peekChar())}
 			l.readChar()
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+			l.readChar()
+			return tok, errors.New(`illegal character "|", did you mean "||" or "|>"?`)
 		}
-	//} else {
-	//	tok = newToken(token.BITOR, l.ch)
-	//}
 
 	case '&':
 		if l.peekChar() != '&' {
-			panic("unsupported infix operator")
+			tok = newToken(token.ILLEGAL, l.ch)
+			l.readChar()
+			return tok, errors.New(`illegal character "&", did you mean "&&"?`)
 		}
 		tok = token.Token{Type: token.AND, Literal: string(l.ch) + string(l.peekChar())}
 		l.readChar()
 
 	case 0:
+		if l.position < len(l.input) {
+			tok = newToken(token.ILLEGAL, l.ch)
+			l.readChar()
+			return tok, fmt.Errorf("illegal character %q", tok.Literal)
+		}
 		tok.Literal = ""
 		tok.Type = token.EOF
 	default:
@@ -154,50 +233,56 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			ident := l.readIdentifier()
 			tok.Literal = ident
 			tok.Type = token.LookupIdent(ident)
-			l.prev = tok
 			return tok, nil
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
-			l.prev = tok
-			return tok, nil
+			typ, lit, err := l.readNumber()
+			tok.Type = typ
+			tok.Literal = lit
+			return tok, err
+		} else if l.ch == utf8.RuneError && l.readPosition-l.position == 1 {
+			tok = token.Token{Type: token.ILLEGAL, Literal: l.input[l.position:l.readPosition]}
+			l.readChar()
+			return tok, errors.New("invalid UTF-8 encoding")
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
-			return tok, fmt.Errorf("illegal syntax on %v token %s", l.position, tok.Literal)
+			l.readChar()
+			return tok, fmt.Errorf("illegal character %q", tok.Literal)
 		}
 	}
 
-	tok.Col = l.col
-	tok.Line = l.line
-
 	l.readChar()
-
-	l.prev = tok
 	return tok, nil
 }
 
+// readChar advances to the next character, decoding the input as UTF-8.
+// line and col always describe the position of l.ch, col counting runes,
+// at the end of input they point just past the last character.
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0 // ASCII for NUL
-	} else {
-		l.ch = l.input[l.readPosition]
+	if l.readPosition > 0 && l.position < len(l.input) {
 		if l.ch == '\n' {
+			l.line++
 			l.col = 1
-			l.line += 1
 		} else {
-			l.col += 1
+			l.col++
 		}
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	if l.readPosition >= len(l.input) {
+		l.ch = 0 // ASCII for NUL
+		l.readPosition += 1
+		return
+	}
+	ch, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	l.ch = ch
+	l.readPosition += width
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -206,65 +291,204 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isLetter(l.ch) {
+// Input returns the source being tokenized.
+func (l *Lexer) Input() string {
+	return l.input
+}
+
+// Comments returns every comment skipped so far, in source order.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// skipComment consumes a `//` line comment or a `/* */` block comment.
+// Block comments nest, so `/* a /* b */ c */` is a single comment.
+// Since a regex can't start with `/` or `*`, both forms always win over
+// the regex literal branch in NextToken.
+func (l *Lexer) skipComment() error {
+	start := l.position
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Col: l.col}
+
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		tok.Literal = l.input[start:l.position]
+		l.comments = append(l.comments, tok)
+		return nil
+	}
+
+	l.readChar() // skip the opening '/'
+	l.readChar() // skip the opening '*'
+	depth := 1
+	for depth > 0 {
+		switch {
+		case l.ch == 0:
+			return &IncompleteError{msg: fmt.Sprintf("unterminated block comment on line %d", tok.Line)}
+		case l.ch == '/' && l.peekChar() == '*':
+			l.readChar()
+			depth++
+		case l.ch == '*' && l.peekChar() == '/':
+			l.readChar()
+			depth--
+		}
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	tok.Literal = l.input[start:l.position]
+	l.comments = append(l.comments, tok)
+	return nil
 }
 
-func (l *Lexer) readNumber() string {
+// readIdentifier consumes a letter followed by any letters and digits,
+// unicode ones included.
+func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isDigit(l.ch) {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString() (string, error) {
-	var ret []byte
-	for {
+// readNumber consumes a numeric literal using Go's syntax: decimal
+// integers, floats with a fraction and/or exponent, integers prefixed
+// with 0x, 0o or 0b, and `_` between digits. A `.` only belongs to the
+// literal when a digit follows it, so `math.Max` and `x.y` still lex as
+// method calls.
+func (l *Lexer) readNumber() (token.Type, string, error) {
+	start := l.position
+	typ := token.Type(token.INT)
+	malformed := func(format string, a ...interface{}) (token.Type, string, error) {
+		// swallow the rest of the word so the error names the whole literal
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+		lit := l.input[start:l.position]
+		return token.ILLEGAL, lit, fmt.Errorf("invalid number literal %q: %s", lit, fmt.Sprintf(format, a...))
+	}
+
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
 		l.readChar()
-		if l.ch == '\\' {
+		base, name := 16, "hexadecimal"
+		switch l.ch {
+		case 'o', 'O':
+			base, name = 8, "octal"
+		case 'b', 'B':
+			base, name = 2, "binary"
+		}
+		l.readChar()
+		digits := l.readDigits(isHex)
+		if reason := checkDigits(digits, true); reason != "" {
+			return malformed("%s literal %s", name, reason)
+		}
+		for _, d := range digits {
+			if d != '_' && digitValue(d) >= base {
+				return malformed("invalid digit %q in %s literal", d, name)
+			}
+		}
+	} else {
+		if reason := checkDigits(l.readDigits(isDigit), false); reason != "" {
+			return malformed("%s", reason)
+		}
+		if l.ch == '.' && isDigit(l.peekChar()) {
+			typ = token.FLOAT
 			l.readChar()
-			switch l.ch {
-			case 'a':
-				ret = append(ret, '\a')
-				continue
-			case 'b':
-				ret = append(ret, '\b')
-				continue
-			case 'f':
-				ret = append(ret, '\f')
-				continue
-			case 'r':
-				ret = append(ret, '\r')
-				continue
-			case 'n':
-				ret = append(ret, '\n')
-				continue
-			case 't':
-				ret = append(ret, '\t')
-				continue
-			case 'v':
-				ret = append(ret, '\v')
-				continue
+			if reason := checkDigits(l.readDigits(isDigit), false); reason != "" {
+				return malformed("fraction %s", reason)
+			}
+		}
+		if l.ch == 'e' || l.ch == 'E' {
+			typ = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			if reason := checkDigits(l.readDigits(isDigit), false); reason != "" {
+				return malformed("exponent %s", reason)
 			}
-			ret = append(ret, '\\', l.ch)
-			continue
-		} else if l.ch == '"' {
-			break
-		} else if len(ret) > len(l.input) {
-			return "", errors.New("syntax error")
-		} else {
-			ret = append(ret, l.ch)
 		}
+		lit := l.input[start:l.position]
+		if typ == token.INT && len(lit) > 1 && lit[0] == '0' {
+			return malformed("leading zero in decimal literal, use 0o for octal")
+		}
+	}
+
+	if isLetter(l.ch) || isDigit(l.ch) {
+		return malformed("unexpected %q after number", l.ch)
 	}
-	return string(ret), nil
+	return typ, l.input[start:l.position], nil
 }
 
-func (l *Lexer) readRegex(delim byte) (lit string) {
+// readDigits consumes a run of digits accepted by valid, including any
+// `_` separators, and returns it.
+func (l *Lexer) readDigits(valid func(rune) bool) string {
+	position := l.position
+	for valid(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+	return l.input[position:l.position]
+}
+
+// checkDigits validates the placement of `_` separators in digits and
+// returns a description of the problem, or "" when digits is well formed.
+// Only digits directly following a base prefix may start with `_`.
+func checkDigits(digits string, leadingUnderscore bool) string {
+	switch {
+	case strings.Trim(digits, "_") == "":
+		return "has no digits"
+	case !leadingUnderscore && digits[0] == '_',
+		digits[len(digits)-1] == '_',
+		strings.Contains(digits, "__"):
+		return "'_' must separate successive digits"
+	}
+	return ""
+}
+
+// readString reads a "double quoted" string literal. Its escapes are
+// resolved, except when it embeds ${expressions}: then it is a TEMPLATE
+// whose raw content is split up by the parser, see Segments.
+func (l *Lexer) readString() (token.Type, string, error) {
+	start := l.position + 1
+	end, interpolated, err := stringEnd(l.input, start)
+	if err != nil {
+		l.skipTo(len(l.input))
+		return token.ILLEGAL, "", err
+	}
+	l.skipTo(end)
+	if interpolated {
+		return token.TEMPLATE, l.input[start:end], nil
+	}
+	str, err := Unescape(l.input[start:end])
+	if err != nil {
+		l.readChar()
+		return token.ILLEGAL, "", err
+	}
+	return token.STRING, str, nil
+}
+
+// readRawString reads a `backtick` string literal, which has no escapes
+// and may span lines.
+func (l *Lexer) readRawString() (string, error) {
+	start := l.position + 1
+	end := strings.IndexByte(l.input[start:], '`')
+	if end < 0 {
+		l.skipTo(len(l.input))
+		return "", &IncompleteError{msg: "unterminated raw string"}
+	}
+	l.skipTo(start + end)
+	return l.input[start : start+end], nil
+}
+
+// skipTo advances to the character at index i, keeping line and col
+// right. The closing quote it stops on is skipped by scan.
+func (l *Lexer) skipTo(i int) {
+	for l.position < i && l.position < len(l.input) {
+		l.readChar()
+	}
+}
+
+// readRegex reads a regex literal enclosed in delim, which may be escaped
+// inside it with a backslash.
+func (l *Lexer) readRegex(delim rune) (string, error) {
 	start := l.position + 1
 	for {
 		l.readChar()
@@ -272,21 +496,48 @@ func (l *Lexer) readRegex(delim byte) (lit string) {
 			// skip escape sequence
 			l.readChar()
 		} else if l.ch == delim {
-			lit = l.input[start:l.position]
-			l.readChar() // skip the closing delim
-			return
+			// the closing delim is skipped by scan
+			return l.input[start:l.position], nil
+		}
+		if l.position >= len(l.input) {
+			return "", errors.New("unterminated regex")
 		}
 	}
 }
 
-func newToken(Type token.Type, ch byte) token.Token {
+func newToken(Type token.Type, ch rune) token.Token {
 	return token.Token{Type: Type, Literal: string(ch)}
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+func isHex(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+func digitValue(ch rune) int {
+	switch {
+	case isDigit(ch):
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		return int(ch-'a') + 10
+	case 'A' <= ch && ch <= 'F':
+		return int(ch-'A') + 10
+	}
+	return 16
+}
@@ -275,3 +275,451 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchOperators(t *testing.T) {
+	input := `s =~ /a\/b/; s !~ ~x~; a = ~y~`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "s"},
+		{token.MATCH, "=~"},
+		{token.REGEX, `a\/b`},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "s"},
+		{token.NOT_MATCH, "!~"},
+		{token.REGEX, "x"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.REGEX, "y"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// komentar baris
+var a = 10 // di akhir baris
+/* komentar
+   /* bersarang */
+   blok */
+a / 2 /* antara */ / 5;
+/[a-z]+/
+`
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.VAR, "var"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.IDENT, "a"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SLASH, "/"},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.REGEX, "[a-z]+"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	comments := []string{
+		"// komentar baris",
+		"// di akhir baris",
+		"/* komentar\n   /* bersarang */\n   blok */",
+		"/* antara */",
+	}
+	if len(l.Comments()) != len(comments) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(comments), len(l.Comments()))
+	}
+	for i, c := range l.Comments() {
+		if c.Type != token.COMMENT {
+			t.Errorf("comments[%d] - tokentype wrong. expected=%q, got=%q", i, token.COMMENT, c.Type)
+		}
+		if c.Literal != comments[i] {
+			t.Errorf("comments[%d] - literal wrong. expected=%q, got=%q", i, comments[i], c.Literal)
+		}
+	}
+	if line := l.Comments()[2].Line; line != 3 {
+		t.Errorf("block comment line wrong. expected=3, got=%d", line)
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	l := New("var a = 1 /* /* */")
+	for i := 0; i < 4; i++ {
+		if _, err := l.NextToken(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tok, err := l.NextToken()
+	if err == nil {
+		t.Fatalf("expected error for unterminated block comment, got token %q", tok.Type)
+	}
+	if tok.Type != token.ILLEGAL {
+		t.Errorf("tokentype wrong. expected=%q, got=%q", token.ILLEGAL, tok.Type)
+	}
+	if _, ok := err.(*IncompleteError); !ok {
+		t.Errorf("error is not *IncompleteError. got=%T (%v)", err, err)
+	}
+}
+
+func TestIncompleteErrors(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"`mentah", true},
+		{"/* komentar", true},
+		{`"biasa`, false},
+		{"#", false},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		var err error
+		for tok := (token.Token{}); err == nil && tok.Type != token.EOF; {
+			tok, err = l.NextToken()
+		}
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if _, ok := err.(*IncompleteError); ok != tt.incomplete {
+			t.Errorf("%q: incomplete wrong. expected=%t, got=%t (%v)", tt.input, tt.incomplete, ok, err)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{"0", token.INT, "0"},
+		{"42", token.INT, "42"},
+		{"1_000_000", token.INT, "1_000_000"},
+		{"0xFF", token.INT, "0xFF"},
+		{"0X_ff_ff", token.INT, "0X_ff_ff"},
+		{"0o755", token.INT, "0o755"},
+		{"0b1010_1010", token.INT, "0b1010_1010"},
+		{"3.14", token.FLOAT, "3.14"},
+		{"0.5", token.FLOAT, "0.5"},
+		{"1e9", token.FLOAT, "1e9"},
+		{"1e-9", token.FLOAT, "1e-9"},
+		{"6.022E+23", token.FLOAT, "6.022E+23"},
+		{"1_000.000_1", token.FLOAT, "1_000.000_1"},
+	}
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %s", i, err)
+		}
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok, _ := l.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("tests[%d] - literal not fully consumed, next token %q", i, tok.Literal)
+		}
+	}
+}
+
+func TestNumberFollowedByDot(t *testing.T) {
+	input := `1.Max 2.5.x math.Max`
+	expected := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.INT, "1"},
+		{token.DOT, "."},
+		{token.IDENT, "Max"},
+		{token.FLOAT, "2.5"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.IDENT, "math"},
+		{token.DOT, "."},
+		{token.IDENT, "Max"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range expected {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestMalformedNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"0x", `invalid number literal "0x": hexadecimal literal has no digits`},
+		{"0b102", `invalid number literal "0b102": invalid digit '2' in binary literal`},
+		{"0o8", `invalid number literal "0o8": invalid digit '8' in octal literal`},
+		{"0xFG", `invalid number literal "0xFG": unexpected 'G' after number`},
+		{"1__000", `invalid number literal "1__000": '_' must separate successive digits`},
+		{"1_", `invalid number literal "1_": '_' must separate successive digits`},
+		{"1.5_", `invalid number literal "1.5_": fraction '_' must separate successive digits`},
+		{"1e", `invalid number literal "1e": exponent has no digits`},
+		{"1e+", `invalid number literal "1e+": exponent has no digits`},
+		{"0755", `invalid number literal "0755": leading zero in decimal literal, use 0o for octal`},
+		{"12abc", `invalid number literal "12abc": unexpected 'a' after number`},
+	}
+	for i, tt := range tests {
+		tok, err := New(tt.input).NextToken()
+		if err == nil {
+			t.Fatalf("tests[%d] - expected error, got token %q %q", i, tok.Type, tok.Literal)
+		}
+		if tok.Type != token.ILLEGAL {
+			t.Errorf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, token.ILLEGAL, tok.Type)
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "var ab = 10 == 2.5;\n" +
+		"\tx |> f(\"hi\");\n" +
+		"/[a-z]+/ y\n"
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+		line, col       int
+		endLine, endCol int
+	}{
+		{token.VAR, "var", 1, 1, 1, 4},
+		{token.IDENT, "ab", 1, 5, 1, 7},
+		{token.ASSIGN, "=", 1, 8, 1, 9},
+		{token.INT, "10", 1, 10, 1, 12},
+		{token.EQ, "==", 1, 13, 1, 15},
+		{token.FLOAT, "2.5", 1, 16, 1, 19},
+		{token.SEMICOLON, ";", 1, 19, 1, 20},
+		{token.IDENT, "x", 2, 2, 2, 3},
+		{token.PIPE, "|>", 2, 4, 2, 6},
+		{token.IDENT, "f", 2, 7, 2, 8},
+		{token.LPAREN, "(", 2, 8, 2, 9},
+		{token.STRING, "hi", 2, 9, 2, 13},
+		{token.RPAREN, ")", 2, 13, 2, 14},
+		{token.SEMICOLON, ";", 2, 14, 2, 15},
+		{token.REGEX, "[a-z]+", 3, 1, 3, 9},
+		{token.IDENT, "y", 3, 10, 3, 11},
+		{token.EOF, "", 4, 1, 4, 1},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %s", i, err)
+		}
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Pos() != (token.Pos{Line: tt.line, Col: tt.col}) {
+			t.Errorf("tests[%d] %q - start wrong. expected=%d:%d, got=%s", i, tok.Literal, tt.line, tt.col, tok.Pos())
+		}
+		if tok.End() != (token.Pos{Line: tt.endLine, Col: tt.endCol}) {
+			t.Errorf("tests[%d] %q - end wrong. expected=%d:%d, got=%s", i, tok.Literal, tt.endLine, tt.endCol, tok.End())
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{`"a\tb\n"`, token.STRING, "a\tb\n"},
+		{`"foo\"bar"`, token.STRING, `foo\"bar`},
+		{`"\u{41}\u{e9}\u{1F600}"`, token.STRING, "Aé😀"},
+		{`"harga \$5"`, token.STRING, "harga $5"},
+		{`"halo ${nama}!"`, token.TEMPLATE, "halo ${nama}!"},
+		{`"${ {"a": "}"}["a"] }"`, token.TEMPLATE, `${ {"a": "}"}["a"] }`},
+		{`"\${nama}"`, token.STRING, "${nama}"},
+		{"`a\\n\"b\"\n${c}`", token.STRING, "a\\n\"b\"\n${c}"},
+	}
+	for i, tt := range tests {
+		tok, err := New(tt.input).NextToken()
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %s", i, err)
+		}
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	l := New("`satu\ndua` x")
+	l.NextToken()
+	tok, _ := l.NextToken()
+	if tok.Line != 2 || tok.Col != 6 {
+		t.Errorf("token after raw string at wrong position. got=%d:%d", tok.Line, tok.Col)
+	}
+}
+
+func TestMalformedStringLiterals(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`"abc`, "unterminated string"},
+		{"`abc", "unterminated raw string"},
+		{`"a ${b"`, "unterminated string"},
+		{`"a ${b`, "unterminated interpolation, missing }"},
+		{`"\u41"`, `invalid unicode escape, want \u{hex}`},
+		{`"\u{110000}"`, `invalid unicode escape \u{110000}`},
+		{`"\u{d800}"`, `invalid unicode escape \u{d800}`},
+		{`"\u{}"`, `invalid unicode escape \u{}`},
+	}
+	for i, tt := range tests {
+		tok, err := New(tt.input).NextToken()
+		if err == nil {
+			t.Fatalf("tests[%d] - expected error, got token %q %q", i, tok.Type, tok.Literal)
+		}
+		if tok.Type != token.ILLEGAL {
+			t.Errorf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, token.ILLEGAL, tok.Type)
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "var café = \"é😀\" + 名前2;\nα"
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+		line, col       int
+	}{
+		{token.VAR, "var", 1, 1},
+		{token.IDENT, "café", 1, 5},
+		{token.ASSIGN, "=", 1, 10},
+		{token.STRING, "é😀", 1, 12},
+		{token.PLUS, "+", 1, 17},
+		{token.IDENT, "名前2", 1, 19},
+		{token.SEMICOLON, ";", 1, 22},
+		{token.IDENT, "α", 2, 1},
+		{token.EOF, "", 2, 2},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Line != tt.line || tok.Col != tt.col {
+			t.Errorf("tests[%d] - %q at wrong position. expected=%d:%d, got=%d:%d", i, tok.Literal, tt.line, tt.col, tok.Line, tok.Col)
+		}
+	}
+
+	tok, err := New("\xff").NextToken()
+	if tok.Type != token.ILLEGAL || err == nil || err.Error() != "invalid UTF-8 encoding" {
+		t.Errorf("invalid UTF-8 not rejected. got=%q %v", tok.Type, err)
+	}
+	if tok, err = New("∑").NextToken(); err == nil || err.Error() != `illegal character "∑"` {
+		t.Errorf("symbol accepted as identifier. got=%q %q", tok.Type, tok.Literal)
+	}
+}
+
+func TestIllegalInput(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"&", `illegal character "&", did you mean "&&"?`},
+		{"|", `illegal character "|", did you mean "||" or "|>"?`},
+		{"/[a-z]+", "unterminated regex"},
+		{`~a\~`, "unterminated regex"},
+		{`/a\`, "unterminated regex"},
+		{"\x00", `illegal character "\x00"`},
+	}
+	for i, tt := range tests {
+		l := New("a = " + tt.input)
+		l.NextToken()
+		l.NextToken()
+		tok, err := l.NextToken()
+		if err == nil {
+			t.Fatalf("tests[%d] - expected error, got token %q %q", i, tok.Type, tok.Literal)
+		}
+		if tok.Type != token.ILLEGAL {
+			t.Errorf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, token.ILLEGAL, tok.Type)
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, err.Error())
+		}
+		if tok, _ := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("tests[%d] - expected EOF after the illegal token, got %q", i, tok.Type)
+		}
+	}
+}
+
+func FuzzNextToken(f *testing.F) {
+	for _, seed := range []string{
+		"var a = 1 + 2.5e3 * 0x1F;",
+		`"a ${b + "c"} \u{41}" + ` + "`raw`",
+		"/[a-z]+/ =~ ~x~ // comment\n/* block */",
+		"a & b | c",
+		"/unterminated",
+		"\xff\x00∑",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		l := New(input)
+		// every token consumes at least a byte, except EOF
+		for i := 0; ; i++ {
+			if i > len(input)+1 {
+				t.Fatalf("lexer does not reach EOF on %q", input)
+			}
+			tok, _ := l.NextToken()
+			if tok.Type == token.EOF {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Segment is a piece of an interpolated string: either Text, with its
+// escapes already resolved, or the source of an embedded ${Expr} that
+// starts Offset bytes into the literal.
+type Segment struct {
+	Text   string
+	Expr   string
+	IsExpr bool
+	Offset int
+}
+
+// stringEnd returns the index of the `"` closing the string literal whose
+// content starts at s[i], and whether the literal embeds ${expressions}.
+func stringEnd(s string, i int) (int, bool, error) {
+	interpolated := false
+	for i < len(s) {
+		switch {
+		case s[i] == '\\':
+			i += 2
+		case s[i] == '"':
+			return i, interpolated, nil
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			end, err := interpolationEnd(s, i+2)
+			if err != nil {
+				return 0, false, err
+			}
+			interpolated = true
+			i = end + 1
+		default:
+			i++
+		}
+	}
+	return 0, false, fmt.Errorf("unterminated string")
+}
+
+// interpolationEnd returns the index of the `}` closing the ${expression}
+// whose source starts at s[i]. Braces and string literals inside the
+// expression are skipped over.
+func interpolationEnd(s string, i int) (int, error) {
+	depth := 1
+	for i < len(s) {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		case '"':
+			end, _, err := stringEnd(s, i+1)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case '`':
+			end := strings.IndexByte(s[i+1:], '`')
+			if end < 0 {
+				return 0, fmt.Errorf("unterminated raw string")
+			}
+			i += end + 1
+		}
+		i++
+	}
+	return 0, fmt.Errorf("unterminated interpolation, missing }")
+}
+
+// Segments splits the content of an interpolated string literal into its
+// text and ${expression} pieces.
+func Segments(lit string) ([]Segment, error) {
+	var (
+		segments []Segment
+		start    int
+	)
+	text := func(end int) error {
+		if end == start {
+			return nil
+		}
+		s, err := Unescape(lit[start:end])
+		if err != nil {
+			return err
+		}
+		segments = append(segments, Segment{Text: s})
+		return nil
+	}
+	for i := 0; i < len(lit); {
+		switch {
+		case lit[i] == '\\':
+			i += 2
+		case lit[i] == '$' && i+1 < len(lit) && lit[i+1] == '{':
+			if err := text(i); err != nil {
+				return nil, err
+			}
+			end, err := interpolationEnd(lit, i+2)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, Segment{Expr: lit[i+2 : end], IsExpr: true, Offset: i + 2})
+			i = end + 1
+			start = i
+		default:
+			i++
+		}
+	}
+	if err := text(len(lit)); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// Unescape resolves the escapes of a string literal: \n and the other
+// single letter escapes of Go, \$ for a literal $ and \u{hex} for any
+// unicode code point. Other escapes are kept as written.
+func Unescape(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'a':
+			out.WriteByte('\a')
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'r':
+			out.WriteByte('\r')
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'v':
+			out.WriteByte('\v')
+		case '$':
+			out.WriteByte('$')
+		case 'u':
+			end := strings.IndexByte(s[i:], '}')
+			if i+1 == len(s) || s[i+1] != '{' || end < 0 {
+				return "", fmt.Errorf(`invalid unicode escape, want \u{hex}`)
+			}
+			hex := s[i+2 : i+end]
+			r, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil || hex == "" || !utf8.ValidRune(rune(r)) {
+				return "", fmt.Errorf(`invalid unicode escape \u{%s}`, hex)
+			}
+			out.WriteRune(rune(r))
+			i += end
+		default:
+			out.WriteByte('\\')
+			out.WriteByte(s[i])
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,383 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+// document is an open file, parsed again on every change. After a syntax
+// error the tree holds what the parser recovered.
+type document struct {
+	uri     string
+	version int
+	lines   []string
+	program *ast.Program
+	errors  []parser.ParseError
+	tokens  []token.Token             // without semicolons
+	closing map[token.Pos]token.Token // the } closing each {
+
+	globals *scope
+	scopes  []*scope
+	uses    map[*ast.Identifier]*binding        // names, also binding ones, to their binding
+	members map[*ast.Identifier]*ast.Identifier // names after a dot to the name before it
+}
+
+func newDocument(uri string, version int, text string) *document {
+	p := parser.New(lexer.New(text))
+	program, _ := p.ParseProgram()
+	d := &document{
+		uri:     uri,
+		version: version,
+		lines:   strings.Split(text, "\n"),
+		program: program,
+		errors:  p.Errors(),
+		closing: make(map[token.Pos]token.Token),
+		uses:    make(map[*ast.Identifier]*binding),
+		members: make(map[*ast.Identifier]*ast.Identifier),
+	}
+
+	var open []token.Token
+	l := lexer.New(text)
+	for {
+		tok, _ := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		switch tok.Type {
+		case token.SEMICOLON:
+			continue
+		case token.LBRACE:
+			open = append(open, tok)
+		case token.RBRACE:
+			if len(open) > 0 {
+				d.closing[open[len(open)-1].Pos()] = tok
+				open = open[:len(open)-1]
+			}
+		}
+		d.tokens = append(d.tokens, tok)
+	}
+
+	r := &resolver{doc: d}
+	d.globals = r.newScope(nil)
+	d.globals.start, d.globals.end = token.Pos{Line: 1, Col: 1}, d.eof()
+	r.statements(program.Statements, d.eof(), d.globals)
+	for _, ref := range r.refs {
+		if b := ref.scope.lookup(ref.id.Value, ref.id.Pos()); b != nil {
+			d.uses[ref.id] = b
+		}
+	}
+	return d
+}
+
+// position converts pos, whose column counts runes, to an LSP position.
+func (d *document) position(pos token.Pos) Position {
+	if pos.Line < 1 || pos.Line > len(d.lines) {
+		return Position{Line: len(d.lines) - 1, Character: utf16Len(d.lines[len(d.lines)-1])}
+	}
+	line, n := d.lines[pos.Line-1], 0
+	for col := 1; col < pos.Col && line != ""; col++ {
+		r, size := utf8.DecodeRuneInString(line)
+		line = line[size:]
+		n += utf16RuneLen(r)
+	}
+	return Position{Line: pos.Line - 1, Character: n}
+}
+
+// pos converts an LSP position back, see position.
+func (d *document) pos(p Position) token.Pos {
+	if p.Line < 0 || p.Line >= len(d.lines) {
+		return token.Pos{}
+	}
+	line, n, col := d.lines[p.Line], 0, 1
+	for n < p.Character && line != "" {
+		r, size := utf8.DecodeRuneInString(line)
+		line = line[size:]
+		n += utf16RuneLen(r)
+		col++
+	}
+	return token.Pos{Line: p.Line + 1, Col: col}
+}
+
+func (d *document) span(start, end token.Pos) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16RuneLen(r)
+	}
+	return n
+}
+
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// diagnostics returns the syntax errors, each marking the token it was
+// found at.
+func (d *document) diagnostics() []Diagnostic {
+	diags := make([]Diagnostic, 0, len(d.errors))
+	for _, e := range d.errors {
+		end := token.Pos{Line: e.Pos.Line, Col: e.Pos.Col + 1}
+		if tok, ok := d.tokenAt(e.Pos); ok && tok.Pos() == e.Pos && tok.EndLine == e.Pos.Line {
+			end = tok.End()
+		}
+		diags = append(diags, Diagnostic{
+			Range:    d.span(e.Pos, end),
+			Severity: severityError,
+			Source:   "bilang",
+			Message:  e.Msg,
+		})
+	}
+	return diags
+}
+
+// tokenAt returns the token at or just before pos.
+func (d *document) tokenAt(pos token.Pos) (token.Token, bool) {
+	i := sort.Search(len(d.tokens), func(i int) bool { return pos.Before(d.tokens[i].Pos()) })
+	if i == 0 {
+		return token.Token{}, false
+	}
+	return d.tokens[i-1], true
+}
+
+// eof is a position after every token.
+func (d *document) eof() token.Pos {
+	return token.Pos{Line: len(d.lines) + 1, Col: 1}
+}
+
+// end returns the end of the last token before bound, which is where a
+// statement followed by what is at bound ends.
+func (d *document) end(bound token.Pos) token.Pos {
+	i := sort.Search(len(d.tokens), func(i int) bool { return !d.tokens[i].Pos().Before(bound) })
+	if i == 0 {
+		return bound
+	}
+	return d.tokens[i-1].End()
+}
+
+// identAt returns the name at pos, or just before it as the cursor is
+// after the name while typing.
+func (d *document) identAt(pos token.Pos) *ast.Identifier {
+	var found *ast.Identifier
+	ast.Inspect(d.program, func(n ast.Node) bool {
+		id, ok := n.(*ast.Identifier)
+		if ok && !pos.Before(id.Pos()) && !id.Token.End().Before(pos) {
+			found = id
+		}
+		return true
+	})
+	return found
+}
+
+// binding is a name bound by var, konst, impor, a parameter, a tiap
+// variable or tangkap. tok is the name, or the path of an impor without
+// alias.
+type binding struct {
+	name  string
+	kind  string // the keyword binding the name, or "parameter"
+	tok   token.Token
+	value ast.Expression // of var and konst
+
+	stmt     ast.Statement // declaring a global, var or konst, for symbols
+	children []*binding    // the var and konst in a function bound by stmt
+	end      token.Pos     // of stmt
+}
+
+// scope holds the bindings of the program, a function, the body of tiap or
+// a tangkap block.
+type scope struct {
+	parent     *scope
+	bindings   map[string][]*binding // in source order
+	order      []*binding
+	start, end token.Pos // of the block
+}
+
+// lookup returns the binding of name visible at pos. In the nearest scope
+// binding name that is the last one before pos, or the first one when all
+// come after pos, e.g. a function calling another declared below it.
+func (s *scope) lookup(name string, pos token.Pos) *binding {
+	for ; s != nil; s = s.parent {
+		bs := s.bindings[name]
+		if len(bs) == 0 {
+			continue
+		}
+		found := bs[0]
+		for _, b := range bs[1:] {
+			if b.tok.Pos().Before(pos) {
+				found = b
+			}
+		}
+		return found
+	}
+	return nil
+}
+
+// resolver binds the names of a document to their bindings. Uses are
+// resolved once every binding is known, see scope.lookup.
+type resolver struct {
+	doc  *document
+	refs []ref
+	fn   *binding  // whose function body is being resolved
+	end  token.Pos // of the statement being resolved
+}
+
+type ref struct {
+	id    *ast.Identifier
+	scope *scope
+}
+
+func (r *resolver) newScope(parent *scope) *scope {
+	sc := &scope{parent: parent, bindings: make(map[string][]*binding)}
+	r.doc.scopes = append(r.doc.scopes, sc)
+	return sc
+}
+
+func (r *resolver) bind(sc *scope, b *binding, id *ast.Identifier) *binding {
+	sc.bindings[b.name] = append(sc.bindings[b.name], b)
+	sc.order = append(sc.order, b)
+	if id != nil {
+		r.doc.uses[id] = b
+	}
+	return b
+}
+
+// statements resolves a program or a block, close is the } ending it or
+// the end of the program.
+func (r *resolver) statements(stmts []ast.Statement, close token.Pos, sc *scope) {
+	for i, stmt := range stmts {
+		bound := close
+		if i+1 < len(stmts) {
+			bound = stmts[i+1].Pos()
+		}
+		r.end = r.doc.end(bound)
+		r.node(stmt, sc)
+	}
+}
+
+// block resolves the statements of b in sc, which spans b unless it is
+// the scope of a block around b.
+func (r *resolver) block(b *ast.BlockStatement, sc *scope) {
+	if b == nil {
+		return
+	}
+	close := r.doc.eof()
+	if tok, ok := r.doc.closing[b.Token.Pos()]; ok {
+		close = tok.Pos()
+	}
+	if !sc.start.IsValid() {
+		sc.start, sc.end = b.Token.Pos(), close
+	}
+	r.statements(b.Statements, close, sc)
+}
+
+func (r *resolver) node(node ast.Node, sc *scope) {
+	switch n := node.(type) {
+	case nil:
+	case *ast.VarStatement:
+		// an assignment is a var statement written without var
+		if n.Name != nil && n.Token.Literal == "VAR" && sc.lookup(n.Name.Value, n.Name.Pos()) != nil {
+			r.refs = append(r.refs, ref{id: n.Name, scope: sc})
+			r.node(n.Value, sc)
+			return
+		}
+		r.declare(n, "var", n.Name, n.Value, sc)
+	case *ast.KonstStatement:
+		r.declare(n, "konst", n.Name, n.Value, sc)
+	case *ast.ImporStatement:
+		if n.Alias != nil {
+			r.bind(sc, &binding{name: n.Alias.Value, kind: "impor", tok: n.Alias.Token, stmt: n, end: r.end}, n.Alias)
+		} else if n.Path != nil {
+			if name, err := evaluator.ImportName(n); err == nil {
+				r.bind(sc, &binding{name: name, kind: "impor", tok: n.Path.Token, stmt: n, end: r.end}, nil)
+			}
+		}
+	case *ast.FunctionLiteral:
+		inner := r.newScope(sc)
+		for _, param := range n.Parameters {
+			r.bind(inner, &binding{name: param.Value, kind: "parameter", tok: param.Token}, param)
+		}
+		r.block(n.Body, inner)
+	case *ast.LoopLiteral:
+		r.node(n.Iter, sc)
+		inner := r.newScope(sc)
+		for _, kv := range n.KV {
+			r.bind(inner, &binding{name: kv.Value, kind: "tiap", tok: kv.Token}, kv)
+		}
+		r.block(n.Body, inner)
+	case *ast.CobaExpression:
+		r.node(n.Body, sc)
+		inner := r.newScope(sc)
+		if n.Param != nil {
+			r.bind(inner, &binding{name: n.Param.Value, kind: "tangkap", tok: n.Param.Token}, n.Param)
+		}
+		r.block(n.Handler, inner)
+	case *ast.MethodCallExpression:
+		// the member is looked up in the object, e.g. Max in math.Max
+		r.node(n.Object, sc)
+		obj, _ := n.Object.(*ast.Identifier)
+		switch call := n.Call.(type) {
+		case *ast.Identifier:
+			r.member(obj, call)
+		case *ast.CallExpression:
+			if name, ok := call.Function.(*ast.Identifier); ok {
+				r.member(obj, name)
+			} else {
+				r.node(call.Function, sc)
+			}
+			for _, arg := range call.Arguments {
+				r.node(arg, sc)
+			}
+		default:
+			r.node(n.Call, sc)
+		}
+	case *ast.BlockStatement:
+		r.block(n, sc)
+	case *ast.Identifier:
+		r.refs = append(r.refs, ref{id: n, scope: sc})
+	default:
+		ast.Inspect(node, func(child ast.Node) bool {
+			if child == node {
+				return true
+			}
+			r.node(child, sc)
+			return false
+		})
+	}
+}
+
+func (r *resolver) member(obj, name *ast.Identifier) {
+	if obj != nil && name != nil {
+		r.doc.members[name] = obj
+	}
+}
+
+// declare binds name before resolving value, so functions can call
+// themselves.
+func (r *resolver) declare(stmt ast.Statement, kind string, name *ast.Identifier, value ast.Expression, sc *scope) {
+	if name == nil {
+		r.node(value, sc)
+		return
+	}
+	b := r.bind(sc, &binding{name: name.Value, kind: kind, tok: name.Token, value: value, stmt: stmt, end: r.end}, name)
+	if r.fn != nil {
+		r.fn.children = append(r.fn.children, b)
+	}
+	outer := r.fn
+	if _, ok := value.(*ast.FunctionLiteral); ok {
+		r.fn = b
+	}
+	r.node(value, sc)
+	r.fn = outer
+}
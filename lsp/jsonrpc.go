@@ -0,0 +1,122 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	codeParseError           = -32700
+	codeInvalidRequest       = -32600
+	codeMethodNotFound       = -32601
+	codeInvalidParams        = -32602
+	codeServerNotInitialized = -32002
+)
+
+// maxContentLength bounds the body of a message, so a wrong or hostile
+// Content-Length can't make the server allocate all the memory there is.
+const maxContentLength = 32 << 20
+
+// message is a JSON-RPC 2.0 request, notification or response. Requests
+// and responses carry an ID, notifications don't.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return fmt.Sprintf("jsonrpc: %s (%d)", e.Message, e.Code) }
+
+// conn reads and writes messages framed by a Content-Length header, as LSP
+// sends them over stdio. Writes may come from several goroutines.
+type conn struct {
+	r  *textproto.Reader
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: textproto.NewReader(bufio.NewReader(r)), w: w}
+}
+
+// read returns the next message. A malformed body gives a *rpcError, the
+// connection can still be read after it.
+func (c *conn) read() (*message, error) {
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("jsonrpc: invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	if length > maxContentLength {
+		// the body can't be skipped without reading it, so the stream is lost
+		return nil, fmt.Errorf("jsonrpc: Content-Length %d exceeds the maximum of %d", length, maxContentLength)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r.R, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, &rpcError{Code: codeParseError, Message: err.Error()}
+	}
+	return &msg, nil
+}
+
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+// call sends a request, or a notification when id is nil.
+func (c *conn) call(id *json.RawMessage, method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{ID: id, Method: method, Params: raw})
+}
+
+// reply answers the request id with result, or with err when it is not
+// nil. A nil result is sent as null, as JSON-RPC requires a result.
+func (c *conn) reply(id *json.RawMessage, result interface{}, err *rpcError) error {
+	if id == nil {
+		id = new(json.RawMessage)
+		*id = json.RawMessage("null")
+	}
+	if err != nil {
+		return c.write(&message{ID: id, Error: err})
+	}
+	raw, merr := json.Marshal(result)
+	if merr != nil {
+		return merr
+	}
+	return c.write(&message{ID: id, Result: raw})
+}
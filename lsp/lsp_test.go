@@ -0,0 +1,381 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// client talks to a server running in the test, like an editor would.
+// What the server sends is read in the background, so the server never
+// waits for the client to read.
+type client struct {
+	t     *testing.T
+	conn  *conn
+	id    int
+	msgs  chan *message
+	notes []*message // notifications read while waiting for a response
+	done  chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, conn: newConn(clientIn, clientOut), msgs: make(chan *message, 100), done: make(chan error, 1)}
+	go func() {
+		c.done <- Serve(serverIn, serverOut)
+		serverOut.Close()
+	}()
+	go func() {
+		defer close(c.msgs)
+		for {
+			msg, err := c.conn.read()
+			if err != nil {
+				return
+			}
+			c.msgs <- msg
+		}
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+// start initializes the server and opens text as doc.bi.
+func (c *client) start(text string) {
+	c.t.Helper()
+	if err := c.call("initialize", map[string]interface{}{}, nil); err != nil {
+		c.t.Fatal(err)
+	}
+	c.notify("initialized", map[string]interface{}{})
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: "file:///doc.bi", LanguageID: "bilang", Version: 1, Text: text},
+	})
+}
+
+// call sends a request and decodes its result into result.
+func (c *client) call(method string, params, result interface{}) *rpcError {
+	c.t.Helper()
+	c.id++
+	id := json.RawMessage(fmtInt(c.id))
+	if err := c.conn.call(&id, method, params); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.read()
+		if msg.ID == nil {
+			c.notes = append(c.notes, msg)
+			continue
+		}
+		if string(*msg.ID) != string(id) {
+			c.t.Fatalf("response to %s, want %s", *msg.ID, id)
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return nil
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	if err := c.conn.call(nil, method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// diagnostics returns the next diagnostics the server publishes.
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+	for {
+		var msg *message
+		if len(c.notes) > 0 {
+			msg, c.notes = c.notes[0], c.notes[1:]
+		} else {
+			msg = c.read()
+		}
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			c.t.Fatal(err)
+		}
+		return p
+	}
+}
+
+func (c *client) read() *message {
+	c.t.Helper()
+	msg, ok := <-c.msgs
+	if !ok {
+		c.t.Fatal("server closed the connection")
+	}
+	return msg
+}
+
+func (c *client) at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///doc.bi"},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func fmtInt(i int) string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+func span(line, start, end int) Range {
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+}
+
+const program = `konst batas = 10
+var tambah = fn(a, b) {
+    var hasil = a + b
+    pilih hasil
+}
+var angka = [1, 2, 3]
+tiap i, v di angka {
+    println(tambah(v, batas))
+}
+var x = math.Max(1, 2) + panjang(angka)
+var ulang = fn(n) { jika (n > 0) { ulang(n - 1) } }
+`
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	c.start("var x = 1\nvar = 2\n")
+	got := c.diagnostics()
+	if got.URI != "file:///doc.bi" || got.Version != 1 || len(got.Diagnostics) != 1 {
+		t.Fatalf("wrong diagnostics %+v", got)
+	}
+	d := got.Diagnostics[0]
+	if d.Range != span(1, 4, 5) || d.Severity != severityError || d.Message != "expected identifier, found '='" {
+		t.Errorf("wrong diagnostic %+v", d)
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: "file:///doc.bi", Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "var x = 1\nvar y = 2\n"}},
+	})
+	if got := c.diagnostics(); got.Version != 2 || len(got.Diagnostics) != 0 {
+		t.Errorf("diagnostics not cleared %+v", got)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	c.start(program)
+	tests := []struct {
+		line, character int
+		expected        string
+		rng             Range
+	}{
+		{9, 14, "math.Max(x, y angka) float", span(9, 13, 16)},
+		{9, 28, "panjang(x string|array) integer", span(9, 25, 32)},
+		{7, 13, "var tambah = fn(a, b)", span(7, 12, 18)},
+		{7, 24, "konst batas", span(7, 22, 27)},
+		{2, 16, "parameter a", span(2, 16, 17)},
+		{7, 19, "tiap v", span(7, 19, 20)},
+		{3, 15, "var hasil", span(3, 10, 15)},
+	}
+	for _, tt := range tests {
+		var h *Hover
+		if err := c.call("textDocument/hover", c.at(tt.line, tt.character), &h); err != nil {
+			t.Fatal(err)
+		}
+		if h == nil {
+			t.Errorf("%d:%d: no hover", tt.line, tt.character)
+			continue
+		}
+		if want := "```bilang\n" + tt.expected + "\n```"; h.Contents.Value != want || h.Contents.Kind != "markdown" {
+			t.Errorf("%d:%d: expected=%q, got=%q", tt.line, tt.character, want, h.Contents.Value)
+		}
+		if h.Range == nil || *h.Range != tt.rng {
+			t.Errorf("%d:%d: expected range %+v, got %+v", tt.line, tt.character, tt.rng, h.Range)
+		}
+	}
+
+	var h *Hover
+	if err := c.call("textDocument/hover", c.at(5, 14), &h); err != nil || h != nil {
+		t.Errorf("hover on a number: %+v, %v", h, err)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c := newClient(t)
+	c.start(program + "var ganda = fn(x) { x * 2 }\nx = 3\n")
+	tests := []struct {
+		line, character int
+		expected        *Range
+	}{
+		{7, 14, &Range{Start: Position{1, 4}, End: Position{1, 10}}},     // tambah
+		{7, 24, &Range{Start: Position{0, 6}, End: Position{0, 11}}},     // batas
+		{2, 20, &Range{Start: Position{1, 19}, End: Position{1, 20}}},    // b
+		{3, 12, &Range{Start: Position{2, 8}, End: Position{2, 13}}},     // hasil
+		{7, 19, &Range{Start: Position{6, 8}, End: Position{6, 9}}},      // v
+		{9, 33, &Range{Start: Position{5, 4}, End: Position{5, 9}}},      // angka
+		{10, 35, &Range{Start: Position{10, 4}, End: Position{10, 9}}},   // ulang calls itself
+		{11, 20, &Range{Start: Position{11, 15}, End: Position{11, 16}}}, // x, the parameter
+		{12, 0, &Range{Start: Position{9, 4}, End: Position{9, 5}}},      // x, assigned
+		{1, 4, &Range{Start: Position{1, 4}, End: Position{1, 10}}},      // the binding itself
+		{9, 25, nil}, // panjang
+		{9, 9, nil},  // math
+	}
+	for _, tt := range tests {
+		var loc *Location
+		if err := c.call("textDocument/definition", c.at(tt.line, tt.character), &loc); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case tt.expected == nil && loc != nil:
+			t.Errorf("%d:%d: expected no definition, got %+v", tt.line, tt.character, loc)
+		case tt.expected != nil && loc == nil:
+			t.Errorf("%d:%d: no definition", tt.line, tt.character)
+		case tt.expected != nil && (loc.URI != "file:///doc.bi" || loc.Range != *tt.expected):
+			t.Errorf("%d:%d: expected %+v, got %+v", tt.line, tt.character, *tt.expected, loc)
+		}
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	c := newClient(t)
+	c.start(program)
+	var got []DocumentSymbol
+	if err := c.call("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: "file:///doc.bi"}}, &got); err != nil {
+		t.Fatal(err)
+	}
+	expected := []DocumentSymbol{
+		{Name: "batas", Kind: symbolConstant, Range: span(0, 0, 16), SelectionRange: span(0, 6, 11)},
+		{
+			Name: "tambah", Detail: "fn(a, b)", Kind: symbolFunction,
+			Range:          Range{Start: Position{1, 0}, End: Position{4, 1}},
+			SelectionRange: span(1, 4, 10),
+			Children: []DocumentSymbol{
+				{Name: "hasil", Kind: symbolVariable, Range: span(2, 4, 21), SelectionRange: span(2, 8, 13)},
+			},
+		},
+		{Name: "angka", Kind: symbolVariable, Range: span(5, 0, 21), SelectionRange: span(5, 4, 9)},
+		{Name: "x", Kind: symbolVariable, Range: span(9, 0, 39), SelectionRange: span(9, 4, 5)},
+		{Name: "ulang", Detail: "fn(n)", Kind: symbolFunction, Range: span(10, 0, 51), SelectionRange: span(10, 4, 9)},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected\n%+v\ngot\n%+v", expected, got)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+	c.start(program + "ji\nmath.Ma\nvar f = fn(param) { pa }; pa\n")
+	tests := []struct {
+		line, character int
+		expected        []string
+	}{
+		{11, 2, []string{"jika"}},
+		{12, 7, []string{"Max"}},
+		{13, 22, []string{"panjang", "param"}},
+		{13, 28, []string{"panjang"}}, // param is out of scope after the function
+		{0, 2, []string{"konst"}},
+	}
+	for _, tt := range tests {
+		var list CompletionList
+		if err := c.call("textDocument/completion", c.at(tt.line, tt.character), &list); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, item := range list.Items {
+			got = append(got, item.Label)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%d:%d: expected=%q, got=%q", tt.line, tt.character, tt.expected, got)
+		}
+	}
+
+	var list CompletionList
+	if err := c.call("textDocument/completion", c.at(12, 7), &list); err != nil {
+		t.Fatal(err)
+	}
+	if item := list.Items[0]; item.Kind != completionFunction || item.Detail != "math.Max(x, y angka) float" {
+		t.Errorf("wrong completion %+v", item)
+	}
+}
+
+func TestUTF16Positions(t *testing.T) {
+	c := newClient(t)
+	c.start("var s = \"😀\"; var t = s\n")
+	var loc *Location
+	if err := c.call("textDocument/definition", c.at(0, 22), &loc); err != nil {
+		t.Fatal(err)
+	}
+	if loc == nil || loc.Range != span(0, 4, 5) {
+		t.Errorf("wrong definition %+v", loc)
+	}
+	var h *Hover
+	if err := c.call("textDocument/hover", c.at(0, 18), &h); err != nil {
+		t.Fatal(err)
+	}
+	if h == nil || *h.Range != span(0, 18, 19) {
+		t.Errorf("wrong hover %+v", h)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	c := newClient(t)
+	if err := c.call("textDocument/hover", c.at(0, 0), nil); err == nil || err.Code != codeServerNotInitialized {
+		t.Errorf("expected not initialized, got %v", err)
+	}
+	var result InitializeResult
+	if err := c.call("initialize", map[string]interface{}{}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if caps := result.Capabilities; !caps.HoverProvider || !caps.DefinitionProvider || caps.TextDocumentSync.Change != syncFull {
+		t.Errorf("wrong capabilities %+v", caps)
+	}
+	if err := c.call("textDocument/rename", c.at(0, 0), nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %v", err)
+	}
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.call("textDocument/hover", c.at(0, 0), nil); err == nil || err.Code != codeInvalidRequest {
+		t.Errorf("expected invalid request after shutdown, got %v", err)
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("exit after shutdown: %v", err)
+	}
+
+	c = newClient(t)
+	c.start("")
+	c.notify("exit", nil)
+	if err := <-c.done; err != ErrNoShutdown {
+		t.Errorf("expected %v, got %v", ErrNoShutdown, err)
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	tests := []struct {
+		header string
+		err    string
+	}{
+		{"Content-Length: 99999999999999\r\n\r\n", "exceeds the maximum"},
+		{fmt.Sprintf("Content-Length: %d\r\n\r\n", maxContentLength+1), "exceeds the maximum"},
+		{"Content-Length: -1\r\n\r\n", "invalid Content-Length"},
+		{"Content-Length: x\r\n\r\n", "invalid Content-Length"},
+	}
+	for _, tt := range tests {
+		err := Serve(strings.NewReader(tt.header), ioutil.Discard)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.header, tt.err, err)
+		}
+	}
+}
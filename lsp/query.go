@@ -0,0 +1,195 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+// hover describes the name at pos: how a binding was declared or how a
+// builtin is called.
+func (d *document) hover(pos token.Pos) *Hover {
+	id := d.identAt(pos)
+	if id == nil {
+		return nil
+	}
+	var text string
+	if obj, ok := d.members[id]; ok {
+		// math.Max, unless math is a module bound by impor
+		if _, bound := d.uses[obj]; !bound {
+			text, _ = evaluator.Signature(obj.Value + "." + id.Value)
+		}
+	} else if b, ok := d.uses[id]; ok {
+		text = b.describe()
+	} else {
+		text, _ = evaluator.Signature(id.Value)
+	}
+	if text == "" {
+		return nil
+	}
+	r := d.span(id.Pos(), id.Token.End())
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```bilang\n" + text + "\n```"},
+		Range:    &r,
+	}
+}
+
+// describe returns the declaration of b, e.g. "var f = fn(a, b)".
+func (b *binding) describe() string {
+	switch b.kind {
+	case "var", "konst":
+		if fn, ok := b.value.(*ast.FunctionLiteral); ok {
+			return b.kind + " " + b.name + " = " + signature(fn)
+		}
+	case "impor":
+		return b.stmt.String()
+	}
+	return b.kind + " " + b.name
+}
+
+// signature returns how fn is called, e.g. "fn(a, b)".
+func signature(fn *ast.FunctionLiteral) string {
+	params := make([]string, len(fn.Parameters))
+	for i, p := range fn.Parameters {
+		params[i] = p.Value
+	}
+	return fn.TokenLiteral() + "(" + strings.Join(params, ", ") + ")"
+}
+
+// definition returns where the name at pos is bound.
+func (d *document) definition(pos token.Pos) *Location {
+	id := d.identAt(pos)
+	if id == nil {
+		return nil
+	}
+	b, ok := d.uses[id]
+	if !ok {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.span(b.tok.Pos(), b.tok.End())}
+}
+
+// symbols returns the globals of the document, the functions among them
+// with the var and konst they declare.
+func (d *document) symbols() []DocumentSymbol {
+	return d.symbolsOf(d.globals.order)
+}
+
+func (d *document) symbolsOf(bindings []*binding) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, b := range bindings {
+		if b.stmt == nil {
+			continue
+		}
+		sym := DocumentSymbol{
+			Name:           b.name,
+			Kind:           symbolVariable,
+			Range:          d.span(b.stmt.Pos(), b.end),
+			SelectionRange: d.span(b.tok.Pos(), b.tok.End()),
+		}
+		switch b.kind {
+		case "impor":
+			sym.Kind = symbolModule
+		case "konst":
+			sym.Kind = symbolConstant
+		}
+		if fn, ok := b.value.(*ast.FunctionLiteral); ok {
+			sym.Kind, sym.Detail = symbolFunction, signature(fn)
+			if len(b.children) > 0 {
+				sym.Children = d.symbolsOf(b.children)
+			}
+		}
+		symbols = append(symbols, sym)
+	}
+	return symbols
+}
+
+// completion offers what may follow the word before pos: after "math."
+// the math.* builtins, otherwise the keywords, builtins and the names
+// visible at pos.
+func (d *document) completion(pos token.Pos, builtins []string) []CompletionItem {
+	word := d.wordBefore(pos)
+	items := []CompletionItem{}
+	if dot := strings.LastIndex(word, "."); dot >= 0 {
+		for _, name := range builtins {
+			if strings.HasPrefix(name, word) {
+				sig, _ := evaluator.Signature(name)
+				items = append(items, CompletionItem{Label: name[dot+1:], Kind: completionFunction, Detail: sig})
+			}
+		}
+		return items
+	}
+
+	seen := make(map[string]bool)
+	add := func(item CompletionItem) {
+		if strings.HasPrefix(item.Label, word) && !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+	for sc := d.scopeAt(pos); sc != nil; sc = sc.parent {
+		for _, b := range sc.order {
+			kind := completionVariable
+			switch b.kind {
+			case "konst":
+				kind = completionConstant
+			case "impor":
+				kind = completionModule
+			}
+			if _, ok := b.value.(*ast.FunctionLiteral); ok {
+				kind = completionFunction
+			}
+			add(CompletionItem{Label: b.name, Kind: kind, Detail: b.describe()})
+		}
+	}
+	for _, word := range token.Keywords() {
+		add(CompletionItem{Label: word, Kind: completionKeyword, Detail: "keyword"})
+	}
+	for _, name := range builtins {
+		if dot := strings.Index(name, "."); dot >= 0 {
+			add(CompletionItem{Label: name[:dot], Kind: completionModule})
+			continue
+		}
+		sig, _ := evaluator.Signature(name)
+		add(CompletionItem{Label: name, Kind: completionFunction, Detail: sig})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
+
+// wordBefore returns the name, possibly qualified like math.Ma, that ends
+// at pos.
+func (d *document) wordBefore(pos token.Pos) string {
+	if pos.Line < 1 || pos.Line > len(d.lines) {
+		return ""
+	}
+	runes := []rune(d.lines[pos.Line-1])
+	end := pos.Col - 1
+	if end > len(runes) {
+		end = len(runes)
+	}
+	start := end
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	return string(runes[start:end])
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// scopeAt returns the innermost scope around pos.
+func (d *document) scopeAt(pos token.Pos) *scope {
+	found := d.globals
+	for _, sc := range d.scopes {
+		if sc.start.IsValid() && sc.start.Before(pos) && !sc.end.Before(pos) && found.start.Before(sc.start) {
+			found = sc
+		}
+	}
+	return found
+}
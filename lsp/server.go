@@ -0,0 +1,217 @@
+// Package lsp is a language server for Bilang, speaking the Language
+// Server Protocol over a pair of streams, usually stdio. It reports syntax
+// errors, describes builtins and bindings on hover, jumps to where a name
+// is bound, lists the symbols of a file and completes names.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/dedisuryadi/bilang/evaluator"
+)
+
+// ErrNoShutdown is returned by Serve when the client sends exit without
+// asking the server to shut down first.
+var ErrNoShutdown = errors.New("lsp: exit tanpa shutdown")
+
+// Serve answers the requests read from in on out until the client sends
+// exit or in is exhausted. Documents are synchronized in full.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{
+		conn:     newConn(in, out),
+		docs:     make(map[string]*document),
+		builtins: evaluator.NewRegistry().Names(),
+	}
+	return s.serve()
+}
+
+type server struct {
+	conn        *conn
+	docs        map[string]*document // open documents by URI
+	builtins    []string
+	initialized bool
+	shutdown    bool
+}
+
+// handlers are the methods the server answers, notifications return no
+// result.
+var handlers = map[string]func(s *server, params json.RawMessage) (interface{}, *rpcError){
+	"initialize":                  (*server).initialize,
+	"initialized":                 func(*server, json.RawMessage) (interface{}, *rpcError) { return nil, nil },
+	"shutdown":                    (*server).shutdownRequest,
+	"textDocument/didOpen":        (*server).didOpen,
+	"textDocument/didChange":      (*server).didChange,
+	"textDocument/didClose":       (*server).didClose,
+	"textDocument/hover":          (*server).hover,
+	"textDocument/definition":     (*server).definition,
+	"textDocument/documentSymbol": (*server).documentSymbol,
+	"textDocument/completion":     (*server).completion,
+}
+
+func (s *server) serve() error {
+	for {
+		msg, err := s.conn.read()
+		if rerr, ok := err.(*rpcError); ok {
+			if err := s.conn.reply(nil, nil, rerr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+		if msg.Method == "" {
+			// a response, the server sends no requests
+			continue
+		}
+
+		result, rerr := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		if err := s.conn.reply(msg.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(msg *message) (interface{}, *rpcError) {
+	switch {
+	case !s.initialized && msg.Method != "initialize":
+		return nil, &rpcError{Code: codeServerNotInitialized, Message: "server belum diinisialisasi"}
+	case s.shutdown:
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server sudah shutdown"}
+	}
+	handler, ok := handlers[msg.Method]
+	if !ok {
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "method tidak dikenal " + msg.Method}
+	}
+	return handler(s, msg.Params)
+}
+
+func decode(params json.RawMessage, v interface{}) *rpcError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *server) initialize(json.RawMessage) (interface{}, *rpcError) {
+	if s.initialized {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server sudah diinisialisasi"}
+	}
+	s.initialized = true
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:       TextDocumentSyncOptions{OpenClose: true, Change: syncFull},
+			HoverProvider:          true,
+			DefinitionProvider:     true,
+			DocumentSymbolProvider: true,
+			CompletionProvider:     CompletionOptions{TriggerCharacters: []string{"."}},
+		},
+		ServerInfo: ServerInfo{Name: "bilang"},
+	}, nil
+}
+
+func (s *server) shutdownRequest(json.RawMessage) (interface{}, *rpcError) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *server) didOpen(params json.RawMessage) (interface{}, *rpcError) {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	s.open(newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text))
+	return nil, nil
+}
+
+func (s *server) didChange(params json.RawMessage) (interface{}, *rpcError) {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	// with full synchronization the last change holds the whole text
+	text := p.ContentChanges[len(p.ContentChanges)-1].Text
+	s.open(newDocument(p.TextDocument.URI, p.TextDocument.Version, text))
+	return nil, nil
+}
+
+func (s *server) didClose(params json.RawMessage) (interface{}, *rpcError) {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	delete(s.docs, p.TextDocument.URI)
+	s.publish(PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	return nil, nil
+}
+
+// open keeps d and publishes its syntax errors.
+func (s *server) open(d *document) {
+	s.docs[d.uri] = d
+	s.publish(PublishDiagnosticsParams{URI: d.uri, Version: d.version, Diagnostics: d.diagnostics()})
+}
+
+func (s *server) publish(p PublishDiagnosticsParams) {
+	_ = s.conn.call(nil, "textDocument/publishDiagnostics", p)
+}
+
+// at decodes the position of a request, d is nil when the document is
+// not open.
+func (s *server) at(params json.RawMessage) (d *document, pos TextDocumentPositionParams, err *rpcError) {
+	if err := decode(params, &pos); err != nil {
+		return nil, pos, err
+	}
+	return s.docs[pos.TextDocument.URI], pos, nil
+}
+
+func (s *server) hover(params json.RawMessage) (interface{}, *rpcError) {
+	d, p, err := s.at(params)
+	if d == nil {
+		return nil, err
+	}
+	return d.hover(d.pos(p.Position)), nil
+}
+
+func (s *server) definition(params json.RawMessage) (interface{}, *rpcError) {
+	d, p, err := s.at(params)
+	if d == nil {
+		return nil, err
+	}
+	return d.definition(d.pos(p.Position)), nil
+}
+
+func (s *server) documentSymbol(params json.RawMessage) (interface{}, *rpcError) {
+	var p DocumentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	if d, ok := s.docs[p.TextDocument.URI]; ok {
+		return d.symbols(), nil
+	}
+	return []DocumentSymbol{}, nil
+}
+
+func (s *server) completion(params json.RawMessage) (interface{}, *rpcError) {
+	d, p, err := s.at(params)
+	if d == nil {
+		return CompletionList{Items: []CompletionItem{}}, err
+	}
+	return CompletionList{Items: d.completion(d.pos(p.Position), s.builtins)}, nil
+}
@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -27,27 +30,37 @@ const (
 	INDEX       // array[index]
 )
 
-var err error
 var precedences = map[token.Type]uint8{
-	token.ASSIGN:   ASSIGN,
-	token.PIPE:     PIPE,
-	token.FATARROW: FATARROW,
-	token.OR:       OR,
-	token.AND:      AND,
-	token.EQ:       EQUALS,
-	token.NEQ:      EQUALS,
-	token.LT:       LESSGREATER,
-	token.LTE:      LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.GTE:      LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.MOD:      PRODUCT,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.DOT:      CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:    ASSIGN,
+	token.PIPE:      PIPE,
+	token.FATARROW:  FATARROW,
+	token.OR:        OR,
+	token.AND:       AND,
+	token.EQ:        EQUALS,
+	token.NEQ:       EQUALS,
+	token.MATCH:     EQUALS,
+	token.NOT_MATCH: EQUALS,
+	token.LT:        LESSGREATER,
+	token.LTE:       LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.GTE:       LESSGREATER,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.MOD:       PRODUCT,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.LPAREN:    CALL,
+	token.DOT:       CALL,
+	token.LBRACKET:  INDEX,
+}
+
+// Precedence returns how tightly the infix operator t binds its operands,
+// LOWEST when t is not an infix operator.
+func Precedence(t token.Type) uint8 {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
 }
 
 type (
@@ -55,9 +68,24 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// ParseError is a syntax error found at Pos. Expected and Found describe
+// the tokens involved when the error is an unexpected token, e.g.
+// "identifier" and "'='".
+type ParseError struct {
+	Pos      token.Pos
+	Msg      string
+	Expected string
+	Found    string
+}
+
+func (e ParseError) Error() string { return e.Pos.String() + ": " + e.Msg }
+
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	file   string
+	errors []ParseError
+	blocks int       // blocks being parsed, see synchronize
+	synced token.Pos // where the last synchronize resumed, see addError
 
 	curToken  token.Token
 	peekToken token.Token
@@ -66,19 +94,26 @@ type Parser struct {
 	infixParseFns  map[token.Type]infixParseFn
 }
 
+// Error renders every error with its position and an excerpt of the
+// offending source line.
 func (p *Parser) Error() string {
-	return strings.Join(p.errors, "\n")
+	msgs := make([]string, len(p.errors))
+	for i, e := range p.errors {
+		msgs[i] = token.FormatError(p.file, p.l.Input(), e.Pos, e.Msg)
+	}
+	return strings.Join(msgs, "\n")
 }
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []ParseError{},
 	}
 
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
-	p.registerPrefix(token.INT, p.parseFloatLiteral)
+	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.BENAR, p.parseBoolean)
@@ -89,12 +124,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.PILAH, p.parsePilahLiteral)
 	p.registerPrefix(token.UNDERSCORE, p.parseWildcard)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE, p.parseInterpolatedString)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	p.registerPrefix(token.REGEX, p.parseRegExLiteralExpression)
 	p.registerPrefix(token.USAI, p.parseBreakExpression)
 	p.registerPrefix(token.LANJUT, p.parseContinueExpression)
 	p.registerPrefix(token.TIAP, p.parseLoopExpression)
+	p.registerPrefix(token.COBA, p.parseCobaExpression)
 
 	p.infixParseFns = make(map[token.Type]infixParseFn)
 	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
@@ -106,6 +143,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NEQ, p.parseInfixExpression)
+	p.registerInfix(token.MATCH, p.parseInfixExpression)
+	p.registerInfix(token.NOT_MATCH, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.LTE, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
@@ -123,32 +162,160 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
-func (p *Parser) appendPosition() {
-	p.errors = append(p.errors, fmt.Sprintf("pada baris %d dan kolom %d", p.curToken.Line, p.curToken.Col))
+// SetFile names the source being parsed, it prefixes every error message.
+func (p *Parser) SetFile(name string) {
+	p.file = name
 }
 
-func (p *Parser) ParseProgram() (prog *ast.Program, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			p.appendPosition()
-			err = p
+// bailout unwinds the parser to the statement being parsed after a syntax
+// error, see parseStatementOrSkip.
+type bailout struct{}
+
+// errorAt records a syntax error and abandons the current statement.
+func (p *Parser) errorAt(pos token.Pos, format string, a ...interface{}) {
+	p.addError(ParseError{Pos: pos, Msg: fmt.Sprintf(format, a...)})
+	panic(bailout{})
+}
+
+// unexpected records that tok was found where expected should be and
+// abandons the current statement. An ILLEGAL token was already reported
+// by the lexer.
+func (p *Parser) unexpected(tok token.Token, expected string) {
+	if tok.Type != token.ILLEGAL {
+		found := describe(tok)
+		p.addError(ParseError{Pos: tok.Pos(), Msg: "expected " + expected + ", found " + found, Expected: expected, Found: found})
+	}
+	panic(bailout{})
+}
+
+// addError records e unless it is a consequence of recovering from an
+// earlier error: at or before where synchronize resumed, or where an
+// error was recorded already.
+func (p *Parser) addError(e ParseError) {
+	if !p.synced.Before(e.Pos) {
+		return
+	}
+	for _, prev := range p.errors {
+		if prev.Pos == e.Pos {
+			return
 		}
-	}()
-	prog = &ast.Program{}
+	}
+	p.errors = append(p.errors, e)
+}
+
+// describe names a token found in the source, with the text of
+// identifiers and literals.
+func describe(tok token.Token) string {
+	switch tok.Type {
+	case token.IDENT, token.INT, token.FLOAT:
+		return token.Describe(tok.Type) + " " + tok.Literal
+	case token.STRING:
+		return token.Describe(tok.Type) + " " + strconv.Quote(tok.Literal)
+	}
+	return token.Describe(tok.Type)
+}
+
+// ParseFile reads and parses the Bilang source file at path.
+func ParseFile(path string) (*ast.Program, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := New(lexer.New(string(src)))
+	p.SetFile(path)
+	return p.ParseProgram()
+}
+
+// ParseProgram parses the whole input. After a syntax error it skips to
+// the next statement and carries on, so every error is reported at once:
+// the error returned is the parser itself, see Errors. The program then
+// holds the statements that did parse.
+func (p *Parser) ParseProgram() (*ast.Program, error) {
+	prog := &ast.Program{}
 	prog.Statements = []ast.Statement{}
 	for !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseStatementOrSkip()
 		if stmt != nil {
 			prog.Statements = append(prog.Statements, stmt)
 		}
-		p.nextToken()
-		if len(p.errors) > 0 {
-			p.appendPosition()
-			return nil, p
+	}
+	prog.Comments = p.l.Comments()
+	if len(p.errors) > 0 {
+		sort.SliceStable(p.errors, func(i, j int) bool {
+			a, b := p.errors[i].Pos, p.errors[j].Pos
+			return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+		})
+		return prog, p
+	}
+	return prog, nil
+}
+
+// parseStatementOrSkip parses a statement and moves to the token after
+// it. On a syntax error it returns nil, leaving the parser on the start of
+// the next statement.
+func (p *Parser) parseStatementOrSkip() (stmt ast.Statement) {
+	start := p.curToken.Pos()
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+			stmt = nil
+			// the error is at a keyword starting the next statement,
+			// which is kept
+			if p.startsStatement() && p.curToken.Pos() != start {
+				return
+			}
+			p.synchronize()
+			p.synced = p.curToken.Pos()
+			p.nextToken()
+		}
+	}()
+	stmt = p.parseStatement()
+	p.nextToken()
+	return stmt
+}
+
+// startsStatement reports whether the current token is a keyword that
+// starts a statement, where synchronize stops.
+func (p *Parser) startsStatement() bool {
+	switch p.curToken.Type {
+	case token.VAR, token.KONST, token.PILIH, token.LEMPAR, token.IMPOR, token.EKSPOR:
+		return true
+	}
+	return false
+}
+
+// synchronize skips the rest of a statement with a syntax error: up to a
+// `;`, or up to a keyword that starts a statement or the `}` closing the
+// enclosing block, outside of any braces the statement opened.
+func (p *Parser) synchronize() {
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+		case token.SEMICOLON:
+			if depth <= 0 {
+				return
+			}
+		}
+		if depth <= 0 {
+			switch p.peekToken.Type {
+			case token.VAR, token.KONST, token.PILIH, token.LEMPAR, token.IMPOR, token.EKSPOR, token.EOF:
+				return
+			case token.RBRACE:
+				if p.blocks > 0 {
+					return
+				}
+			}
 		}
+		p.nextToken()
 	}
-	return
 }
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.VAR:
@@ -157,6 +324,12 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseKonstStatement()
 	case token.PILIH:
 		return p.parsePilihStatement()
+	case token.LEMPAR:
+		return p.parseLemparStatement()
+	case token.IMPOR:
+		return p.parseImporStatement()
+	case token.EKSPOR:
+		return p.parseEksporStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -174,16 +347,10 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 func (p *Parser) peekPrecedence() uint8 {
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return Precedence(p.peekToken.Type)
 }
 func (p *Parser) curPrecedence() uint8 {
-	if p, ok := precedences[p.curToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return Precedence(p.curToken.Type)
 }
 
 func (p *Parser) registerPrefix(tokenType token.Type, fn prefixParseFn) {
@@ -193,19 +360,23 @@ func (p *Parser) registerInfix(tokenType token.Type, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-func (p *Parser) Errors() []string {
+// Errors returns the syntax errors found by ParseProgram, in source order.
+func (p *Parser) Errors() []ParseError {
 	return p.errors
 }
+
 func (p *Parser) peekError(t token.Type) {
-	msg := fmt.Sprintf("expected next token to be:%s got:%s", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.unexpected(p.peekToken, token.Describe(t))
 }
 
+// nextToken advances a token. Lexer errors are recorded without giving up
+// on the statement, the ILLEGAL token stops it once it is reached.
 func (p *Parser) nextToken() {
+	var err error
 	p.curToken = p.peekToken
 	p.peekToken, err = p.l.NextToken()
 	if err != nil {
-		p.errors = append(p.errors, err.Error())
+		p.addError(ParseError{Pos: p.peekToken.Pos(), Msg: err.Error()})
 	}
 }
 
@@ -244,11 +415,7 @@ func (p *Parser) parseExpression(precedence uint8) ast.Expression {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.Type) {
-	if t == token.EOF {
-		return
-	}
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.unexpected(p.curToken, "expression")
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -308,12 +475,31 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+func (p *Parser) parseIntegerLiteral() ast.Expression {
+	lit := &ast.IntegerLiteral{Token: p.curToken}
+	// the lexer already validated the digits, base 0 handles the 0x/0o/0b prefixes
+	value, err := strconv.ParseInt(strings.ReplaceAll(p.curToken.Literal, "_", ""), 0, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
+		if errors.Is(err, strconv.ErrRange) {
+			msg = fmt.Sprintf("integer literal %q overflows INTEGER", p.curToken.Literal)
+		}
+		p.errorAt(p.curToken.Pos(), "%s", msg)
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
 func (p *Parser) parseFloatLiteral() ast.Expression {
 	lit := &ast.FloatLiteral{Token: p.curToken}
-	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	value, err := strconv.ParseFloat(strings.ReplaceAll(p.curToken.Literal, "_", ""), 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		if errors.Is(err, strconv.ErrRange) {
+			msg = fmt.Sprintf("float literal %q overflows FLOAT", p.curToken.Literal)
+		}
+		p.errorAt(p.curToken.Pos(), "%s", msg)
 		return nil
 	}
 	lit.Value = value
@@ -333,14 +519,84 @@ func (p *Parser) parsePilihStatement() *ast.PilihStatement {
 	return stmt
 }
 
+func (p *Parser) parseLemparStatement() *ast.LemparStatement {
+	stmt := &ast.LemparStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseImporStatement() ast.Statement {
+	stmt := &ast.ImporStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseEksporStatement() ast.Statement {
+	stmt := &ast.EksporStatement{Token: p.curToken}
+	if p.blocks > 0 {
+		p.errorAt(p.curToken.Pos(), "ekspor is only allowed at the top level of a module")
+	}
+
+	p.nextToken()
+	switch p.curToken.Type {
+	case token.VAR:
+		vs := p.parseVarStatement()
+		if vs == nil {
+			return nil
+		}
+		stmt.Statement = vs
+	case token.KONST:
+		ks := p.parseKonstStatement()
+		if ks == nil {
+			return nil
+		}
+		stmt.Statement = ks
+	default:
+		msg := fmt.Sprintf("ekspor must be followed by var or konst, got %s", p.curToken.Type)
+		p.errorAt(p.curToken.Pos(), "%s", msg)
+		return nil
+	}
+
+	return stmt
+}
+
+// nameFunction names a function literal after the variable it is bound
+// to, so a traceback can say faktorial(3) instead of fn@1:17(3).
+func nameFunction(value ast.Expression, name string) {
+	if fn, ok := value.(*ast.FunctionLiteral); ok && fn.Name == "" {
+		fn.Name = name
+	}
+}
+
 func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
 	ident, ok := left.(*ast.Identifier)
 	if !ok {
+		p.errorAt(p.curToken.Pos(), "cannot assign to %s", left.String())
 		return nil
 	}
 
 	stmt := &ast.VarStatement{
-		Token: token.Token{Type: token.VAR, Literal: token.VAR},
+		Token: token.Token{Type: token.VAR, Literal: token.VAR, Line: ident.Token.Line, Col: ident.Token.Col},
 		Name:  &ast.Identifier{Token: ident.Token, Value: ident.TokenLiteral()},
 		Value: nil,
 	}
@@ -351,6 +607,7 @@ func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
 
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
+	nameFunction(stmt.Value, stmt.Name.Value)
 
 	for p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -373,6 +630,7 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
+	nameFunction(stmt.Value, stmt.Name.Value)
 
 	for p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -395,6 +653,7 @@ func (p *Parser) parseKonstStatement() ast.Statement {
 
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
+	nameFunction(stmt.Value, stmt.Name.Value)
 
 	for p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -466,21 +725,51 @@ func (p *Parser) parseJikaExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseCobaExpression() ast.Expression {
+	expression := &ast.CobaExpression{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	if !p.expectPeek(token.TANGKAP) {
+		return nil
+	}
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		expression.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Handler = p.parseBlockStatement()
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	p.blocks++
+	defer func() { p.blocks-- }()
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) {
-		stmt := p.parseStatement()
+		if p.curTokenIs(token.EOF) {
+			p.unexpected(p.curToken, "'}'")
+		}
+		stmt := p.parseStatementOrSkip()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
-		p.nextToken()
-		if len(p.errors) > 0 {
-			panic(p)
-		}
 	}
 
 	return block
@@ -489,6 +778,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parsePilahLiteral() ast.Expression {
 	lit := &ast.PilahExpression{Token: p.curToken}
 	lit.Target = p.parsePilahTarget()
+	lit.Lbrace = p.curToken
 
 	conditions := make([]*ast.ExpressionStatement, 0)
 	expressions := make([]ast.Expression, 0)
@@ -500,7 +790,7 @@ func (p *Parser) parsePilahLiteral() ast.Expression {
 		conditions = append(conditions, cond)
 
 		if !p.expectPeek(token.ARROW) {
-			panic("must small arrow")
+			return nil
 		}
 
 		p.nextToken()
@@ -552,6 +842,7 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 func (p *Parser) parseFatArrowLiteral(param ast.Expression) ast.Expression {
 	ident, ok := param.(*ast.Identifier)
 	if !ok || ident == nil {
+		p.errorAt(p.curToken.Pos(), "parameter of => must be an identifier, got %s", param.String())
 		return nil
 	}
 
@@ -562,7 +853,10 @@ func (p *Parser) parseFatArrowLiteral(param ast.Expression) ast.Expression {
 
 	p.nextToken()
 
+	// the body is a function's, like a block
+	p.blocks++
 	stmt := p.parseStatement()
+	p.blocks--
 	lit.Body = &ast.BlockStatement{
 		Token:      p.curToken,
 		Statements: []ast.Statement{stmt},
@@ -579,14 +873,18 @@ func (p *Parser) parseFunctionParameters(end token.Type) []*ast.Identifier {
 		return identifiers
 	}
 
-	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
 	}
@@ -632,6 +930,62 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseInterpolatedString splits a TEMPLATE token into its text and the
+// expressions embedded with ${}, which are parsed by a parser of their own
+// positioned where they sit in the source.
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	segments, err := lexer.Segments(p.curToken.Literal)
+	if err != nil {
+		p.errorAt(p.curToken.Pos(), "%s", err)
+		return nil
+	}
+	for _, seg := range segments {
+		if !seg.IsExpr {
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: seg.Text})
+			continue
+		}
+		str.Parts = append(str.Parts, p.parseEmbedded(seg.Expr, p.offsetPos(seg.Offset)))
+	}
+	return str
+}
+
+// parseEmbedded parses the source of an ${expression} found at pos.
+func (p *Parser) parseEmbedded(src string, pos token.Pos) ast.Expression {
+	if strings.TrimSpace(src) == "" {
+		p.errorAt(pos, "empty interpolation ${}")
+	}
+	sub := New(lexer.NewAt(src, pos.Line, pos.Col))
+	defer func() {
+		for _, e := range sub.errors {
+			p.addError(e)
+		}
+	}()
+	exp := sub.parseExpression(LOWEST)
+	if !sub.peekTokenIs(token.EOF) {
+		sub.unexpected(sub.peekToken, "'}'")
+	}
+	if len(sub.errors) > 0 {
+		panic(bailout{})
+	}
+	return exp
+}
+
+// offsetPos is the position of the byte at offset in the content of the
+// current string token, just past its opening quote.
+func (p *Parser) offsetPos(offset int) token.Pos {
+	line, col := p.curToken.Line, p.curToken.Col+1
+	for _, ch := range p.curToken.Literal[:offset] {
+		if ch == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return token.Pos{Line: line, Col: col}
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 	array.Elements = p.parseExpressionList(token.RBRACKET)
@@ -2,12 +2,15 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/dedisuryadi/bilang/evaluator"
 
 	"github.com/dedisuryadi/bilang/ast"
 	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/token"
 )
 
 func TestVarStatements(t *testing.T) {
@@ -131,8 +134,8 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 
 	t.Errorf("parser has %d errors", len(errors))
-	for _, msg := range errors {
-		t.Errorf("parser error: %q", msg)
+	for _, e := range errors {
+		t.Errorf("parser error: %s", e)
 	}
 	t.FailNow()
 }
@@ -202,7 +205,7 @@ func TestIdentifierExpression(t *testing.T) {
 	}
 }
 
-func TestFloatLiteralExpression(t *testing.T) {
+func TestIntegerLiteralExpression(t *testing.T) {
 	input := "5;"
 	l := lexer.New(input)
 	p := New(l)
@@ -218,18 +221,85 @@ func TestFloatLiteralExpression(t *testing.T) {
 	if !ok {
 		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
 	}
-	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	literal, ok := stmt.Expression.(*ast.IntegerLiteral)
 	if !ok {
-		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
 	}
 	if literal.Value != 5 {
-		t.Errorf("literal.Value not %d. got=%G", 5, literal.Value)
+		t.Errorf("literal.Value not %d. got=%d", 5, literal.Value)
 	}
 	if literal.TokenLiteral() != "5" {
 		t.Errorf("literal.TokenLiteral not %s. got=%s", "5", literal.TokenLiteral())
 	}
 }
 
+func TestNumberLiteralExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"0xFF", int64(255)},
+		{"0o755", int64(493)},
+		{"0b1010", int64(10)},
+		{"1_000_000", int64(1000000)},
+		{"3.14", 3.14},
+		{"1e-9", 1e-9},
+		{"2.5E3", 2500.0},
+		{"1_000.5", 1000.5},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program, err := p.ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		switch expected := tt.expected.(type) {
+		case int64:
+			literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+			if !ok {
+				t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+			}
+			if literal.Value != expected {
+				t.Errorf("literal.Value not %d. got=%d", expected, literal.Value)
+			}
+		case float64:
+			literal, ok := stmt.Expression.(*ast.FloatLiteral)
+			if !ok {
+				t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+			}
+			if literal.Value != expected {
+				t.Errorf("literal.Value not %G. got=%G", expected, literal.Value)
+			}
+		}
+		if stmt.Expression.TokenLiteral() != tt.input {
+			t.Errorf("TokenLiteral not %s. got=%s", tt.input, stmt.Expression.TokenLiteral())
+		}
+	}
+}
+
+func TestNumberLiteralOverflow(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"9223372036854775808", `integer literal "9223372036854775808" overflows INTEGER`},
+		{"0xFFFFFFFFFFFFFFFFF", `integer literal "0xFFFFFFFFFFFFFFFFF" overflows INTEGER`},
+		{"1e400", `float literal "1e400" overflows FLOAT`},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		if _, err := p.ParseProgram(); err == nil {
+			t.Fatalf("expected error for %q", tt.input)
+		}
+		if p.Errors()[0].Msg != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, p.Errors()[0].Msg)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -314,12 +384,27 @@ func TestLoopExpressions(t *testing.T) {
 			t.Errorf("loop iter error. expected=%v got=%v", tt.iter, loop.Iter.String())
 		}
 
-		env := evaluator.NewEnvironment()
-		script := evaluator.NewScript()
-		script.Eval(program, env)
+		evaluator.NewScript().Run(program)
 	}
 }
 
+func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
+	integ, ok := il.(*ast.IntegerLiteral)
+	if !ok {
+		t.Errorf("il not *ast.IntegerLiteral. got=%T", il)
+		return false
+	}
+	if integ.Value != value {
+		t.Errorf("integ.Value not %d. got=%d", value, integ.Value)
+		return false
+	}
+	if integ.TokenLiteral() != fmt.Sprintf("%d", value) {
+		t.Errorf("integ.TokenLiteral not %d. got=%s", value, integ.TokenLiteral())
+		return false
+	}
+	return true
+}
+
 func testFloatLiteral(t *testing.T, il ast.Expression, value float64) bool {
 	integ, ok := il.(*ast.FloatLiteral)
 	if !ok {
@@ -406,6 +491,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a + add(b * c) + d",
 			"((a + add((b * c))) + d)",
 		},
+		{
+			"a + b =~ /x/ && c !~ ~y~",
+			"(((a + b) =~ x) && (c !~ y))",
+		},
 		{
 			"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))",
 			"add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))",
@@ -548,11 +637,13 @@ func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{
 	case bool:
 		return testBooleanLiteral(t, exp, v)
 	case int:
-		return testFloatLiteral(t, exp, float64(v))
+		return testIntegerLiteral(t, exp, int64(v))
 	case int64:
-		return testFloatLiteral(t, exp, float64(v))
+		return testIntegerLiteral(t, exp, v)
 	case float32:
 		return testFloatLiteral(t, exp, float64(v))
+	case float64:
+		return testFloatLiteral(t, exp, v)
 	case string:
 		return testIdentifier(t, exp, v)
 	}
@@ -763,6 +854,39 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestFunctionLiteralName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var tambah = fn(x, y) { x + y };", "tambah"},
+		{"konst ganda = x => x * 2;", "ganda"},
+		{"kurang = fn(x, y) { x - y };", "kurang"},
+		{"fn(x) { x };", ""},
+		{"var f = g(fn(x) { x });", ""},
+	}
+	for _, tt := range tests {
+		program, err := New(lexer.New(tt.input)).ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var name string
+		found := false
+		ast.Inspect(program, func(n ast.Node) bool {
+			if fn, ok := n.(*ast.FunctionLiteral); ok && !found {
+				name, found = fn.Name, true
+			}
+			return true
+		})
+		if !found {
+			t.Fatalf("%q: no function literal", tt.input)
+		}
+		if name != tt.expected {
+			t.Errorf("%q: wrong name. want=%q, got=%q", tt.input, tt.expected, name)
+		}
+	}
+}
+
 func TestPilahLiteralParsing(t *testing.T) {
 	input := `
 pilah 3 {
@@ -937,6 +1061,60 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestInterpolatedString(t *testing.T) {
+	input := "var a = 1;\n\"x = ${a + 1}, \\${b} ${f(\"${a}\")}\""
+	p := New(lexer.New(input))
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := program.Statements[1].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+	if str.String() != "x = ${(a + 1)}, ${b} ${f(${a})}" {
+		t.Errorf("str.String() wrong. got=%q", str.String())
+	}
+	if len(str.Parts) != 4 {
+		t.Fatalf("str.Parts does not contain 4 parts. got=%d", len(str.Parts))
+	}
+	if text, ok := str.Parts[0].(*ast.StringLiteral); !ok || text.Value != "x = " {
+		t.Errorf("str.Parts[0] wrong. got=%#v", str.Parts[0])
+	}
+	if !testInfixExpression(t, str.Parts[1], "a", "+", 1) {
+		return
+	}
+	if pos := str.Parts[1].(*ast.InfixExpression).Left.Pos(); pos.Line != 2 || pos.Col != 8 {
+		t.Errorf("embedded expression at wrong position. got=%d:%d", pos.Line, pos.Col)
+	}
+	if _, ok := str.Parts[3].(*ast.CallExpression); !ok {
+		t.Errorf("str.Parts[3] not *ast.CallExpression. got=%T", str.Parts[3])
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a ${} b"`, "1:6: empty interpolation ${}"},
+		{"1;\n\"a ${1 +} b\"", "2:9: expected expression, found end of input"},
+		{`"${a b}"`, "1:6: expected '}', found identifier b"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		_, err := p.ParseProgram()
+		if err == nil {
+			t.Errorf("%q: expected a parse error", tt.input)
+			continue
+		}
+		if got := p.Errors()[0].Error(); got != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 	l := lexer.New(input)
@@ -954,7 +1132,7 @@ func TestParsingArrayLiterals(t *testing.T) {
 	if len(array.Elements) != 3 {
 		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
 	}
-	testFloatLiteral(t, array.Elements[0], 1)
+	testIntegerLiteral(t, array.Elements[0], 1)
 	testInfixExpression(t, array.Elements[1], 2, "*", 2)
 	testInfixExpression(t, array.Elements[2], 3, "+", 3)
 }
@@ -998,7 +1176,7 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	if len(hash.Pairs) != 3 {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
-	expected := map[string]float64{
+	expected := map[string]int64{
 		"one":   1,
 		"two":   2,
 		"three": 3,
@@ -1009,7 +1187,7 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
 		}
 		expectedValue := expected[literal.String()]
-		testFloatLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, value, expectedValue)
 	}
 }
 
@@ -1153,3 +1331,333 @@ func TestParsingPipeExpression(t *testing.T) {
 		t.Fatalf("expected=b got=%v", lx.Right.String())
 	}
 }
+
+func TestParsingComments(t *testing.T) {
+	input := `
+// tambah dua angka
+var tambah = fn(a, b) {
+	a + b /* jumlah */
+}
+tambah(1, 2) // hasil 3
+`
+	l := lexer.New(input)
+	p := New(l)
+
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+	if len(program.Comments) != 3 {
+		t.Fatalf("program.Comments does not contain 3 comments. got=%d", len(program.Comments))
+	}
+	if program.Comments[0].Literal != "// tambah dua angka" {
+		t.Errorf("program.Comments[0] wrong. got=%q", program.Comments[0].Literal)
+	}
+}
+
+func TestCobaExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedParam string
+	}{
+		{`coba { x } tangkap (e) { lempar e }`, "e"},
+		{`coba { x } tangkap { 0 }`, ""},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program, err := p.ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.CobaExpression)
+		if !ok {
+			t.Fatalf("exp not *ast.CobaExpression. got=%T", stmt.Expression)
+		}
+		if len(exp.Body.Statements) != 1 {
+			t.Errorf("body is not 1 statement. got=%d", len(exp.Body.Statements))
+		}
+		if !testIdentifier(t, exp.Body.Statements[0].(*ast.ExpressionStatement).Expression, "x") {
+			return
+		}
+		if tt.expectedParam == "" {
+			if exp.Param != nil {
+				t.Errorf("exp.Param is not nil. got=%s", exp.Param)
+			}
+		} else if !testIdentifier(t, exp.Param, tt.expectedParam) {
+			return
+		}
+		if len(exp.Handler.Statements) != 1 {
+			t.Errorf("handler is not 1 statement. got=%d", len(exp.Handler.Statements))
+		}
+	}
+}
+
+func TestLemparStatement(t *testing.T) {
+	l := lexer.New(`lempar "gagal";`)
+	p := New(l)
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LemparStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.LemparStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Value.String() != "gagal" {
+		t.Errorf("stmt.Value not %q. got=%q", "gagal", stmt.Value.String())
+	}
+}
+
+func TestImporEksporParsing(t *testing.T) {
+	input := `
+impor "lib/mat.bi"
+impor m "lib/mat.bi";
+ekspor var a = 1
+ekspor konst b = 2
+`
+	l := lexer.New(input)
+	p := New(l)
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program.Statements does not contain 4 statements. got=%d", len(program.Statements))
+	}
+	imp, ok := program.Statements[0].(*ast.ImporStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ImporStatement. got=%T", program.Statements[0])
+	}
+	if imp.Alias != nil || imp.Path.Value != "lib/mat.bi" {
+		t.Errorf("wrong impor. got=%s", imp)
+	}
+	alias := program.Statements[1].(*ast.ImporStatement)
+	if !testIdentifier(t, alias.Alias, "m") {
+		return
+	}
+	exp, ok := program.Statements[2].(*ast.EksporStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.EksporStatement. got=%T", program.Statements[2])
+	}
+	if !testVarStatement(t, exp.Statement, "a") {
+		return
+	}
+	exp = program.Statements[3].(*ast.EksporStatement)
+	if !testKonstStatement(t, exp.Statement, "b") {
+		return
+	}
+}
+
+func TestEksporRequiresDeclaration(t *testing.T) {
+	p := New(lexer.New(`ekspor 1 + 2`))
+	if _, err := p.ParseProgram(); err == nil {
+		t.Fatal("expected error")
+	}
+	if p.Errors()[0].Msg != "ekspor must be followed by var or konst, got INT" {
+		t.Errorf("wrong error. got=%q", p.Errors()[0].Msg)
+	}
+}
+
+func TestEksporOnlyAtTopLevel(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   token.Pos
+	}{
+		{"var f = fn() { ekspor var z = 3 }", token.Pos{Line: 1, Col: 16}},
+		{"jika (benar) {\n    ekspor konst z = 3\n}", token.Pos{Line: 2, Col: 5}},
+		{"var f = x => ekspor var z = x", token.Pos{Line: 1, Col: 14}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		if _, err := p.ParseProgram(); err == nil {
+			t.Errorf("%q: expected error", tt.input)
+			continue
+		}
+		e := p.Errors()[0]
+		if e.Msg != "ekspor is only allowed at the top level of a module" || e.Pos != tt.pos {
+			t.Errorf("%q: wrong error. got=%q at %s", tt.input, e.Msg, e.Pos)
+		}
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	tests := []struct {
+		input     string
+		line, col int
+	}{
+		{"  x + y * 2", 1, 3},
+		{"\nf(1, 2)", 2, 1},
+		{"x => x * 2", 1, 1},
+		{"a.b()", 1, 1},
+		{"arr[0]", 1, 1},
+		{"a |> f", 1, 1},
+		{"var a = 1", 1, 1},
+		{"\n\n   -5", 3, 4},
+		{`fn(a) { a }`, 1, 1},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program, _ := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		expected := token.Pos{Line: tt.line, Col: tt.col}
+		stmt := program.Statements[0]
+		if es, ok := stmt.(*ast.ExpressionStatement); ok {
+			if pos := es.Expression.Pos(); pos != expected {
+				t.Errorf("%q: expression position wrong. expected=%s, got=%s", tt.input, expected, pos)
+			}
+		}
+		if pos := stmt.Pos(); pos != expected {
+			t.Errorf("%q: statement position wrong. expected=%s, got=%s", tt.input, expected, pos)
+		}
+	}
+}
+
+func TestParseErrorFormat(t *testing.T) {
+	p := New(lexer.New("var x = 1;\nvar = 5;"))
+	p.SetFile("main.bi")
+	_, err := p.ParseProgram()
+	if err == nil {
+		t.Fatalf("expected a parse error")
+	}
+	expected := "main.bi:2:5: expected identifier, found '='\n" +
+		"    var = 5;\n" +
+		"        ^"
+	if err.Error() != expected {
+		t.Errorf("wrong error.\nexpected=%q\ngot=%q", expected, err.Error())
+	}
+}
+
+func TestParseErrorColumnsCountRunes(t *testing.T) {
+	p := New(lexer.New("var s = \"héllo\" + ;"))
+	_, err := p.ParseProgram()
+	if err == nil {
+		t.Fatalf("expected a parse error")
+	}
+	expected := "1:19: expected expression, found ';'\n" +
+		"    var s = \"héllo\" + ;\n" +
+		"                      ^"
+	if err.Error() != expected {
+		t.Errorf("wrong error.\nexpected=%q\ngot=%q", expected, err.Error())
+	}
+}
+
+func TestParserRecoversFromErrors(t *testing.T) {
+	input := `var = 5;
+var y = 2;
+konst 3;
+var z = (1 + ;
+var a = {1: 2;
+var b = 3;
+konst = 1; var c = ); var d = 4;`
+	p := New(lexer.New(input))
+	program, err := p.ParseProgram()
+	if err == nil {
+		t.Fatalf("expected parse errors")
+	}
+
+	expected := []ParseError{
+		{Pos: token.Pos{Line: 1, Col: 5}, Msg: "expected identifier, found '='", Expected: "identifier", Found: "'='"},
+		{Pos: token.Pos{Line: 3, Col: 7}, Msg: "expected identifier, found integer 3", Expected: "identifier", Found: "integer 3"},
+		{Pos: token.Pos{Line: 4, Col: 14}, Msg: "expected expression, found ';'", Expected: "expression", Found: "';'"},
+		{Pos: token.Pos{Line: 5, Col: 14}, Msg: "expected ',', found ';'", Expected: "','", Found: "';'"},
+		{Pos: token.Pos{Line: 7, Col: 7}, Msg: "expected identifier, found '='", Expected: "identifier", Found: "'='"},
+		{Pos: token.Pos{Line: 7, Col: 20}, Msg: "expected expression, found ')'", Expected: "expression", Found: "')'"},
+	}
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%v)", len(expected), len(errors), errors)
+	}
+	for i, e := range expected {
+		if errors[i] != e {
+			t.Errorf("errors[%d] wrong. expected=%#v, got=%#v", i, e, errors[i])
+		}
+	}
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+	for i, name := range []string{"y", "b", "d"} {
+		testVarStatement(t, program.Statements[i], name)
+	}
+}
+
+func TestParserRecoversWithoutSemicolons(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []token.Pos
+		names  []string // of the var statements that parse
+	}{
+		{"var a =\nvar b = 1 +\nvar c = 2\nvar d = 3 *", []token.Pos{{Line: 2, Col: 1}, {Line: 3, Col: 1}, {Line: 4, Col: 12}}, []string{"c"}},
+		{"var = 1\nvar b = 2\nkonst 3\nvar d = (1 +\nvar e = 5", []token.Pos{{Line: 1, Col: 5}, {Line: 3, Col: 7}, {Line: 5, Col: 1}}, []string{"b", "e"}},
+		{"var f = fn() {\n    var x =\n    var y = 1\n}\nvar g = 2", []token.Pos{{Line: 3, Col: 5}}, []string{"f", "g"}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program, err := p.ParseProgram()
+		if err == nil {
+			t.Errorf("%q: expected parse errors", tt.input)
+			continue
+		}
+		var got []token.Pos
+		for _, e := range p.Errors() {
+			got = append(got, e.Pos)
+		}
+		if !reflect.DeepEqual(got, tt.errors) {
+			t.Errorf("%q: wrong errors. expected at %v, got %v", tt.input, tt.errors, p.Errors())
+		}
+		if len(program.Statements) != len(tt.names) {
+			t.Errorf("%q: wrong number of statements. expected=%d, got=%d", tt.input, len(tt.names), len(program.Statements))
+			continue
+		}
+		for i, name := range tt.names {
+			testVarStatement(t, program.Statements[i], name)
+		}
+	}
+}
+
+func FuzzParseProgram(f *testing.F) {
+	for _, seed := range []string{
+		"var a = fn(x, y) { x + y }; a(1, 2);",
+		"jika (a < b) { pilih a } atau { pilih b }",
+		"var p = pilah x { 1 -> \"satu\" _ -> \"lain\" };",
+		"coba { lempar \"x\" } tangkap (e) { e }",
+		"impor \"mod.bi\"; ekspor konst x = [1, {\"a\": 2}][0];",
+		"tiap (i < 3) { i = i + 1; lanjut; }",
+		`"${a ${b}}" |> f; x =~ /a/ && y !~ ~b~`,
+		"var = 5; fn(1 { ) }; & |",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p := New(lexer.New(input))
+			if _, err := p.ParseProgram(); err != nil && len(p.Errors()) == 0 {
+				t.Errorf("error without diagnostics on %q", input)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("parser does not terminate on %q", input)
+		}
+	})
+}
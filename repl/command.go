@@ -0,0 +1,151 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+const help = `Perintah:
+    :tokens kode     tampilkan token kode beserta posisinya
+    :ast kode        tampilkan pohon AST kode
+    :type ekspresi   evaluasi ekspresi lalu tampilkan tipenya
+    :env             tampilkan variabel global dan tipenya
+    :load file.bi    jalankan file skrip di REPL ini
+    :reset           lupakan semua variabel, konstanta dan modul
+    :help            tampilkan bantuan ini
+`
+
+// commands are the REPL commands by name, each gets the rest of the input
+// after the name.
+var commands = map[string]func(s *session, arg string){
+	":tokens": (*session).tokens,
+	":ast":    (*session).tree,
+	":type":   (*session).typeOf,
+	":env":    (*session).env,
+	":load":   (*session).load,
+	":reset":  (*session).reset,
+	":help":   func(s *session, arg string) { _, _ = io.WriteString(s.out, help) },
+}
+
+// commandNames returns the names of the commands, sorted.
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// command runs input, the name of a command followed by its argument.
+func (s *session) command(input string) {
+	name, arg := input, ""
+	if i := strings.IndexAny(input, " \t\n"); i >= 0 {
+		name, arg = input[:i], strings.TrimSpace(input[i+1:])
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		_, _ = fmt.Fprintf(s.out, "perintah tidak dikenal %s, lihat :help\n", name)
+		return
+	}
+	cmd(s, arg)
+}
+
+// tokens writes every token of src with its start and end position.
+// Lexer errors are written after the ILLEGAL token they produce.
+func (s *session) tokens(src string) {
+	w := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	l := lexer.New(src)
+	for {
+		tok, err := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		line := fmt.Sprintf("%s-%s\t%s\t%q", tok.Pos(), tok.End(), tok.Type, tok.Literal)
+		if err != nil {
+			line += "\t" + err.Error()
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	_ = w.Flush()
+}
+
+// tree writes the AST of src. After a syntax error the errors are written
+// first, followed by the part of the tree the parser recovered.
+func (s *session) tree(src string) {
+	p := parser.New(lexer.New(src))
+	prog, err := p.ParseProgram()
+	if err != nil {
+		msgs := make([]string, len(p.Errors()))
+		for i, e := range p.Errors() {
+			msgs[i] = e.Error()
+		}
+		_, _ = io.WriteString(s.out, "parse errors:\n")
+		printParseErrors(s.out, msgs)
+	}
+	_ = ast.Fprint(s.out, prog)
+}
+
+// typeOf evaluates src and writes the type of its value.
+func (s *session) typeOf(src string) {
+	if evaluated, ok := s.run("", src); ok && evaluated != nil {
+		_, _ = fmt.Fprintln(s.out, evaluated.Type())
+	}
+}
+
+// env writes the globals with the type of their value, konstanta are
+// marked as such.
+func (s *session) env(string) {
+	w := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	for _, name := range s.script.GlobalNames() {
+		val, ok := s.script.Global(name)
+		if !ok {
+			continue
+		}
+		line := name + "\t" + string(val.Type())
+		if s.script.IsKonst(name) {
+			line += "\tkonst"
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	_ = w.Flush()
+}
+
+// load runs the file at path, its globals stay in the REPL.
+func (s *session) load(path string) {
+	if path == "" {
+		_, _ = io.WriteString(s.out, "file skrip belum diberikan, misalnya :load file.bi\n")
+		return
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		_, _ = fmt.Fprintln(s.out, err)
+		return
+	}
+	// relative imports of the file are resolved against its directory
+	s.script.SetFile(path)
+	defer s.script.SetFile("")
+	s.run(path, string(src))
+}
+
+// reset starts over with a script as new as the one given to Start.
+func (s *session) reset(string) {
+	s.script.Reset()
+	s.script.DefineKonst("argv", &evaluator.Array{})
+}
+
+func printParseErrors(out io.Writer, errors []string) {
+	for _, msg := range errors {
+		_, _ = io.WriteString(out, "\t"+msg+"\n")
+	}
+}
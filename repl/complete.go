@@ -0,0 +1,50 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/dedisuryadi/bilang/token"
+)
+
+// complete completes the word before pos in line, pos counts runes. It
+// offers the keywords, builtins and globals of script that start with the
+// word, so "math." offers the math.* builtins, and the commands at the
+// start of a line starting with ':'.
+func complete(script Script, line string, pos int) (head string, completions []string, tail string) {
+	runes := []rune(line)
+	if word := string(runes[:pos]); strings.HasPrefix(word, ":") && !strings.ContainsAny(word, " \t") {
+		for _, name := range commandNames() {
+			if strings.HasPrefix(name, word) {
+				completions = append(completions, name)
+			}
+		}
+		return "", completions, string(runes[pos:])
+	}
+	start := pos
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	head, tail = string(runes[:start]), string(runes[pos:])
+	prefix := string(runes[start:pos])
+	if prefix == "" {
+		return head, nil, tail
+	}
+
+	seen := make(map[string]bool)
+	for _, names := range [][]string{token.Keywords(), script.Builtins().Names(), script.GlobalNames()} {
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) && !seen[name] {
+				seen[name] = true
+				completions = append(completions, name)
+			}
+		}
+	}
+	sort.Strings(completions)
+	return head, completions, tail
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
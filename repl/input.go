@@ -0,0 +1,93 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/token"
+	"golang.org/x/term"
+)
+
+// errAborted is returned by a lineReader when the user abandons the
+// input, e.g. with Ctrl-C.
+var errAborted = errors.New("input aborted")
+
+// lineReader reads the lines typed at the REPL, without their line
+// ending. It returns io.EOF once the input is exhausted.
+type lineReader interface {
+	Prompt(prompt string) (string, error)
+}
+
+// plain reads lines from any reader, writing the prompt to out.
+type plain struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (p *plain) Prompt(prompt string) (string, error) {
+	_, _ = io.WriteString(p.out, prompt)
+	line, err := p.in.ReadString('\n')
+	if line == "" && err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readInput reads one input, asking for more lines with CONTINUE_PROMPT
+// while it is incomplete. Input cut short by the end of in is returned
+// as is, the parser then reports what is missing.
+func readInput(lines lineReader) (string, error) {
+	var input strings.Builder
+	prompt := PROMPT
+	for {
+		line, err := lines.Prompt(prompt)
+		if err == io.EOF && input.Len() > 0 {
+			return input.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		input.WriteString(line)
+		if !incomplete(input.String()) {
+			return input.String(), nil
+		}
+		input.WriteByte('\n')
+		prompt = CONTINUE_PROMPT
+	}
+}
+
+// incomplete reports whether src needs more lines: a brace, bracket or
+// parenthesis is still open, or a raw string or block comment runs to
+// the end of src.
+func incomplete(src string) bool {
+	l := lexer.New(src)
+	depth := 0
+	for {
+		tok, err := l.NextToken()
+		if err != nil {
+			var incomplete *lexer.IncompleteError
+			if errors.As(err, &incomplete) {
+				return true
+			}
+			continue
+		}
+		switch tok.Type {
+		case token.LBRACE, token.LPAREN, token.LBRACKET:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			depth--
+		case token.EOF:
+			return depth > 0
+		}
+	}
+}
+
+// IsTerminal reports whether f is a terminal, rather than a file, a pipe
+// or a device such as /dev/null.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
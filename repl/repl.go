@@ -2,58 +2,113 @@ package repl
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
+	"strings"
 
+	"github.com/dedisuryadi/bilang/ast"
 	"github.com/dedisuryadi/bilang/evaluator"
 	"github.com/dedisuryadi/bilang/lexer"
 	"github.com/dedisuryadi/bilang/parser"
+	"github.com/dedisuryadi/bilang/token"
 )
 
-const PROMPT = "bilang >>"
-
-func Start(in io.Reader, out io.Writer) {
-	var (
-		env      = evaluator.NewEnvironment()
-		script   = evaluator.NewScript()
-		evaluate = func(input string) {
-			l := lexer.New(input)
-			p := parser.New(l)
-			prog, err := p.ParseProgram()
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-			evaluated := script.Eval(prog, env)
-			if evaluated != nil {
-				_, _ = io.WriteString(out, evaluated.Inspect())
-				_, _ = io.WriteString(out, "\n")
-			}
-		}
-	)
+const (
+	PROMPT          = "bilang >>"
+	CONTINUE_PROMPT = "      ..."
+)
+
+// Script runs parsed programs. evaluator.Script walks the AST while
+// vm.Script compiles it to bytecode first, both keep their globals
+// between calls to Run.
+type Script interface {
+	SetFile(path string)
+	SetModuleLoader(loader evaluator.ModuleLoader)
+	DefineKonst(name string, val evaluator.Object)
+	SetMaxDepth(n int)
+	SetLimits(l evaluator.Limits)
+	SetStdout(w io.Writer)
+	SetStdin(r io.Reader)
+	Builtins() *evaluator.Registry
+	Global(name string) (evaluator.Object, bool)
+	GlobalNames() []string
+	IsKonst(name string) bool
+	Reset()
+	Run(program *ast.Program) evaluator.Object
+	RunContext(ctx context.Context, program *ast.Program) evaluator.Object
+}
 
-	defer script.Free()
-	if stat, _ := os.Stdin.Stat(); (stat.Mode() & os.ModeCharDevice) == 0 {
-		inp, _ := ioutil.ReadAll(in)
-		evaluate(string(inp))
-		return
+// Start reads inputs from in and writes their values to out until in is
+// exhausted. An input spans several lines while it is incomplete, see
+// readInput, and inputs starting with ':' are commands, see commands.
+// When in and out are the terminal, lines are read with a line editor
+// that keeps a history and completes names on tab.
+func Start(in io.Reader, out io.Writer, script Script) {
+	s := &session{out: out, script: script}
+	script.SetModuleLoader(parser.ParseFile)
+	script.DefineKonst("argv", &evaluator.Array{})
+	script.SetStdout(out)
+
+	var lines lineReader
+	if in == os.Stdin && out == os.Stdout && IsTerminal(os.Stdin) && IsTerminal(os.Stdout) {
+		t := newTerminal(script)
+		defer t.Close()
+		lines = t
+	} else {
+		// scripts read the lines typed after the one that calls baca_baris
+		reader := bufio.NewReader(in)
+		script.SetStdin(reader)
+		lines = &plain{in: reader, out: out}
 	}
 
-	scanner := bufio.NewScanner(in)
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		input, err := readInput(lines)
+		if err == errAborted {
+			continue
+		}
+		if err != nil {
 			return
 		}
-		evaluate(scanner.Text())
+		if strings.HasPrefix(strings.TrimSpace(input), ":") {
+			s.command(strings.TrimSpace(input))
+			continue
+		}
+		s.evaluate(input)
 	}
 }
 
-func printParseErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		_, _ = io.WriteString(out, "\t"+msg+"\n")
+// session is the state of a REPL started by Start.
+type session struct {
+	out    io.Writer
+	script Script
+}
+
+func (s *session) evaluate(input string) {
+	if evaluated, ok := s.run("", input); ok && evaluated != nil {
+		_, _ = io.WriteString(s.out, evaluated.Inspect())
+		_, _ = io.WriteString(s.out, "\n")
+	}
+}
+
+// run parses and runs src, the source of file, and returns its value.
+// Parse and runtime errors are written to out, ok is then false.
+func (s *session) run(file, src string) (evaluated evaluator.Object, ok bool) {
+	p := parser.New(lexer.New(src))
+	p.SetFile(file)
+	prog, err := p.ParseProgram()
+	if err != nil {
+		_, _ = fmt.Fprintln(s.out, err)
+		return nil, false
+	}
+	evaluated = s.script.Run(prog)
+	if err, ok := evaluated.(*evaluator.Error); ok {
+		_, _ = fmt.Fprintln(s.out, token.FormatError(file, src, err.Pos(), err.Inspect()))
+		if len(err.Stack) > 1 {
+			_, _ = fmt.Fprintln(s.out, "\n"+err.Traceback())
+		}
+		return nil, false
 	}
+	return evaluated, true
 }
@@ -0,0 +1,120 @@
+package repl
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/vm"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{`1 + 2`, false},
+		{`var f = fn(x) {`, true},
+		{"var f = fn(x) {\nx * 2", true},
+		{"var f = fn(x) {\nx * 2\n}", false},
+		{`var a = [1, 2,`, true},
+		{`println(`, true},
+		{`"{"`, false},
+		{`var s = ` + "`satu", true},
+		{`/* komentar`, true},
+		{`}`, false},
+	}
+	for _, tt := range tests {
+		if got := incomplete(tt.input); got != tt.want {
+			t.Errorf("incomplete(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	script := evaluator.NewScript()
+	script.DefineKonst("panjang_nama", &evaluator.Integer{Value: 1})
+
+	tests := []struct {
+		line string
+		pos  int
+		head string
+		want []string
+		tail string
+	}{
+		{`math.Mi`, 7, ``, []string{"math.Min"}, ``},
+		{`var x = kon`, 11, `var x = `, []string{"konst"}, ``},
+		{`panjang_n(a)`, 9, ``, []string{"panjang_nama"}, `(a)`},
+		{`println(panj`, 12, `println(`, []string{"panjang", "panjang_nama"}, ``},
+		{`1 + `, 4, `1 + `, nil, ``},
+	}
+	for _, tt := range tests {
+		head, got, tail := complete(script, tt.line, tt.pos)
+		if head != tt.head || tail != tt.tail || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("complete(%q, %d) = %q, %q, %q, want %q, %q, %q",
+				tt.line, tt.pos, head, got, tail, tt.head, tt.want, tt.tail)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	for name, script := range map[string]Script{"eval": evaluator.NewScript(), "vm": vm.NewScript()} {
+		t.Run(name, func(t *testing.T) {
+			input := "var f = fn(x) {\nx * 2\n}\nf(21)\nvar a = [1,\n2]\npanjang(a)\n"
+			var out strings.Builder
+			Start(strings.NewReader(input), &out, script)
+
+			got := out.String()
+			for _, want := range []string{"42\n", "2\n", CONTINUE_PROMPT} {
+				if !strings.Contains(got, want) {
+					t.Errorf("output %q does not contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.bi")
+	if err := ioutil.WriteFile(lib, []byte("konst dua = 2\nvar kali = fn(x) { x * dua }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{":tokens var x = 1", []string{`1:1-1:4   VAR    "var"`, `1:9-1:10  INT    "1"`}},
+		{":tokens 0x", []string{`ILLEGAL  "0x"  invalid number literal`}},
+		{":ast 1 + x", []string{"Program 1:1\n  ExpressionStatement 1:1\n    InfixExpression + 1:1\n      IntegerLiteral 1 1:1\n      Identifier x 1:5\n"}},
+		{":ast var = 1", []string{"parse errors:\n\t1:5: expected identifier, found '='\n"}},
+		{"var s = \"a\"\n:type s", []string{"STRING\n"}},
+		{":type 1 / 0", []string{"division by zero"}},
+		{":load " + lib + "\nkali(21)", []string{"42\n"}},
+		{":load " + lib + "\n:env", []string{"argv  ARRAY    konst\ndua   INTEGER  konst\nkali  FUNCTION\n"}},
+		{"var x = 1\n:reset\n:env\nx", []string{"argv  ARRAY  konst\n", "identifier not found: x"}},
+		{":bantu", []string{"perintah tidak dikenal :bantu"}},
+		{":help", []string{":tokens kode"}},
+	}
+	for _, backend := range []string{"eval", "vm"} {
+		for _, tt := range tests {
+			var script Script = evaluator.NewScript()
+			if backend == "vm" {
+				script = vm.NewScript()
+			}
+			var out strings.Builder
+			Start(strings.NewReader(tt.input+"\n"), &out, script)
+
+			got := strings.ReplaceAll(out.String(), PROMPT, "")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%s: %q: output %q does not contain %q", backend, tt.input, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/peterh/liner"
+)
+
+// terminal reads lines with a line editor: the cursor can move through
+// the line, earlier lines are recalled with the arrow keys and saved in
+// HistoryFile, and tab completes names, see complete.
+type terminal struct {
+	line    *liner.State
+	history string
+}
+
+func newTerminal(script Script) *terminal {
+	t := &terminal{line: liner.NewLiner(), history: HistoryFile()}
+	t.line.SetCtrlCAborts(true)
+	t.line.SetWordCompleter(func(line string, pos int) (string, []string, string) {
+		return complete(script, line, pos)
+	})
+	if f, err := os.Open(t.history); err == nil {
+		_, _ = t.line.ReadHistory(f)
+		_ = f.Close()
+	}
+	return t
+}
+
+func (t *terminal) Prompt(prompt string) (string, error) {
+	line, err := t.line.Prompt(prompt)
+	if err == liner.ErrPromptAborted {
+		return "", errAborted
+	}
+	if err == nil && strings.TrimSpace(line) != "" {
+		t.line.AppendHistory(line)
+	}
+	return line, err
+}
+
+// Close saves the history and gives the terminal back.
+func (t *terminal) Close() error {
+	if t.history != "" {
+		if f, err := os.Create(t.history); err == nil {
+			_, _ = t.line.WriteHistory(f)
+			_ = f.Close()
+		}
+	}
+	return t.line.Close()
+}
+
+// HistoryFile is where the REPL keeps the lines typed at the terminal:
+// $BILANG_HISTORY, or .bilang_history in the home directory.
+func HistoryFile() string {
+	if path := os.Getenv("BILANG_HISTORY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".bilang_history")
+}
@@ -0,0 +1,91 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos is a position in the source. Line and Col start at 1, the zero Pos
+// means the position is unknown.
+type Pos struct {
+	Line int
+	Col  int
+}
+
+func (p Pos) IsValid() bool { return p.Line > 0 }
+
+// Before reports whether p comes before q in the source.
+func (p Pos) Before(q Pos) bool {
+	return p.Line < q.Line || p.Line == q.Line && p.Col < q.Col
+}
+
+func (p Pos) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// Pos returns the position of the first character of the token.
+func (t Token) Pos() Pos { return Pos{Line: t.Line, Col: t.Col} }
+
+// End returns the position just after the last character of the token.
+func (t Token) End() Pos { return Pos{Line: t.EndLine, Col: t.EndCol} }
+
+// FormatError renders msg as `file:line:col: msg` followed by the source
+// line at pos and a caret under the column, e.g.
+//
+//	main.bi:2:3: ERROR: division by zero: 1 / 0
+//	    x / 0;
+//	      ^
+//
+// file may be empty, and without a valid pos only `file: msg` is returned.
+func FormatError(file, src string, pos Pos, msg string) string {
+	var out strings.Builder
+	switch {
+	case file != "" && pos.IsValid():
+		out.WriteString(file + ":" + pos.String() + ": ")
+	case file != "":
+		out.WriteString(file + ": ")
+	case pos.IsValid():
+		out.WriteString(pos.String() + ": ")
+	}
+	out.WriteString(msg)
+	if excerpt := Excerpt(src, pos); excerpt != "" {
+		out.WriteString("\n" + excerpt)
+	}
+	return out.String()
+}
+
+// Excerpt returns the source line at pos and a caret under its column,
+// both indented by four spaces. It returns "" when pos is outside src.
+func Excerpt(src string, pos Pos) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	lines := strings.Split(src, "\n")
+	if pos.Line > len(lines) {
+		return ""
+	}
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+	if strings.TrimSpace(line) == "" {
+		return ""
+	}
+
+	// keep tabs in the caret line, so the caret lines up with the source,
+	// columns count runes
+	var caret strings.Builder
+	col := 1
+	for _, ch := range line {
+		if col == pos.Col {
+			break
+		}
+		if ch == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+		col++
+	}
+	return "    " + line + "\n    " + caret.String() + "^"
+}
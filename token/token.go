@@ -1,11 +1,14 @@
 package token
 
+import "sort"
+
 const (
 	PROGRAM    = "PROGRAM"
 	ILLEGAL    = "ILLEGAL"
 	EOF        = "EOF"
 	IDENT      = "IDENT"
 	INT        = "INT"
+	FLOAT      = "FLOAT"
 	DOT        = "."
 	ASSIGN     = "="
 	PLUS       = "+"
@@ -30,6 +33,8 @@ const (
 	GTE        = ">="
 	EQ         = "=="
 	NEQ        = "!="
+	MATCH      = "=~"
+	NOT_MATCH  = "!~"
 	PIPE       = "|>"
 	OR         = "||"
 	AND        = "&&"
@@ -41,10 +46,16 @@ const (
 	SALAH      = "SALAH"
 	NIHIL      = "NIHIL"
 	STRING     = "STRING"
+	TEMPLATE   = "TEMPLATE"
 	TIAP       = "TIAP"
 	DI         = "DI"
 	LANJUT     = "LANJUT"
 	USAI       = "USAI"
+	COBA       = "COBA"
+	TANGKAP    = "TANGKAP"
+	LEMPAR     = "LEMPAR"
+	IMPOR      = "IMPOR"
+	EKSPOR     = "EKSPOR"
 	REGEX      = "REGEX"
 	LBRACKET   = "["
 	RBRACKET   = "]"
@@ -52,39 +63,86 @@ const (
 	FATARROW   = "=>"
 	ARROW      = "->"
 	TILDE      = "~"
+	COMMENT    = "COMMENT"
 )
 
 var (
 	keywords = map[string]Type{
-		"fn":     FUNCTION,
-		"var":    VAR,
-		"konst":  KONST,
-		"jika":   JIKA,
-		"atau":   ATAU,
-		"pilah":  PILAH,
-		"pilih":  PILIH,
-		"benar":  BENAR,
-		"salah":  SALAH,
-		"nihil":  NIHIL,
-		"tiap":   TIAP,
-		"di":     DI,
-		"lanjut": LANJUT,
-		"usai":   USAI,
+		"fn":      FUNCTION,
+		"var":     VAR,
+		"konst":   KONST,
+		"jika":    JIKA,
+		"atau":    ATAU,
+		"pilah":   PILAH,
+		"pilih":   PILIH,
+		"benar":   BENAR,
+		"salah":   SALAH,
+		"nihil":   NIHIL,
+		"tiap":    TIAP,
+		"di":      DI,
+		"lanjut":  LANJUT,
+		"usai":    USAI,
+		"coba":    COBA,
+		"tangkap": TANGKAP,
+		"lempar":  LEMPAR,
+		"impor":   IMPOR,
+		"ekspor":  EKSPOR,
 	}
 )
 
 type Type string
 
+// Token is a lexeme with its position, Line and Col mark the first
+// character and EndLine and EndCol the position just after the last one.
 type Token struct {
 	Col     int
 	Line    int
+	EndCol  int
+	EndLine int
 	Type    Type
 	Literal string
 }
 
+// Keywords returns the keywords of the language, sorted.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
 func LookupIdent(ident string) Type {
 	if tok, ok := keywords[ident]; ok {
 		return tok
 	}
 	return IDENT
 }
+
+// Describe names a token type for error messages: keywords and operators
+// quoted as they are written, other types by what they are.
+func Describe(t Type) string {
+	switch t {
+	case IDENT:
+		return "identifier"
+	case INT:
+		return "integer"
+	case FLOAT:
+		return "float"
+	case STRING, TEMPLATE:
+		return "string"
+	case REGEX:
+		return "regex"
+	case EOF:
+		return "end of input"
+	case ILLEGAL:
+		return "illegal token"
+	}
+	for word, typ := range keywords {
+		if typ == t {
+			return "'" + word + "'"
+		}
+	}
+	return "'" + string(t) + "'"
+}
@@ -0,0 +1,215 @@
+package vm
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/dedisuryadi/bilang/ast"
+	"github.com/dedisuryadi/bilang/compiler"
+	"github.com/dedisuryadi/bilang/evaluator"
+)
+
+// Script compiles programs to bytecode and runs them on the vm, with the
+// same results as evaluator.Script. Globals and konstanta survive between
+// calls to Run, so a REPL can feed it one line at a time. Like
+// evaluator.Script it is safe for concurrent use, runs take turns.
+type Script struct {
+	mu   sync.Mutex // held by a run and by the setters
+	unit *unit
+	vm   *VM
+}
+
+func NewScript() *Script {
+	return newScript(evaluator.NewModules(), evaluator.NewRegistry())
+}
+
+func newScript(modules *evaluator.Modules, builtins *evaluator.Registry) *Script {
+	s := &Script{
+		unit: &unit{
+			konst:    make(map[string]struct{}),
+			exports:  make(map[string]struct{}),
+			modules:  modules,
+			compiler: compiler.New(),
+		},
+		vm: New(),
+	}
+	s.unit.builtins = builtins
+	s.unit.compiler.SetBuiltins(builtins)
+	return s
+}
+
+// Builtins returns the registry of the functions the script can call, see
+// evaluator.Script.Builtins.
+func (s *Script) Builtins() *evaluator.Registry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unit.builtins
+}
+
+// SetBuiltins replaces the registry of the script. Builtins are resolved
+// when a program is compiled, so changes to the registry apply to the
+// programs run afterwards.
+func (s *Script) SetBuiltins(r *evaluator.Registry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unit.builtins = r
+	s.unit.compiler.SetBuiltins(r)
+}
+
+// SetFile tells the script which file it is running, relative imports
+// are resolved against its directory.
+func (s *Script) SetFile(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unit.file = path
+	s.unit.compiler.SetFile(path)
+}
+
+// SetModuleLoader enables impor, without a loader impor returns an error.
+func (s *Script) SetModuleLoader(loader evaluator.ModuleLoader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unit.modules.SetLoader(loader)
+}
+
+// DefineKonst binds a host provided value as a global konstanta, e.g. argv.
+// Unlike evaluator.Script.DefineKonst it waits for the current run.
+func (s *Script) DefineKonst(name string, val evaluator.Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.unit.compiler.DefineGlobal(name, true)
+	s.unit.growGlobals(i + 1)
+	s.unit.globals[i] = val
+	s.unit.konst[name] = struct{}{}
+}
+
+// Global returns the value of a global variable or konstanta.
+func (s *Script) Global(name string) (evaluator.Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.unit.compiler.GlobalIndex(name)
+	if !ok || i >= len(s.unit.globals) || s.unit.globals[i] == nil {
+		return nil, false
+	}
+	return s.unit.globals[i], true
+}
+
+// IsKonst reports whether the global name is a konstanta.
+func (s *Script) IsKonst(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.unit.konst[name]
+	return ok
+}
+
+// GlobalNames returns the names of the globals that have a value, sorted.
+func (s *Script) GlobalNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for i, name := range s.unit.compiler.GlobalNames() {
+		if i < len(s.unit.globals) && s.unit.globals[i] != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Reset forgets every global, konstanta and imported module, as if the
+// script were new. The builtins, module loader, limits and stdio stay.
+func (s *Script) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.unit
+	s.unit = &unit{
+		konst:    make(map[string]struct{}),
+		exports:  make(map[string]struct{}),
+		file:     u.file,
+		modules:  u.modules,
+		builtins: u.builtins,
+		compiler: compiler.New(),
+	}
+	s.unit.compiler.SetBuiltins(u.builtins)
+	s.unit.compiler.SetFile(u.file)
+	s.unit.modules.Reset()
+}
+
+// SetMaxDepth limits how deep function calls may nest, see
+// evaluator.Script.SetMaxDepth.
+func (s *Script) SetMaxDepth(n int) {
+	s.vm.SetMaxDepth(n)
+}
+
+// Runtime returns the runtime the script shares with its modules.
+func (s *Script) Runtime() *evaluator.Runtime {
+	return s.vm.Runtime()
+}
+
+// SetStdout sends the output of stdout and println to w, os.Stdout by
+// default.
+func (s *Script) SetStdout(w io.Writer) {
+	s.vm.Runtime().SetStdout(w)
+}
+
+// SetStdin makes baca_baris read from r, os.Stdin by default.
+func (s *Script) SetStdin(r io.Reader) {
+	s.vm.Runtime().SetStdin(r)
+}
+
+// SetLimits bounds every run of the script, see evaluator.Limits.
+func (s *Script) SetLimits(l evaluator.Limits) {
+	s.vm.Runtime().SetLimits(l)
+}
+
+func (s *Script) Run(program *ast.Program) evaluator.Object {
+	return s.RunContext(context.Background(), program)
+}
+
+// RunContext is Run stopping with a LimitError once ctx is done or the
+// run exceeds the script's limits.
+func (s *Script) RunContext(ctx context.Context, program *ast.Program) evaluator.Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vm.Runtime().Start(ctx)
+	return s.run(program)
+}
+
+func (s *Script) run(program *ast.Program) evaluator.Object {
+	bc, err := s.unit.compiler.Compile(program)
+	if err != nil {
+		return evaluator.NewError("%s", err)
+	}
+	s.unit.constants = bc.Constants
+	s.unit.names = bc.Globals
+	s.unit.growGlobals(len(bc.Globals))
+	return s.vm.Run(&Closure{Fn: bc.Main, unit: s.unit})
+}
+
+func (u *unit) growGlobals(n int) {
+	for len(u.globals) < n {
+		u.globals = append(u.globals, nil)
+	}
+}
+
+// runModule runs imported modules in a Script of their own that shares
+// the module cache and the runtime of the importer.
+func runModule(modules *evaluator.Modules, builtins *evaluator.Registry, rt *evaluator.Runtime) evaluator.ModuleRunner {
+	return func(path string, program *ast.Program) (map[string]evaluator.Object, evaluator.Object) {
+		child := newScript(modules, builtins)
+		child.SetFile(path)
+		child.vm.SetRuntime(rt)
+		if result := child.run(program); result != nil && result.Type() == evaluator.ERROR {
+			return nil, result
+		}
+		exports := make(map[string]evaluator.Object, len(child.unit.exports))
+		for name := range child.unit.exports {
+			if i, ok := child.unit.compiler.GlobalIndex(name); ok {
+				exports[name] = child.unit.globals[i]
+			}
+		}
+		return exports, nil
+	}
+}
@@ -0,0 +1,711 @@
+package vm
+
+import (
+	"math"
+
+	"github.com/dedisuryadi/bilang/compiler"
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/token"
+)
+
+const (
+	CELL     = "CELL"
+	ITERATOR = "ITERATOR"
+)
+
+const initialStackSize = 2048
+
+// Cell boxes a variable captured by a closure, the function declaring it
+// and every closure capturing it share the cell.
+type Cell struct {
+	Value evaluator.Object
+}
+
+func (c *Cell) Type() evaluator.Type { return CELL }
+func (c *Cell) Inspect() string {
+	if c.Value == nil {
+		return "cell"
+	}
+	return c.Value.Inspect()
+}
+
+// Closure is a function value, the vm's counterpart of evaluator.Function.
+type Closure struct {
+	Fn   *compiler.CompiledFunction
+	Free []*Cell
+	unit *unit
+}
+
+func (c *Closure) Type() evaluator.Type { return evaluator.FUNCTION }
+func (c *Closure) Inspect() string      { return c.Fn.Inspect() }
+
+type iterator struct {
+	*evaluator.Iterator
+}
+
+func (it *iterator) Type() evaluator.Type { return ITERATOR }
+func (it *iterator) Inspect() string      { return "iterator" }
+
+// unit is what the code compiled from one file shares: constants and
+// globals. Closures keep their unit, so functions exported by a module
+// still see the module's globals when another file calls them.
+type unit struct {
+	constants []evaluator.Object
+	globals   []evaluator.Object
+	names     []string // of the globals
+	konst     map[string]struct{}
+	exports   map[string]struct{}
+	file      string
+	modules   *evaluator.Modules
+	builtins  *evaluator.Registry
+	compiler  *compiler.Compiler
+}
+
+type frame struct {
+	cl   *Closure
+	ip   int
+	bp   int
+	call int // offset of the OpCall that is waiting for the next frame
+}
+
+// handler is the tangkap of an open coba.
+type handler struct {
+	frame int
+	sp    int
+	ip    int
+}
+
+type VM struct {
+	stack    []evaluator.Object
+	sp       int // the next free slot, the top of the stack is stack[sp-1]
+	frames   []frame
+	handlers []handler
+	rt       *evaluator.Runtime
+}
+
+func New() *VM {
+	return &VM{stack: make([]evaluator.Object, initialStackSize), rt: evaluator.NewRuntime()}
+}
+
+// Runtime returns the runtime that counts the instructions, allocations
+// and output of the vm against its limits.
+func (vm *VM) Runtime() *evaluator.Runtime {
+	return vm.rt
+}
+
+// SetRuntime makes the vm share rt, e.g. with the script that imports
+// the module it runs.
+func (vm *VM) SetRuntime(rt *evaluator.Runtime) {
+	vm.rt = rt
+}
+
+// SetMaxDepth limits how deep function calls may nest, n <= 0 restores
+// evaluator.DefaultMaxDepth.
+func (vm *VM) SetMaxDepth(n int) {
+	vm.rt.SetMaxDepth(n)
+}
+
+func (vm *VM) push(obj evaluator.Object) {
+	if vm.sp == len(vm.stack) {
+		vm.grow(vm.sp + 1)
+	}
+	vm.stack[vm.sp] = obj
+	vm.sp++
+}
+
+func (vm *VM) pop() evaluator.Object {
+	vm.sp--
+	obj := vm.stack[vm.sp]
+	vm.stack[vm.sp] = nil
+	return obj
+}
+
+func (vm *VM) grow(size int) {
+	if size <= len(vm.stack) {
+		return
+	}
+	stack := make([]evaluator.Object, 2*size)
+	copy(stack, vm.stack[:vm.sp])
+	vm.stack = stack
+}
+
+// Run executes the main function cl and returns its value, or the Error
+// that stopped it.
+func (vm *VM) Run(cl *Closure) evaluator.Object {
+	vm.frames = append(vm.frames[:0], frame{cl: cl})
+	vm.handlers = vm.handlers[:0]
+	vm.sp = 0
+	vm.grow(cl.Fn.NumLocals)
+	for i := 0; i < cl.Fn.NumLocals; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = cl.Fn.NumLocals
+
+	for {
+		f := &vm.frames[len(vm.frames)-1]
+		ins := f.cl.Fn.Instructions
+		u := f.cl.unit
+		start := f.ip
+		if err := vm.rt.Step(); err != nil {
+			return vm.raise(err, start)
+		}
+		op := compiler.Opcode(ins[f.ip])
+		f.ip++
+
+		var errObj *evaluator.Error
+		switch op {
+		case compiler.OpConstant:
+			vm.push(u.constants[compiler.ReadUint16(ins[f.ip:])])
+			f.ip += 2
+
+		case compiler.OpPop:
+			vm.pop()
+
+		case compiler.OpSwap:
+			vm.stack[vm.sp-1], vm.stack[vm.sp-2] = vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+
+		case compiler.OpNihil:
+			vm.push(evaluator.NIHIL)
+
+		case compiler.OpVoid:
+			vm.push(nil)
+
+		case compiler.OpTrue:
+			vm.push(evaluator.TRUE)
+
+		case compiler.OpFalse:
+			vm.push(evaluator.FALSE)
+
+		case compiler.OpAdd, compiler.OpSub, compiler.OpMul, compiler.OpDiv, compiler.OpMod,
+			compiler.OpEqual, compiler.OpNotEqual, compiler.OpLess, compiler.OpLessEqual,
+			compiler.OpGreater, compiler.OpGreaterEqual, compiler.OpAnd, compiler.OpOr,
+			compiler.OpRegexMatch, compiler.OpRegexNotMatch:
+			right := vm.pop()
+			left := vm.pop()
+			result := binaryInteger(op, left, right)
+			if result == nil {
+				result = evaluator.Infix(compiler.Operators[op], orNihil(left), orNihil(right))
+			}
+			if err, ok := result.(*evaluator.Error); ok {
+				errObj = err
+				break
+			}
+			errObj = vm.alloc(result)
+
+		case compiler.OpMinus, compiler.OpBang:
+			operator := "-"
+			if op == compiler.OpBang {
+				operator = "!"
+			}
+			result := evaluator.Prefix(operator, orNihil(vm.pop()))
+			if err, ok := result.(*evaluator.Error); ok {
+				errObj = err
+				break
+			}
+			errObj = vm.alloc(result)
+
+		case compiler.OpJump:
+			f.ip = int(compiler.ReadUint16(ins[f.ip:]))
+
+		case compiler.OpJumpNotTruthy:
+			target := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			if !evaluator.IsTruthy(vm.pop()) {
+				f.ip = target
+			}
+
+		case compiler.OpGetGlobal:
+			i := compiler.ReadUint16(ins[f.ip:])
+			f.ip += 2
+			val := u.globals[i]
+			if val == nil {
+				errObj = notFound(u.names[i])
+				break
+			}
+			vm.push(val)
+
+		case compiler.OpSetGlobal:
+			u.globals[compiler.ReadUint16(ins[f.ip:])] = vm.pop()
+			f.ip += 2
+
+		case compiler.OpGetLocal:
+			i := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			val := vm.stack[f.bp+i]
+			if val == nil {
+				errObj = notFound(f.cl.Fn.LocalNames[i])
+				break
+			}
+			vm.push(val)
+
+		case compiler.OpSetLocal:
+			vm.stack[f.bp+int(compiler.ReadUint16(ins[f.ip:]))] = vm.pop()
+			f.ip += 2
+
+		case compiler.OpGetCell:
+			i := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			cell, _ := vm.stack[f.bp+i].(*Cell)
+			if cell == nil || cell.Value == nil {
+				errObj = notFound(f.cl.Fn.LocalNames[i])
+				break
+			}
+			vm.push(cell.Value)
+
+		case compiler.OpSetCell:
+			i := f.bp + int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			val := vm.pop()
+			if cell, ok := vm.stack[i].(*Cell); ok {
+				cell.Value = val
+			} else {
+				vm.stack[i] = &Cell{Value: val}
+			}
+
+		case compiler.OpLoadCell:
+			i := f.bp + int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			cell, ok := vm.stack[i].(*Cell)
+			if !ok {
+				cell = &Cell{Value: vm.stack[i]}
+				vm.stack[i] = cell
+			}
+			vm.push(cell)
+
+		case compiler.OpMakeCell:
+			i := f.bp + int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			vm.stack[i] = &Cell{Value: vm.stack[i]}
+
+		case compiler.OpGetFree:
+			i := ins[f.ip]
+			f.ip++
+			val := f.cl.Free[i].Value
+			if val == nil {
+				errObj = notFound(f.cl.Fn.FreeNames[i])
+				break
+			}
+			vm.push(val)
+
+		case compiler.OpLoadFree:
+			vm.push(f.cl.Free[ins[f.ip]])
+			f.ip++
+
+		case compiler.OpClear:
+			first := f.bp + int(compiler.ReadUint16(ins[f.ip:]))
+			n := int(compiler.ReadUint16(ins[f.ip+2:]))
+			f.ip += 4
+			for i := first; i < first+n; i++ {
+				vm.stack[i] = nil
+			}
+
+		case compiler.OpCheckKonst, compiler.OpDefineKonst:
+			name := u.constants[compiler.ReadUint16(ins[f.ip:])].(*evaluator.String).Value
+			f.ip += 2
+			if _, ok := u.konst[name]; ok {
+				errObj = evaluator.NewErrorKind(evaluator.TypeError, "konstanta %s tidak bisa ditugaskan kembali", name)
+				break
+			}
+			if op == compiler.OpDefineKonst {
+				u.konst[name] = struct{}{}
+			}
+
+		case compiler.OpCheckType:
+			scope := int(ins[f.ip])
+			prev := vm.variable(f, scope, int(compiler.ReadUint16(ins[f.ip+1:])))
+			name := u.constants[compiler.ReadUint16(ins[f.ip+3:])].(*evaluator.String).Value
+			f.ip += 5
+			val := vm.stack[vm.sp-1]
+			if prev != nil && val != nil && prev.Type() != val.Type() {
+				errObj = evaluator.NewErrorKind(evaluator.TypeError, "perubahan tipe variabel %s dari %s menjadi %s tidak diizinkan", name, prev.Type(), val.Type())
+			}
+
+		case compiler.OpArray:
+			n := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			elements := make([]evaluator.Object, n)
+			copy(elements, vm.stack[vm.sp-n:vm.sp])
+			vm.sp -= n
+			errObj = vm.alloc(&evaluator.Array{Elements: elements})
+
+		case compiler.OpInterpolate:
+			n := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			str := evaluator.Interpolate(vm.stack[vm.sp-n : vm.sp])
+			vm.sp -= n
+			errObj = vm.alloc(str)
+
+		case compiler.OpHash:
+			n := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			pairs := make(map[evaluator.HashKey]evaluator.HashPair, n/2)
+			for i := vm.sp - n; i < vm.sp; i += 2 {
+				key, value := vm.stack[i], vm.stack[i+1]
+				hashable, ok := key.(evaluator.Hashable)
+				if !ok {
+					errObj = evaluator.NewErrorKind(evaluator.TypeError, "unusable as hash key: %s", typeOf(key))
+					break
+				}
+				pairs[hashable.HashKey()] = evaluator.HashPair{Key: key, Value: value}
+			}
+			if errObj != nil {
+				break
+			}
+			vm.sp -= n
+			errObj = vm.alloc(&evaluator.Hash{Pairs: pairs})
+
+		case compiler.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			result := evaluator.Index(orNihil(left), orNihil(index))
+			if err, ok := result.(*evaluator.Error); ok {
+				errObj = err
+				break
+			}
+			vm.push(result)
+
+		case compiler.OpMember:
+			scope := int(ins[f.ip])
+			val := vm.variable(f, scope, int(compiler.ReadUint16(ins[f.ip+1:])))
+			obj := u.constants[compiler.ReadUint16(ins[f.ip+3:])].(*evaluator.String).Value
+			name := u.constants[compiler.ReadUint16(ins[f.ip+5:])].(*evaluator.String).Value
+			f.ip += 7
+			result := evaluator.Member(u.builtins, obj, val, name)
+			if err, ok := result.(*evaluator.Error); ok {
+				errObj = err
+				break
+			}
+			vm.push(result)
+
+		case compiler.OpCall:
+			argc := int(ins[f.ip])
+			f.ip++
+			errObj = vm.call(start, argc)
+
+		case compiler.OpTailCall:
+			argc := int(ins[f.ip])
+			f.ip++
+			errObj = vm.tailCall(start, argc)
+
+		case compiler.OpReturn:
+			val := vm.pop()
+			depth := len(vm.frames) - 1
+			for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].frame >= depth {
+				vm.handlers = vm.handlers[:len(vm.handlers)-1]
+			}
+			if depth == 0 {
+				return val
+			}
+			for i := f.bp - 1; i < vm.sp; i++ {
+				vm.stack[i] = nil
+			}
+			vm.sp = f.bp - 1
+			vm.frames = vm.frames[:depth]
+			vm.push(val)
+
+		case compiler.OpClosure:
+			fn := u.constants[compiler.ReadUint16(ins[f.ip:])].(*compiler.CompiledFunction)
+			n := int(ins[f.ip+2])
+			f.ip += 3
+			free := make([]*Cell, n)
+			for i := 0; i < n; i++ {
+				free[i] = vm.stack[vm.sp-n+i].(*Cell)
+			}
+			vm.sp -= n
+			errObj = vm.alloc(&Closure{Fn: fn, Free: free, unit: u})
+
+		case compiler.OpPipe:
+			target := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			val, ok := evaluator.PipeValue(vm.pop())
+			if !ok {
+				vm.push(evaluator.NIHIL)
+				f.ip = target
+				break
+			}
+			vm.push(val)
+
+		case compiler.OpIterInit:
+			slot := f.bp + int(compiler.ReadUint16(ins[f.ip:]))
+			name := u.constants[compiler.ReadUint16(ins[f.ip+2:])].(*evaluator.String).Value
+			f.ip += 4
+			it, ok := evaluator.NewIterator(vm.pop())
+			if !ok {
+				errObj = evaluator.NewErrorKind(evaluator.TypeError, "identifier %s is not iterable", name)
+				break
+			}
+			vm.stack[slot] = &iterator{it}
+
+		case compiler.OpIterNext:
+			it := vm.stack[f.bp+int(compiler.ReadUint16(ins[f.ip:]))].(*iterator)
+			n := ins[f.ip+2]
+			target := int(compiler.ReadUint16(ins[f.ip+3:]))
+			f.ip += 5
+			key, value, ok := it.Next()
+			if !ok {
+				f.ip = target
+				break
+			}
+			if n > 1 {
+				vm.push(value)
+			}
+			if n > 0 {
+				vm.push(key)
+			}
+
+		case compiler.OpMatch:
+			cond := vm.pop()
+			vm.push(evaluator.NativeBool(evaluator.Match(orNihil(vm.stack[vm.sp-1]), orNihil(cond))))
+
+		case compiler.OpTry:
+			target := int(compiler.ReadUint16(ins[f.ip:]))
+			f.ip += 2
+			vm.handlers = append(vm.handlers, handler{frame: len(vm.frames) - 1, sp: vm.sp, ip: target})
+
+		case compiler.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+		case compiler.OpThrow:
+			errObj = evaluator.Throw(vm.pop())
+
+		case compiler.OpFail:
+			err := *u.constants[compiler.ReadUint16(ins[f.ip:])].(*evaluator.Error)
+			f.ip += 2
+			errObj = &err
+
+		case compiler.OpImport:
+			path := u.constants[compiler.ReadUint16(ins[f.ip:])].(*evaluator.String).Value
+			f.ip += 2
+			mod := u.modules.Import(u.file, path, runModule(u.modules, u.builtins, vm.rt))
+			if err, ok := mod.(*evaluator.Error); ok {
+				errObj = err
+				break
+			}
+			vm.push(mod)
+
+		case compiler.OpExport:
+			name := u.constants[compiler.ReadUint16(ins[f.ip:])].(*evaluator.String).Value
+			f.ip += 2
+			u.exports[name] = struct{}{}
+
+		default:
+			errObj = evaluator.NewError("opcode %d undefined", op)
+		}
+
+		if errObj != nil {
+			if result := vm.raise(errObj, start); result != nil {
+				return result
+			}
+		}
+	}
+}
+
+// call calls the function below the argc arguments on top of the stack,
+// start is the offset of the OpCall.
+func (vm *VM) call(start, argc int) *evaluator.Error {
+	switch fn := vm.stack[vm.sp-1-argc].(type) {
+	case *Closure:
+		if n := fn.Fn.NumParameters; n != argc {
+			return evaluator.NewError("invalid length between function parameter=%d & args=%d", n, argc)
+		}
+		if max := vm.rt.MaxDepth(); len(vm.frames)-1 >= max {
+			return evaluator.StackOverflow(max)
+		}
+		vm.frames[len(vm.frames)-1].call = start
+		bp := vm.sp - argc
+		top := bp + fn.Fn.NumLocals
+		vm.grow(top)
+		for i := vm.sp; i < top; i++ {
+			vm.stack[i] = nil
+		}
+		vm.sp = top
+		vm.frames = append(vm.frames, frame{cl: fn, bp: bp})
+		return nil
+
+	case *evaluator.Builtin:
+		args := make([]evaluator.Object, argc)
+		copy(args, vm.stack[vm.sp-argc:vm.sp])
+		for i := vm.sp - argc - 1; i < vm.sp; i++ {
+			vm.stack[i] = nil
+		}
+		vm.sp -= argc + 1
+		result := fn.Call(vm.rt, args...)
+		if err, ok := result.(*evaluator.Error); ok {
+			return err
+		}
+		return vm.alloc(result)
+
+	default:
+		return evaluator.NewErrorKind(evaluator.TypeError, "not a function: %s", typeOf(fn))
+	}
+}
+
+// tailCall replaces the current frame with a call of the closure below
+// the argc arguments on the stack, so tail recursion does not grow the
+// frames. The caller's frame keeps pointing at the original call site.
+// Other callees are called normally, the OpReturn after the OpTailCall
+// then returns their result.
+func (vm *VM) tailCall(start, argc int) *evaluator.Error {
+	fn, ok := vm.stack[vm.sp-1-argc].(*Closure)
+	if !ok || fn.Fn.NumParameters != argc {
+		return vm.call(start, argc)
+	}
+	depth := len(vm.frames) - 1
+	for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].frame >= depth {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+	bp := vm.frames[depth].bp
+	copy(vm.stack[bp-1:], vm.stack[vm.sp-1-argc:vm.sp])
+	top := bp + fn.Fn.NumLocals
+	vm.grow(top)
+	for i := bp + argc; i < top || i < vm.sp; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = top
+	vm.frames[depth] = frame{cl: fn, bp: bp}
+	return nil
+}
+
+// raise stamps err with the position of the instruction at start, or of
+// the innermost call that has one, and the call stack from there. Then it
+// unwinds to the closest tangkap, without one it returns err, which ends
+// the run.
+func (vm *VM) raise(err *evaluator.Error, start int) evaluator.Object {
+	if err.Line == 0 {
+		offset := start
+		for i := len(vm.frames) - 1; i >= 0; i-- {
+			fn := vm.frames[i].cl.Fn
+			if pos, ok := fn.Positions[offset]; ok && pos.IsValid() {
+				err.File, err.Line, err.Col = fn.File, pos.Line, pos.Col
+				err.Stack = vm.stackTrace(i, pos)
+				break
+			}
+			if i > 0 {
+				offset = vm.frames[i-1].call
+			}
+		}
+	}
+
+	if len(vm.handlers) == 0 || !err.Recoverable() {
+		return err
+	}
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.frames = vm.frames[:h.frame+1]
+	for i := h.sp; i < vm.sp; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = h.sp
+	vm.frames[h.frame].ip = h.ip
+	vm.push(&evaluator.Exception{Err: err})
+	return nil
+}
+
+// alloc pushes obj, a newly allocated value, unless it exceeds the
+// limits of the run.
+func (vm *VM) alloc(obj evaluator.Object) *evaluator.Error {
+	if err := vm.rt.Alloc(obj); err != nil {
+		return err
+	}
+	vm.push(obj)
+	return nil
+}
+
+// stackTrace snapshots frames[:top+1] for an error raised at pos in the
+// frame top.
+func (vm *VM) stackTrace(top int, pos token.Pos) []evaluator.StackFrame {
+	frames := make([]evaluator.StackFrame, 0, top+1)
+	for i := top; i >= 0; i-- {
+		f := vm.frames[i]
+		sf := evaluator.StackFrame{Function: "main", File: f.cl.Fn.File, Pos: pos}
+		if lit := f.cl.Fn.Literal; lit != nil {
+			args := make([]evaluator.Object, f.cl.Fn.NumParameters)
+			for j := range args {
+				args[j] = vm.stack[f.bp+j]
+				if cell, ok := args[j].(*Cell); ok {
+					args[j] = cell.Value
+				}
+			}
+			sf.Function, sf.Args = evaluator.FunctionName(lit), evaluator.FormatArgs(args)
+		}
+		frames = append(frames, sf)
+		if i > 0 {
+			pos = vm.frames[i-1].cl.Fn.Positions[vm.frames[i-1].call]
+		}
+	}
+	return frames
+}
+
+// variable reads the variable an OpCheckType or OpMember refers to, nil
+// when it is unset.
+func (vm *VM) variable(f *frame, scope, index int) evaluator.Object {
+	switch scope {
+	case compiler.ScopeGlobal:
+		return f.cl.unit.globals[index]
+	case compiler.ScopeLocal:
+		return vm.stack[f.bp+index]
+	case compiler.ScopeCell:
+		if cell, ok := vm.stack[f.bp+index].(*Cell); ok {
+			return cell.Value
+		}
+	case compiler.ScopeFree:
+		return f.cl.Free[index].Value
+	}
+	return nil
+}
+
+// binaryInteger is the fast path for INTEGER operands, it returns nil
+// whenever evaluator.Infix has to decide.
+func binaryInteger(op compiler.Opcode, left, right evaluator.Object) evaluator.Object {
+	l, ok := left.(*evaluator.Integer)
+	if !ok {
+		return nil
+	}
+	r, ok := right.(*evaluator.Integer)
+	if !ok {
+		return nil
+	}
+	switch op {
+	case compiler.OpAdd:
+		if sum := l.Value + r.Value; (sum > l.Value) == (r.Value > 0) {
+			return &evaluator.Integer{Value: sum}
+		}
+	case compiler.OpSub:
+		if diff := l.Value - r.Value; (diff < l.Value) == (r.Value > 0) && r.Value != math.MinInt64 {
+			return &evaluator.Integer{Value: diff}
+		}
+	case compiler.OpEqual:
+		return evaluator.NativeBool(l.Value == r.Value)
+	case compiler.OpNotEqual:
+		return evaluator.NativeBool(l.Value != r.Value)
+	case compiler.OpLess:
+		return evaluator.NativeBool(l.Value < r.Value)
+	case compiler.OpLessEqual:
+		return evaluator.NativeBool(l.Value <= r.Value)
+	case compiler.OpGreater:
+		return evaluator.NativeBool(l.Value > r.Value)
+	case compiler.OpGreaterEqual:
+		return evaluator.NativeBool(l.Value >= r.Value)
+	}
+	return nil
+}
+
+func notFound(name string) *evaluator.Error {
+	return evaluator.NewErrorKind(evaluator.NameError, "identifier not found: %s", name)
+}
+
+// orNihil replaces the value of a statement without one, so the
+// evaluator's operators can inspect it.
+func orNihil(obj evaluator.Object) evaluator.Object {
+	if obj == nil {
+		return evaluator.NIHIL
+	}
+	return obj
+}
+
+func typeOf(obj evaluator.Object) evaluator.Type {
+	if obj == nil {
+		return evaluator.VOID
+	}
+	return obj.Type()
+}
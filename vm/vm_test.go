@@ -0,0 +1,75 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/dedisuryadi/bilang/evaluator"
+	"github.com/dedisuryadi/bilang/lexer"
+	"github.com/dedisuryadi/bilang/parser"
+)
+
+// The language itself is tested against both backends by the evaluator
+// tests, these cover what is specific to the vm.
+
+func run(t *testing.T, s *Script, input string) evaluator.Object {
+	program, err := parser.New(lexer.New(input)).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Run(program)
+}
+
+func TestScriptKeepsGlobals(t *testing.T) {
+	s := NewScript()
+	s.DefineKonst("argv", &evaluator.Array{})
+	inputs := []struct {
+		input    string
+		expected string
+	}{
+		{`var a = 1`, ""},
+		{`var tambah = fn(x) { x + a }`, ""},
+		{`tambah(1)`, "2"},
+		{`a = 10; tambah(1)`, "11"},
+		{`panjang(argv)`, "0"},
+		{`argv = [1]`, "ERROR: konstanta argv tidak bisa ditugaskan kembali"},
+		{`konst b = 2`, ""},
+		{`b = 3`, "ERROR: konstanta b tidak bisa ditugaskan kembali"},
+		{`a + b`, "12"},
+	}
+	for _, tt := range inputs {
+		got := ""
+		if result := run(t, s, tt.input); result != nil {
+			got = result.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("%q: want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// TestDeepRecursion checks that calls do not grow the Go stack, the vm
+// can recurse far deeper than evaluator.DefaultMaxDepth when allowed to.
+func TestDeepRecursion(t *testing.T) {
+	input := `
+var hitung = fn(n) { jika (n == 0) { 0 } atau { 1 + hitung(n - 1) } }
+hitung(100000)
+`
+	s := NewScript()
+	s.SetMaxDepth(200000)
+	result := run(t, s, input)
+	if i, ok := result.(*evaluator.Integer); !ok || i.Value != 100000 {
+		t.Errorf("wrong result. got=%v", result)
+	}
+}
+
+func TestErrorInsideCallIsCaught(t *testing.T) {
+	input := `
+var f = fn(n) { var a = [n]; a[0] / 0 }
+var g = fn() { coba { 1 + f(1) } tangkap (e) { e["baris"] * 100 + e["kolom"] } };
+[g(), g()]
+`
+	result := run(t, NewScript(), input)
+	if result == nil || result.Inspect() != "[235, 235]" {
+		t.Errorf("wrong result. got=%v", result)
+	}
+}